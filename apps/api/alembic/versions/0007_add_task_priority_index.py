@@ -0,0 +1,33 @@
+"""Add priority ordering index to tasks table.
+
+Revision ID: 0007
+Revises: 0006_add_mfa_fields
+Create Date: 2026-10-16
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+# revision identifiers, used by Alembic.
+revision = '0007_add_task_priority_index'
+down_revision = '0006_add_mfa_fields'
+branch_labels = None
+depends_on = None
+
+# Must match taskPriorityRank in apps/gateway/internal/db/db.go so the
+# gateway's ?sort=priority query can use the index.
+PRIORITY_RANK = "CASE priority WHEN 'P0' THEN 0 WHEN 'P1' THEN 1 WHEN 'P2' THEN 2 WHEN 'P3' THEN 3 ELSE 4 END"
+
+
+def upgrade() -> None:
+    """Index tasks by project, priority rank, and creation time."""
+    op.create_index(
+        'ix_tasks_project_priority',
+        'tasks',
+        ['project_id', sa.text(f'({PRIORITY_RANK})'), 'created_at'],
+    )
+
+
+def downgrade() -> None:
+    """Drop the priority ordering index."""
+    op.drop_index('ix_tasks_project_priority', table_name='tasks')
@@ -0,0 +1,33 @@
+"""Add email verification, last login, and soft-delete fields to users table.
+
+Revision ID: 0008
+Revises: 0007_add_task_priority_index
+Create Date: 2026-10-16
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+# revision identifiers, used by Alembic.
+revision = '0008_add_user_lifecycle_fields'
+down_revision = '0007_add_task_priority_index'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    """Add account lifecycle columns used by the gateway cleanup jobs."""
+    op.add_column('users', sa.Column('email_verified_at', sa.DateTime(timezone=True), nullable=True))
+    op.add_column('users', sa.Column('last_login_at', sa.DateTime(timezone=True), nullable=True))
+    op.add_column('users', sa.Column('deleted_at', sa.DateTime(timezone=True), nullable=True))
+
+    # Treat existing accounts as verified so enabling the unverified-account
+    # cleanup doesn't sweep up users who predate verification tracking.
+    op.execute("UPDATE users SET email_verified_at = created_at")
+
+
+def downgrade() -> None:
+    """Remove account lifecycle columns from users table."""
+    op.drop_column('users', 'deleted_at')
+    op.drop_column('users', 'last_login_at')
+    op.drop_column('users', 'email_verified_at')
@@ -0,0 +1,35 @@
+"""Add due date and overdue notification fields to tasks table.
+
+Revision ID: 0009
+Revises: 0008_add_user_lifecycle_fields
+Create Date: 2026-10-16
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+# revision identifiers, used by Alembic.
+revision = '0009_add_task_due_at'
+down_revision = '0008_add_user_lifecycle_fields'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    """Add due_at and the gateway's overdue bookkeeping column."""
+    op.add_column('tasks', sa.Column('due_at', sa.DateTime(timezone=True), nullable=True))
+    # Set by the gateway once task_overdue has been published for the current due_at
+    op.add_column('tasks', sa.Column('overdue_notified_at', sa.DateTime(timezone=True), nullable=True))
+    op.create_index(
+        'ix_tasks_due_at_open',
+        'tasks',
+        ['due_at'],
+        postgresql_where=sa.text("due_at IS NOT NULL AND status <> 'completed'"),
+    )
+
+
+def downgrade() -> None:
+    """Remove due date fields from tasks table."""
+    op.drop_index('ix_tasks_due_at_open', table_name='tasks')
+    op.drop_column('tasks', 'overdue_notified_at')
+    op.drop_column('tasks', 'due_at')
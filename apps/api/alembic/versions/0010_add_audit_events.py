@@ -0,0 +1,41 @@
+"""Add audit_events table for queryable security audit records.
+
+Revision ID: 0010
+Revises: 0009_add_task_due_at
+Create Date: 2026-10-16
+
+"""
+from alembic import op
+import sqlalchemy as sa
+from sqlalchemy.dialects import postgresql
+
+# revision identifiers, used by Alembic.
+revision = '0010_add_audit_events'
+down_revision = '0009_add_task_due_at'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    """Create audit_events, written by the gateway alongside its audit log."""
+    op.create_table(
+        'audit_events',
+        sa.Column('id', sa.BigInteger(), primary_key=True, autoincrement=True),
+        sa.Column('occurred_at', sa.DateTime(timezone=True), nullable=False, server_default=sa.func.now()),
+        sa.Column('event_type', sa.String(100), nullable=False),
+        sa.Column('actor', sa.String(255), nullable=False),
+        sa.Column('resource', sa.String(255), nullable=False),
+        sa.Column('action', sa.String(50), nullable=False),
+        sa.Column('outcome', sa.String(50), nullable=False),
+        sa.Column('ip_address', sa.String(64), nullable=True),
+        sa.Column('user_agent', sa.Text(), nullable=True),
+        sa.Column('request_id', sa.String(100), nullable=True),
+        sa.Column('details', postgresql.JSONB(astext_type=sa.Text()), nullable=True),
+    )
+    op.create_index('ix_audit_events_occurred_at', 'audit_events', ['occurred_at'])
+
+
+def downgrade() -> None:
+    """Drop audit_events table."""
+    op.drop_index('ix_audit_events_occurred_at', table_name='audit_events')
+    op.drop_table('audit_events')
@@ -0,0 +1,43 @@
+"""Add organizations and scope users and projects to them.
+
+Revision ID: 0011
+Revises: 0010_add_audit_events
+Create Date: 2026-10-16
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+# revision identifiers, used by Alembic.
+revision = '0011_add_organizations'
+down_revision = '0010_add_audit_events'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    """Create organizations and add org membership to users and projects."""
+    op.create_table(
+        'organizations',
+        sa.Column('id', sa.String(), primary_key=True),
+        sa.Column('name', sa.String(255), nullable=False),
+        sa.Column('slug', sa.String(100), nullable=False, unique=True),
+        sa.Column('created_at', sa.DateTime(timezone=True), nullable=False, server_default=sa.func.now()),
+    )
+
+    # Rows without an org keep the pre-tenancy shared namespace
+    op.add_column('users', sa.Column('org_id', sa.String(), sa.ForeignKey('organizations.id', ondelete='SET NULL'), nullable=True))
+    op.add_column('users', sa.Column('org_role', sa.String(20), nullable=False, server_default='member'))
+    op.add_column('projects', sa.Column('org_id', sa.String(), sa.ForeignKey('organizations.id', ondelete='CASCADE'), nullable=True))
+    op.create_index('ix_users_org_id', 'users', ['org_id'])
+    op.create_index('ix_projects_org_id', 'projects', ['org_id'])
+
+
+def downgrade() -> None:
+    """Remove organizations and org membership columns."""
+    op.drop_index('ix_projects_org_id', table_name='projects')
+    op.drop_index('ix_users_org_id', table_name='users')
+    op.drop_column('projects', 'org_id')
+    op.drop_column('users', 'org_role')
+    op.drop_column('users', 'org_id')
+    op.drop_table('organizations')
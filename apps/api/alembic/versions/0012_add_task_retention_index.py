@@ -0,0 +1,31 @@
+"""Index completed, unarchived tasks for the gateway's retention job.
+
+Revision ID: 0012
+Revises: 0011_add_organizations
+Create Date: 2026-10-16
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+# revision identifiers, used by Alembic.
+revision = '0012_add_task_retention_index'
+down_revision = '0011_add_organizations'
+branch_labels = None
+depends_on = None
+
+
+def upgrade() -> None:
+    """Add a partial index on completion time for tasks awaiting archival."""
+    # Matches ArchiveCompletedTasks in apps/gateway/internal/db/db.go
+    op.create_index(
+        'ix_tasks_completed_unarchived',
+        'tasks',
+        ['updated_at'],
+        postgresql_where=sa.text("status = 'completed' AND NOT archived"),
+    )
+
+
+def downgrade() -> None:
+    """Remove the task retention index."""
+    op.drop_index('ix_tasks_completed_unarchived', table_name='tasks')
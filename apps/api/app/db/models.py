@@ -5,7 +5,7 @@ This module defines SQLAlchemy ORM models for crew runs, events, and users.
 
 from uuid import uuid4
 
-from sqlalchemy import Boolean, Column, DateTime, ForeignKey, Integer, String, Text, func, UniqueConstraint
+from sqlalchemy import BigInteger, Boolean, Column, DateTime, ForeignKey, Integer, String, Text, func, UniqueConstraint
 from sqlalchemy.dialects.postgresql import JSONB
 from sqlalchemy.ext.declarative import declarative_base
 from sqlalchemy.orm import relationship
@@ -49,6 +49,17 @@ class CrewEvent(Base):
     run = relationship("CrewRun", back_populates="events")
 
 
+class Organization(Base):
+    """Model for a tenant organization owning users and projects."""
+    
+    __tablename__ = "organizations"
+    
+    id = Column(String(), primary_key=True, default=lambda: str(uuid4()))
+    name = Column(String(255), nullable=False)
+    slug = Column(String(100), nullable=False, unique=True)
+    created_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now())
+
+
 class User(Base):
     """Model for user authentication and authorization."""
     
@@ -63,6 +74,11 @@ class User(Base):
     mfa_enabled = Column(Boolean(), nullable=False, server_default="false")
     mfa_secret = Column(String(), nullable=True)
     backup_codes = Column(JSONB(astext_type=Text()), nullable=True)
+    org_id = Column(String(), ForeignKey("organizations.id", ondelete="SET NULL"), nullable=True, index=True)
+    org_role = Column(String(20), nullable=False, server_default="member")
+    email_verified_at = Column(DateTime(timezone=True), nullable=True)
+    last_login_at = Column(DateTime(timezone=True), nullable=True)
+    deleted_at = Column(DateTime(timezone=True), nullable=True)
     created_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now())
     updated_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now(), onupdate=func.now())
 
@@ -77,6 +93,7 @@ class Project(Base):
     description = Column(Text(), nullable=True)
     status = Column(String(50), nullable=False, server_default="planning")
     created_by = Column(String(), nullable=True)
+    org_id = Column(String(), ForeignKey("organizations.id", ondelete="CASCADE"), nullable=True, index=True)
     created_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now())
     updated_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now(), onupdate=func.now())
     
@@ -98,6 +115,8 @@ class Task(Base):
     status = Column(String(50), nullable=False, server_default="queued")
     crew_run_id = Column(String(), ForeignKey("crew_runs.id", ondelete="SET NULL"), nullable=True)
     dependencies = Column(JSONB(astext_type=Text()), nullable=True)
+    due_at = Column(DateTime(timezone=True), nullable=True)
+    overdue_notified_at = Column(DateTime(timezone=True), nullable=True)
     archived = Column(Boolean(), nullable=False, server_default="false")
     created_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now())
     updated_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now(), onupdate=func.now())
@@ -134,6 +153,24 @@ class MemoryEvent(Base):
     published_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now())
 
 
+class AuditEvent(Base):
+    """Model for security audit records written by the gateway."""
+    
+    __tablename__ = "audit_events"
+    
+    id = Column(BigInteger(), primary_key=True, autoincrement=True)
+    occurred_at = Column(DateTime(timezone=True), nullable=False, server_default=func.now(), index=True)
+    event_type = Column(String(100), nullable=False)
+    actor = Column(String(255), nullable=False)
+    resource = Column(String(255), nullable=False)
+    action = Column(String(50), nullable=False)
+    outcome = Column(String(50), nullable=False)
+    ip_address = Column(String(64), nullable=True)
+    user_agent = Column(Text(), nullable=True)
+    request_id = Column(String(100), nullable=True)
+    details = Column(JSONB(astext_type=Text()), nullable=True)
+
+
 class WorkflowStage(Base):
     """Model for tracking workflow pipeline stages."""
     
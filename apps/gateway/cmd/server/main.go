@@ -3,21 +3,30 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"github.com/kyros-praxis/gateway/internal/auth"
 	"github.com/kyros-praxis/gateway/internal/config"
 	"github.com/kyros-praxis/gateway/internal/db"
 	"github.com/kyros-praxis/gateway/internal/events"
 	"github.com/kyros-praxis/gateway/internal/handlers"
+	"github.com/kyros-praxis/gateway/internal/jobs"
 	"github.com/kyros-praxis/gateway/internal/middleware"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/kyros-praxis/gateway/internal/server"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -32,9 +41,104 @@ func main() {
 	cfg := config.Load()
 	log.Info("configuration loaded",
 		"env", cfg.Environment,
+		"bind_address", cfg.BindAddress,
 		"port", cfg.Port,
 	)
 
+	if err := server.ValidateBindAddress(cfg.BindAddress); err != nil {
+		log.Error("invalid BIND_ADDRESS", "error", err)
+		os.Exit(1)
+	}
+
+	if cfg.ShutdownTimeoutSecs <= 0 {
+		log.Error("SHUTDOWN_TIMEOUT_SECONDS must be positive", "value", cfg.ShutdownTimeoutSecs)
+		os.Exit(1)
+	}
+
+	if cfg.RateLimitWarnPct < 0 || cfg.RateLimitWarnPct > 100 {
+		log.Error("RATE_LIMIT_WARN_PERCENT must be between 0 and 100", "value", cfg.RateLimitWarnPct)
+		os.Exit(1)
+	}
+
+	if cfg.LoginMaxFailures <= 0 || cfg.LoginMaxSuccesses <= 0 || cfg.LoginWindowMinutes <= 0 {
+		log.Error("LOGIN_MAX_FAILURES, LOGIN_MAX_SUCCESSES and LOGIN_LIMIT_WINDOW_MINUTES must be positive",
+			"max_failures", cfg.LoginMaxFailures, "max_successes", cfg.LoginMaxSuccesses, "window_minutes", cfg.LoginWindowMinutes)
+		os.Exit(1)
+	}
+
+	if cfg.LoginMaxAttempts < 0 || (cfg.LoginMaxAttempts > 0 && cfg.LoginLockoutMinutes <= 0) {
+		log.Error("LOGIN_MAX_ATTEMPTS must not be negative, and LOGIN_LOCKOUT_MINUTES must be positive while it is set",
+			"max_attempts", cfg.LoginMaxAttempts, "lockout_minutes", cfg.LoginLockoutMinutes)
+		os.Exit(1)
+	}
+
+	if cfg.MaxSessionsPerUser < 0 {
+		log.Error("MAX_SESSIONS_PER_USER must not be negative", "value", cfg.MaxSessionsPerUser)
+		os.Exit(1)
+	}
+
+	if cfg.BatchMaxRequests <= 0 {
+		log.Error("BATCH_MAX_REQUESTS must be positive", "value", cfg.BatchMaxRequests)
+		os.Exit(1)
+	}
+
+	if cfg.MFAEnableSkew < 0 || cfg.MFAEnableSkew > 10 {
+		log.Error("MFA_ENABLE_SKEW must be between 0 and 10", "value", cfg.MFAEnableSkew)
+		os.Exit(1)
+	}
+
+	// A window as long as the token itself would reissue it on every request
+	if cfg.JWTSlidingRefreshMinutes < 0 || (cfg.JWTSlidingRefreshMinutes > 0 && cfg.JWTSlidingRefreshMinutes >= cfg.JWTExpireMinutes) {
+		log.Error("JWT_SLIDING_REFRESH_MINUTES must be between 0 and JWT_EXPIRE_MINUTES",
+			"value", cfg.JWTSlidingRefreshMinutes, "jwt_expire_minutes", cfg.JWTExpireMinutes)
+		os.Exit(1)
+	}
+
+	if cfg.AuthMode != "cookie" && cfg.AuthMode != "token" && cfg.AuthMode != "both" {
+		log.Error("AUTH_MODE must be 'cookie', 'token' or 'both'", "value", cfg.AuthMode)
+		os.Exit(1)
+	}
+
+	if _, err := auth.NewRedirectAllowlist(cfg.OAuthAllowedRedirects); err != nil {
+		log.Error("invalid OAUTH_ALLOWED_REDIRECTS", "error", err)
+		os.Exit(1)
+	}
+
+	if cfg.OAuthStateMode != "store" && cfg.OAuthStateMode != "signed" {
+		log.Error("OAUTH_STATE_MODE must be 'store' or 'signed'", "value", cfg.OAuthStateMode)
+		os.Exit(1)
+	}
+
+	if cfg.PasswordChangeSessions != "revoke_others" && cfg.PasswordChangeSessions != "revoke_all" {
+		log.Error("PASSWORD_CHANGE_SESSIONS must be 'revoke_others' or 'revoke_all'", "value", cfg.PasswordChangeSessions)
+		os.Exit(1)
+	}
+
+	dbSSLMode := db.SSLMode(cfg.DatabaseURL)
+
+	if !models.IsValidPriority(cfg.DefaultTaskPriority) {
+		log.Error("DEFAULT_TASK_PRIORITY must be one of "+strings.Join(models.TaskPriorities, ", "), "value", cfg.DefaultTaskPriority)
+		os.Exit(1)
+	}
+
+	// Checked here so a typo fails the deploy instead of every proxied request
+	if _, err := cfg.WorkerURL(); err != nil {
+		log.Error("invalid WORKER_BASE_URL", "value", cfg.WorkerBaseURL, "error", err)
+		os.Exit(1)
+	}
+
+	taskTransitions := models.DefaultTaskTransitions()
+	if err := taskTransitions.Extend(cfg.TaskStatusTransitions); err != nil {
+		log.Error("invalid TASK_STATUS_TRANSITIONS", "error", err)
+		os.Exit(1)
+	}
+
+	providerModels := models.DefaultProviderModels()
+	if err := providerModels.Extend(cfg.ProviderModels); err != nil {
+		log.Error("invalid PROVIDER_MODELS", "error", err)
+		os.Exit(1)
+	}
+
 	// Production security validation
 	if cfg.IsProduction() {
 		log.Info("Production mode - validating security configuration...")
@@ -44,6 +148,23 @@ func main() {
 			log.Error("CRITICAL: JWT_SECRET_KEY must be set to a secure value (min 32 chars) in production")
 			os.Exit(1)
 		}
+		for _, secret := range cfg.JWTPreviousSecrets {
+			if len(secret) < 32 {
+				log.Error("CRITICAL: JWT_PREVIOUS_SECRETS entries must be at least 32 chars in production")
+				os.Exit(1)
+			}
+		}
+
+		// Enforce encrypted database connections
+		if !db.SSLEnforced(dbSSLMode) {
+			log.Error("CRITICAL: DATABASE_URL must use sslmode=require, verify-ca, or verify-full in production", "sslmode", dbSSLMode)
+			os.Exit(1)
+		}
+
+		// The worker can't tell gateway traffic apart without the shared secret
+		if cfg.WorkerAuthToken == "" {
+			log.Warn("WARNING: WORKER_AUTH_TOKEN is not set; the worker accepts unauthenticated requests")
+		}
 
 		// Warn about debug mode
 		if cfg.Debug {
@@ -53,17 +174,29 @@ func main() {
 		log.Info("Production security validation passed ✓")
 	}
 
-	// Connect to database
-	database, err := db.New(cfg.DatabaseURL)
+	if !db.SSLEnforced(dbSSLMode) {
+		log.Warn("database connection is not guaranteed to use TLS", "sslmode", dbSSLMode)
+	}
+
+	// Connect to database, waiting for it during a coordinated deploy
+	database, err := db.Connect(context.Background(), cfg.DatabaseURL, cfg.DatabaseSSLRootCert,
+		cfg.DBConnectRetries, time.Duration(cfg.DBConnectRetrySecs)*time.Second, log)
 	if err != nil {
 		log.Error("failed to connect to database", "error", err)
 		os.Exit(1)
 	}
 	defer database.Close()
-	log.Info("database connected")
+	database.SetMaxRetries(cfg.DatabaseMaxRetries)
+	observability.RegisterDBPoolMetrics(database.PoolStats)
+	log.Info("database connected", "sslmode", dbSSLMode, "ca_verified", cfg.DatabaseSSLRootCert != "")
 
 	// Initialize auth service
 	authService := auth.New(cfg, database)
+	if cfg.DebugAuth {
+		// Like the body logger, its own Debug logger so the rest stays at Info
+		authService.SetDecisionLogger(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		log.Warn("logging authentication decisions")
+	}
 
 	// Initialize OAuth manager
 	oauthManager := auth.NewOAuthManager(auth.OAuthConfig{
@@ -78,13 +211,19 @@ func main() {
 		log.Info("oauth providers configured", "providers", oauthManager.ListProviders())
 	}
 
-	// Initialize session manager (optional, requires Redis)
+	// Initialize session manager (optional, requires Redis). If Redis is still
+	// down after the startup retries, keep reconnecting in the background.
 	var sessionManager *auth.SessionManager
+	sessionTTL := time.Duration(cfg.SessionTTLHours) * time.Hour
+	redisRetryInterval := time.Duration(cfg.RedisConnectRetrySecs) * time.Second
+	sessionsPending := false
 	if cfg.RedisURL != "" {
 		var err error
-		sessionManager, err = auth.NewSessionManager(cfg.RedisURL, time.Duration(cfg.SessionTTLHours)*time.Hour)
+		sessionManager, err = auth.ConnectSessionManager(context.Background(), cfg.RedisURL, sessionTTL,
+			cfg.RedisConnectRetries, redisRetryInterval, log)
 		if err != nil {
-			log.Warn("session manager disabled", "error", err)
+			sessionsPending = !errors.Is(err, auth.ErrInvalidRedisURL)
+			log.Warn("session manager disabled", "error", err, "reconnecting", sessionsPending)
 		} else {
 			log.Info("session manager connected to Redis")
 		}
@@ -108,92 +247,298 @@ func main() {
 	h := handlers.New(cfg, database, authService, eventsService, log)
 	h.SetOAuth(oauthManager)
 	h.SetSessions(sessionManager)
-	if redisClient != nil {
+	authService.SetSessions(sessionManager)
+	h.SetTaskTransitions(taskTransitions)
+	h.SetProviderModels(providerModels)
+	if valid, missing := h.CurrentModelStatus(); !valid {
+		log.Warn("configured LLM model is not in the provider's model list; extend it with PROVIDER_MODELS",
+			"provider", cfg.ModelProvider,
+			"model", cfg.ModelName,
+			"invalid", missing,
+		)
+	}
+
+	// Readiness: Postgres is required; Redis only backs optional features
+	h.AddReadinessCheck("postgres", true, database.Ping)
+	if cfg.RedisURL != "" {
+		h.AddReadinessCheck("redis", false, func(ctx context.Context) error {
+			if redisClient == nil {
+				return errors.New("redis client not initialized")
+			}
+			return redisClient.Ping(ctx).Err()
+		})
+	}
+
+	mfaCtx, mfaCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	mfaReady, err := database.HasMFAColumns(mfaCtx)
+	mfaCancel()
+	if err != nil {
+		log.Warn("failed to check MFA schema", "error", err)
+	} else if !mfaReady {
+		log.Warn("MFA columns missing - run migrations to enable MFA")
+	}
+	h.SetMFAAvailable(mfaReady)
+	if cfg.OAuthStateMode == "signed" {
+		log.Info("OAuth state using signed stateless tokens")
+	} else if redisClient != nil {
 		h.SetOAuthStateRedis(redisClient)
 		log.Info("OAuth state store connected to Redis")
 	}
 
+	// Background work is cancelled on shutdown
+	bgCtx, cancelBackground := context.WithCancel(context.Background())
+	defer cancelBackground()
+
+	// Expired sessions leave their IDs behind in the per-user session sets
+	startSessionPruner := func(m *auth.SessionManager) {
+		if cfg.SessionPruneMinutes > 0 {
+			go jobs.NewSessionPruner(m, time.Duration(cfg.SessionPruneMinutes)*time.Minute, log).Run(bgCtx)
+			log.Info("session pruner started", "interval_minutes", cfg.SessionPruneMinutes)
+		}
+	}
+	if sessionManager != nil {
+		startSessionPruner(sessionManager)
+	}
+	if sessionsPending {
+		go auth.ReconnectSessionManager(bgCtx, cfg.RedisURL, sessionTTL, redisRetryInterval, log, func(m *auth.SessionManager) {
+			h.SetSessions(m)
+			authService.SetSessions(m)
+			log.Info("session manager connected to Redis; session features enabled")
+			startSessionPruner(m)
+		})
+	}
+
+	// Start event consumer for worker callbacks
+	if redisClient != nil {
+		consumer := events.NewConsumer(redisClient, cfg.EventMaxAttempts, log)
+		consumer.Handle(events.EventTypeTaskUpdated, h.HandleTaskUpdatedEvent)
+		go consumer.Run(bgCtx)
+		log.Info("event consumer started")
+	}
+
+	// Account lifecycle cleanup (skipped unless configured)
+	cleanupInterval := time.Duration(cfg.AccountCleanupIntervalHours) * time.Hour
+	if cleanupInterval <= 0 {
+		cleanupInterval = 24 * time.Hour
+	}
+	var exemptEmails []string
+	if cfg.MFABreakGlassEmail != "" {
+		exemptEmails = append(exemptEmails, cfg.MFABreakGlassEmail)
+	}
+	accountCleanup := jobs.NewAccountCleanup(database, jobs.AccountPolicy{
+		DormantAfter: time.Duration(cfg.DormantAccountDays) * 24 * time.Hour,
+		ExemptEmails: exemptEmails,
+	}, cleanupInterval, log)
+	if accountCleanup.Enabled() {
+		go accountCleanup.Run(bgCtx)
+		log.Info("account cleanup started", "dormant_days", cfg.DormantAccountDays)
+	}
+
+	// Completed task retention keeps the tasks table small
+	if cfg.TaskRetentionDays > 0 {
+		retentionInterval := time.Duration(cfg.TaskRetentionIntervalHours) * time.Hour
+		if retentionInterval <= 0 {
+			retentionInterval = 24 * time.Hour
+		}
+		archiver := jobs.NewTaskArchiver(database, jobs.TaskRetention{
+			After:      time.Duration(cfg.TaskRetentionDays) * 24 * time.Hour,
+			HardDelete: cfg.TaskRetentionHardDelete,
+		}, retentionInterval, log)
+		go archiver.Run(bgCtx)
+		log.Info("task retention started",
+			"retention_days", cfg.TaskRetentionDays,
+			"hard_delete", cfg.TaskRetentionHardDelete,
+		)
+	}
+
+	// Overdue task detection publishes task_overdue (requires Redis to publish)
+	if cfg.OverdueCheckIntervalSecs > 0 {
+		var publisher jobs.Publisher
+		if eventsService != nil {
+			publisher = eventsService
+		}
+		checker := jobs.NewOverdueChecker(database, publisher, time.Duration(cfg.OverdueCheckIntervalSecs)*time.Second, log)
+		go checker.Run(bgCtx)
+		log.Info("overdue task checker started", "interval_seconds", cfg.OverdueCheckIntervalSecs)
+	}
+
+	// Opt-in response cache for read-heavy endpoints (requires Redis)
+	var responseCache *middleware.ResponseCache
+	if cfg.ResponseCacheTTL > 0 {
+		if redisClient != nil {
+			responseCache = middleware.NewResponseCache(redisClient, time.Duration(cfg.ResponseCacheTTL)*time.Second)
+			log.Info("response cache enabled", "ttl_seconds", cfg.ResponseCacheTTL)
+		} else {
+			log.Warn("RESPONSE_CACHE_TTL_SECONDS set without REDIS_URL - response cache disabled")
+		}
+	}
+
 	// Initialize router
 	r := chi.NewRouter()
 
 	// Middleware
-	r.Use(middleware.Recoverer(log))
+	r.Use(chimw.RequestID)
+	r.Use(middleware.RequestIDHeader)
+	r.Use(middleware.Recoverer(log, cfg.Debug && !cfg.IsProduction())) // Stack traces in responses only for local debugging
+	if cfg.MetricsEnabled {
+		r.Use(observability.MetricsMiddleware) // Inside the recoverer so panics still release the gauge
+	}
 	r.Use(middleware.SecurityHeaders)
+	hsts, err := middleware.HSTS(cfg.HSTSMaxAge, cfg.HSTSIncludeSubdomains, cfg.HSTSPreload)
+	if err != nil {
+		log.Error("invalid HSTS settings", "error", err)
+		os.Exit(1)
+	}
+	r.Use(hsts)
 	r.Use(middleware.Logger(log))
-	r.Use(middleware.NewRateLimiter(cfg.RateLimitRPM).Middleware)
-	r.Use(cors.Handler(cors.Options{
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimitRPM, "/"+handlers.APIVersion, authService.ClientIP)
+	rateLimiter.SetWarnPercent(cfg.RateLimitWarnPct)
+	h.SetRateLimiter(rateLimiter)
+	r.Use(rateLimiter.Middleware)
+	bodyLimiter := middleware.NewBodyLimiter(int64(cfg.MaxRequestBody))
+	r.Use(bodyLimiter.Middleware)
+	corsHandler, err := middleware.RouteCORS(cors.Options{
 		AllowedOrigins:   cfg.CORSAllowOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Session-ID"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "X-Session-ID"},
+		ExposedHeaders:   []string{"ETag", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Warning"},
 		AllowCredentials: true,
 		MaxAge:           300,
-	}))
+	}, cfg.CORSPublicRoutes, cfg.CORSPublicOrigins, cfg.MonitoringCORSOrigins(), "/"+handlers.APIVersion)
+	if err != nil {
+		log.Error("invalid CORS_PUBLIC_ROUTES", "error", err)
+		os.Exit(1)
+	}
+	r.Use(corsHandler)
+	jsonBodies := middleware.NewJSONContentType()
+	if cfg.RequireJSONBodies {
+		r.Use(jsonBodies.Middleware)
+	}
+	concurrencyLimiter := middleware.NewConcurrencyLimiter("/" + handlers.APIVersion)
+	r.Use(concurrencyLimiter.Middleware)
 	r.Use(authService.Middleware)
+	r.Use(authService.SlidingRefresh)
+	r.Use(authService.OrgScope)
+	r.Use(middleware.RequestLogger(log))
+	if cfg.DebugBodyRoute != "" {
+		// A logger of its own so the bodies show at Debug while the rest stays at Info
+		bodyLog := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		r.Use(middleware.BodyLogger(bodyLog, cfg.DebugBodyRoute, "/"+handlers.APIVersion))
+		log.Warn("logging redacted request and response bodies", "route", cfg.DebugBodyRoute)
+	}
 
 	// Routes
 	r.Get("/health", h.Health)
+	r.Get("/ready", h.Ready)
+	if cfg.MetricsEnabled {
+		r.Handle("/metrics", observability.MetricsHandler())
+	}
 
-	// Auth routes
-	r.Route("/auth", func(r chi.Router) {
-		// Basic auth
-		r.Post("/register", h.Register)
-		r.Post("/login", h.Login)
-		r.With(authService.RequireAuth).Get("/me", h.GetMe)
-
-		// OAuth routes
-		r.Get("/oauth/providers", h.ListOAuthProviders)
-		r.Get("/oauth/{provider}", h.OAuthStart)
-		r.Get("/oauth/{provider}/callback", h.OAuthCallback)
-
-		// MFA routes - verify has aggressive rate limiting to prevent brute-force
-		mfaLimiter := middleware.NewMFALimiter()
-		r.With(authService.RequireAuth).Post("/mfa/setup", h.MFASetup)
-		r.With(authService.RequireAuth).Post("/mfa/enable", h.MFAEnable)
-		r.With(mfaLimiter.Middleware).Post("/mfa/verify", h.MFAVerify)
-		r.With(authService.RequireAuth).Post("/mfa/disable", h.MFADisable)
-
-		// Session routes
-		r.With(authService.RequireAuth).Get("/sessions", h.ListSessions)
-		r.With(authService.RequireAuth).Delete("/sessions/{id}", h.RevokeSession)
-		r.With(authService.RequireAuth).Delete("/sessions", h.RevokeAllSessions)
-	})
+	// API routes. v1 is served under /v1 and, unless API_ROOT_ROUTES=false,
+	// also at the root for existing clients. A v2 gets its own route function
+	// mounted alongside, leaving v1 untouched.
+	adminIPFilter, err := middleware.NewIPFilter(cfg.AdminAllowCIDRs, cfg.AdminDenyCIDRs, authService.ClientIP, log)
+	if err != nil {
+		log.Error("invalid admin IP filter", "error", err)
+		os.Exit(1)
+	}
 
-	// Project routes
-	r.Route("/projects", func(r chi.Router) {
-		r.Get("/", h.ListProjects)
-		r.With(authService.RequireAuth).Post("/", h.CreateProject)
-		r.Get("/{id}", h.GetProject)
-
-		// Task routes
-		r.With(authService.RequireAuth).Post("/{id}/tasks", h.CreateTask)
-		r.Get("/{id}/tasks", h.ListTasks)
-		r.With(authService.RequireAuth).Get("/{id}/dashboard", h.GetDashboard)
-
-		// Worker proxy routes (Workflow execution)
-		r.With(authService.RequireAuth).Post("/{id}/generate", h.ProxyWorker)
-		r.With(authService.RequireAuth).Post("/{id}/approve", h.ProxyWorker)
-		r.With(authService.RequireAuth).Post("/{id}/regenerate", h.ProxyWorker)
-		r.With(authService.RequireAuth).Get("/{id}/specification", h.ProxyWorker)
-		r.With(authService.RequireAuth).Get("/{id}/code", h.ProxyWorker)
-		r.With(authService.RequireAuth).Get("/{id}/status", h.ProxyWorker)
-	})
+	api := routeDeps{
+		h:             h,
+		auth:          authService,
+		responseCache: responseCache,
+		mfaLimiter:    middleware.NewMFALimiter(authService.ClientIP),
+		loginLimiter:  middleware.NewLoginLimiter(cfg.LoginMaxFailures, cfg.LoginMaxSuccesses, time.Duration(cfg.LoginWindowMinutes)*time.Minute, authService.ClientIP),
+		streaming:     middleware.Streaming(time.Duration(cfg.StreamWriteTimeoutSecs) * time.Second),
+		adminIPFilter: adminIPFilter,
+	}
+	r.Route("/v1", v1Routes(api))
+	if cfg.APIRootRoutes {
+		r.Group(v1Routes(api))
+	}
+
+	// Batch sub-requests go back through the full router
+	h.SetBatchRouter(r)
+
+	// Every route either requires auth or is listed in publicRoutes
+	publicAPI, protectedAPI, err := checkRouteAuth(r, authMiddleware(authService))
+	if err != nil {
+		log.Error("route auth check failed", "error", err)
+		os.Exit(1)
+	}
+	log.Info("route auth policy", "public", publicAPI, "protected", protectedAPI)
+
+	// Per-route rate limits apply to both the root and /v1 forms of a route
+	routeLimits, err := middleware.ParseRouteLimits(cfg.RateLimitRoutes)
+	if err == nil {
+		routeLimits, err = versionedRouteLimits(r, routeLimits)
+	}
+	if err != nil {
+		log.Error("invalid RATE_LIMIT_ROUTES", "error", err)
+		os.Exit(1)
+	}
+	rateLimiter.SetRouteLimits(routeLimits)
 
-	// Admin routes
-	r.Get("/admin/providers", h.GetProviders)
+	// Body limit overrides resolve the same way; 0 exempts a route
+	bodyLimits, err := middleware.ParseBodyLimits(cfg.MaxBodyRoutes)
+	if err == nil {
+		bodyLimits, err = versionedRouteLimits(r, bodyLimits)
+	}
+	if err != nil {
+		log.Error("invalid MAX_REQUEST_BODY_ROUTES", "error", err)
+		os.Exit(1)
+	}
+	bodyLimiter.SetRouteLimits(bodyLimits)
 
-	// Create server
-	server := &http.Server{
-		Addr:         ":" + cfg.Port,
+	// Concurrency limits resolve the same way; both forms share one limit
+	concurrencyLimits, err := middleware.ParseRouteLimits(cfg.ConcurrencyRoutes)
+	if err == nil {
+		concurrencyLimits, err = versionedRouteLimits(r, concurrencyLimits)
+	}
+	if err != nil {
+		log.Error("invalid CONCURRENCY_LIMIT_ROUTES", "error", err)
+		os.Exit(1)
+	}
+	concurrencyLimiter.SetRouteLimits(concurrencyLimits)
+
+	// Content-Type exemptions resolve the same way
+	exempt := make(map[string]int)
+	for _, pattern := range append(slices.Clone(passthroughBodyRoutes), cfg.JSONExemptRoutes...) {
+		exempt[strings.TrimSpace(pattern)] = 0
+	}
+	exempt, err = versionedRouteLimits(r, exempt)
+	if err != nil {
+		log.Error("invalid JSON_CONTENT_TYPE_EXEMPT_ROUTES", "error", err)
+		os.Exit(1)
+	}
+	jsonBodies.SetExemptRoutes(slices.Collect(maps.Keys(exempt)))
+
+	// DEBUG_BODY_ROUTE must name a registered route, like RATE_LIMIT_ROUTES
+	if cfg.DebugBodyRoute != "" {
+		if _, err := versionedRouteLimits(r, map[string]int{cfg.DebugBodyRoute: 0}); err != nil {
+			log.Error("invalid DEBUG_BODY_ROUTE", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	listenAddr := server.ListenAddr(cfg.BindAddress, cfg.Port)
+
+	// Create server. WriteTimeout bounds ordinary responses; streaming routes
+	// replace it per request (see STREAM_WRITE_TIMEOUT_SECONDS).
+	conns := server.NewConnTracker()
+	httpServer := &http.Server{
+		Addr:         listenAddr,
 		Handler:      r,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
+		ConnState:    conns.Track,
 	}
 
 	// Start server in goroutine
 	go func() {
-		log.Info("server starting", "addr", server.Addr)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Info("server starting", "addr", httpServer.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Error("server error", "error", err)
 			os.Exit(1)
 		}
@@ -205,13 +550,17 @@ func main() {
 	<-quit
 
 	log.Info("shutting down server...")
-
-	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Error("server forced to shutdown", "error", err)
+	cancelBackground()
+
+	// Graceful shutdown; connections still open after the timeout are closed
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeoutSecs) * time.Second
+	undrained, err := server.Drain(httpServer, conns, shutdownTimeout)
+	if undrained > 0 {
+		log.Warn("forced shutdown closed connections that did not drain in time",
+			"connections", undrained, "timeout", shutdownTimeout)
+	}
+	if err != nil {
+		log.Error("server shutdown error", "error", err)
 	}
 
 	log.Info("server stopped")
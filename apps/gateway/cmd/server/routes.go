@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/handlers"
+	"github.com/kyros-praxis/gateway/internal/middleware"
+)
+
+// routeDeps are the services shared by every API version's routes. Stateful
+// middleware lives here so it is shared when a version is mounted twice.
+type routeDeps struct {
+	h             *handlers.Handler
+	auth          *auth.Auth
+	responseCache *middleware.ResponseCache
+	mfaLimiter    *middleware.MFALimiter
+	loginLimiter  *middleware.LoginLimiter
+	streaming     func(http.Handler) http.Handler // Replaces the server write timeout on streaming routes
+	adminIPFilter *middleware.IPFilter            // Restricts /admin routes by client address; nil allows all
+}
+
+// publicRoutes is the complete list of routes that may be called without
+// authentication, as "METHOD /pattern" without the API version prefix, with
+// the reason each is public. Every other route must sit behind one of the
+// auth middlewares; checkRouteAuth refuses to start the gateway otherwise.
+var publicRoutes = map[string]string{
+	"GET /health":                         "liveness probe",
+	"GET /ready":                          "readiness probe",
+	"GET /metrics":                        "Prometheus scrape; restrict at the network edge",
+	"POST /auth/register":                 "creates the caller's account",
+	"POST /auth/login":                    "issues the caller's tokens",
+	"POST /auth/refresh":                  "authenticated by the refresh token in the body or cookie",
+	"GET /auth/oauth/providers":           "lists login options for the sign-in page",
+	"GET /auth/oauth/{provider}":          "starts an OAuth login",
+	"GET /auth/oauth/{provider}/callback": "finishes an OAuth login; guarded by the OAuth state",
+	"POST /auth/mfa/verify":               "second login step; rate limited per client",
+	"GET /admin/providers":                "provider status without secrets; limited by the admin IP filter",
+	"POST /worker/events":                 "workers sign the body with WORKER_CALLBACK_SECRET instead",
+}
+
+// passthroughBodyRoutes forward request bodies to the worker untouched, so
+// they are exempt from the JSON Content-Type check; the worker decides what
+// it accepts. Worker callbacks are signed rather than cookie-authenticated.
+var passthroughBodyRoutes = []string{
+	"/projects/{id}/approve",
+	"/projects/{id}/regenerate",
+	"/worker/events",
+}
+
+// checkRouteAuth walks every route on r and sorts it into public and
+// protected by whether its middleware chain includes one of authMiddleware.
+// Versioned and root forms of a route are reported once. It fails if a route
+// without auth is missing from publicRoutes, so a forgotten RequireAuth stops
+// startup instead of exposing the endpoint.
+func checkRouteAuth(r chi.Routes, authMiddleware []func(http.Handler) http.Handler) (public, protected []string, err error) {
+	// Middleware are compared by code pointer, which method values such as
+	// RequireAuth share. Closures like the ones RequireRole returns may be
+	// copied by inlining, so routes must use the method forms to be recognized.
+	guards := make(map[uintptr]bool, len(authMiddleware))
+	for _, mw := range authMiddleware {
+		guards[reflect.ValueOf(mw).Pointer()] = true
+	}
+
+	var unlisted []string
+	seen := make(map[string]bool)
+	err = chi.Walk(r, func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		route = strings.TrimPrefix(route, "/"+handlers.APIVersion)
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		key := method + " " + route
+		if seen[key] {
+			return nil
+		}
+		seen[key] = true
+
+		if slices.ContainsFunc(middlewares, func(mw func(http.Handler) http.Handler) bool {
+			return guards[reflect.ValueOf(mw).Pointer()]
+		}) {
+			protected = append(protected, key)
+			return nil
+		}
+		if _, ok := publicRoutes[key]; !ok {
+			unlisted = append(unlisted, key)
+		}
+		public = append(public, key)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(unlisted) > 0 {
+		slices.Sort(unlisted)
+		return nil, nil, fmt.Errorf("routes without authentication missing from the public route list: %s", strings.Join(unlisted, ", "))
+	}
+	slices.Sort(public)
+	slices.Sort(protected)
+	return public, protected, nil
+}
+
+// authMiddleware returns the middleware that make a route protected for
+// checkRouteAuth.
+func authMiddleware(a *auth.Auth) []func(http.Handler) http.Handler {
+	return []func(http.Handler) http.Handler{
+		a.RequireAuth,
+		a.RequireIdentity,
+		a.RequireAdmin,
+		a.RequireOrgAdmin,
+	}
+}
+
+// versionedRouteLimits checks that every rate-limited pattern names a route
+// registered on r, either as given or under /v1, and returns the limits with
+// each pattern also keyed by its /v1 form.
+func versionedRouteLimits(r chi.Routes, limits map[string]int) (map[string]int, error) {
+	registered := make(map[string]bool)
+	err := chi.Walk(r, func(_, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		registered[route] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versioned := make(map[string]int, 2*len(limits))
+	for pattern, limit := range limits {
+		root := strings.TrimPrefix(pattern, "/"+handlers.APIVersion)
+		if root == "" {
+			root = "/"
+		}
+		prefixed := "/" + handlers.APIVersion + strings.TrimSuffix(root, "/")
+		if !registered[root] && !registered[prefixed] {
+			return nil, fmt.Errorf("no route matches %q", pattern)
+		}
+		versioned[root] = limit
+		versioned[prefixed] = limit
+	}
+	return versioned, nil
+}
+
+// v1Routes registers the v1 API on a router.
+func v1Routes(d routeDeps) func(r chi.Router) {
+	h, authService := d.h, d.auth
+
+	return func(r chi.Router) {
+		// Auth routes
+		r.Route("/auth", func(r chi.Router) {
+			// Basic auth
+			r.Post("/register", h.Register)
+			r.With(d.loginLimiter.Middleware).Post("/login", h.Login)
+			r.Post("/refresh", h.RefreshToken)
+			r.With(authService.RequireIdentity).Get("/me", h.GetMe)
+			r.With(authService.RequireAuth).Post("/introspect", h.Introspect)
+			r.With(authService.RequireAuth).Post("/password", h.ChangePassword)
+
+			// OAuth routes
+			r.Get("/oauth/providers", h.ListOAuthProviders)
+			r.Get("/oauth/{provider}", h.OAuthStart)
+			r.Get("/oauth/{provider}/callback", h.OAuthCallback)
+
+			// MFA routes - verify has aggressive rate limiting to prevent brute-force
+			r.With(authService.RequireAuth).Post("/mfa/setup", h.MFASetup)
+			r.With(authService.RequireAuth).Post("/mfa/enable", h.MFAEnable)
+			r.With(authService.RequireAuth).Get("/mfa/backup-codes/download", h.MFADownloadBackupCodes)
+			r.With(authService.RequireAuth).Get("/mfa/backup-codes/count", h.MFABackupCodeCount)
+			r.With(d.mfaLimiter.Middleware).Post("/mfa/verify", h.MFAVerify)
+			r.With(authService.RequireAuth).Post("/mfa/disable", h.MFADisable)
+
+			// Session routes
+			r.With(authService.RequireAuth).Get("/sessions", h.ListSessions)
+			r.With(authService.RequireAuth).Delete("/sessions/{id}", h.RevokeSession)
+			r.With(authService.RequireAuth).Delete("/sessions", h.RevokeAllSessions)
+		})
+
+		// Project routes
+		r.Route("/projects", func(r chi.Router) {
+			projectCache := d.responseCache.Middleware("projects")
+			r.With(authService.RequireAuth, projectCache).Get("/", h.ListProjects)
+			r.With(authService.RequireAuth, projectCache).Post("/", h.CreateProject)
+			r.With(authService.RequireAuth, projectCache).Get("/{id}", h.GetProject)
+			r.With(authService.RequireAuth, projectCache).Patch("/{id}", h.UpdateProject)
+
+			// Task routes
+			r.With(authService.RequireAuth).Post("/{id}/tasks", h.CreateTask)
+			r.With(authService.RequireAuth).Get("/{id}/tasks", h.ListTasks)
+			r.With(authService.RequireAuth).Get("/{id}/tasks/{taskID}", h.GetTask)
+			r.With(authService.RequireAuth).Patch("/{id}/tasks/{taskID}", h.UpdateTask)
+			r.With(authService.RequireAuth).Get("/{id}/dashboard", h.GetDashboard)
+			r.With(authService.RequireAuth).Get("/{id}/events/history", h.ListProjectEvents)
+
+			// Worker proxy routes (Workflow execution)
+			r.Group(func(r chi.Router) {
+				r.Use(authService.RequireAuth, h.ProjectInOrg)
+				r.Get("/{id}/specification", h.ProxyWorker)
+				r.Get("/{id}/code", h.ProxyWorker)
+				r.Get("/{id}/status", h.ProxyWorker)
+
+				// These may stream LLM output
+				r.Group(func(r chi.Router) {
+					r.Use(d.streaming)
+					r.Post("/{id}/generate", h.GenerateWorkflow)
+					r.Post("/{id}/approve", h.ProxyWorkerStream)
+					r.Post("/{id}/regenerate", h.ProxyWorkerStream)
+				})
+			})
+		})
+
+		// Worker callbacks, authenticated by X-Worker-Signature
+		r.Post("/worker/events", h.WorkerCallback)
+
+		// Cross-project task inbox
+		r.With(authService.RequireAuth).Get("/tasks", h.ListMyTasks)
+
+		// Several read-only requests in one round trip
+		r.With(authService.RequireAuth).Post("/batch", h.Batch)
+
+		// Organization routes
+		r.Route("/org", func(r chi.Router) {
+			r.Use(authService.RequireAuth)
+			r.Get("/", h.GetMyOrganization)
+			r.With(authService.RequireOrgAdmin).Post("/members", h.AddOrgMember)
+		})
+
+		// Admin routes
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(d.adminIPFilter.Middleware)
+			r.Get("/providers", h.GetProviders)
+			r.Route("/events", func(r chi.Router) {
+				r.Use(authService.RequireAdmin)
+				r.Get("/dlq", h.ListDeadLetters)
+				r.Post("/dlq/replay", h.ReplayDeadLetters)
+				r.Post("/replay", h.ReplayEvents)
+			})
+			r.With(authService.RequireAdmin).Post("/users/{id}/mfa/reset", h.AdminResetMFA)
+			r.Route("/ratelimit", func(r chi.Router) {
+				r.Use(authService.RequireAdmin)
+				r.Get("/{ip}", h.GetRateLimit)
+				r.Delete("/{ip}", h.ResetRateLimit)
+			})
+			r.With(authService.RequireAdmin).Get("/audit/export", h.ExportAudit)
+			r.With(authService.RequireAdmin).Get("/projects", h.AdminListProjects)
+			r.With(authService.RequireAdmin).Post("/orgs", h.CreateOrganization)
+			r.With(authService.RequireAdmin).Get("/runtime", h.Runtime)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kyros-praxis/gateway/internal/handlers"
+	"github.com/kyros-praxis/gateway/internal/middleware"
+)
+
+func TestV1RoutesMountedAtPrefixAndRoot(t *testing.T) {
+	api := routeDeps{h: &handlers.Handler{}, streaming: middleware.Streaming(0)}
+
+	r := chi.NewRouter()
+	r.Route("/v1", v1Routes(api))
+	r.Group(v1Routes(api))
+
+	routes := make(map[string]bool)
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[method+" "+strings.TrimSuffix(route, "/")] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk failed: %v", err)
+	}
+
+	var versioned int
+	for route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		rootPath, ok := strings.CutPrefix(path, "/v1")
+		if !ok {
+			continue
+		}
+		versioned++
+		if !routes[method+" "+rootPath] {
+			t.Errorf("%s %s has no root counterpart", method, path)
+		}
+	}
+	if versioned == 0 {
+		t.Fatal("no routes mounted under /v1")
+	}
+}
+
+func TestVersionedRouteLimits(t *testing.T) {
+	api := routeDeps{h: &handlers.Handler{}, streaming: middleware.Streaming(0)}
+	r := chi.NewRouter()
+	r.Route("/v1", v1Routes(api))
+
+	got, err := versionedRouteLimits(r, map[string]int{"/auth/login": 10, "/projects": 200})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, pattern := range []string{"/auth/login", "/v1/auth/login", "/projects", "/v1/projects"} {
+		if got[pattern] == 0 {
+			t.Errorf("missing limit for %s", pattern)
+		}
+	}
+
+	if _, err := versionedRouteLimits(r, map[string]int{"/no/such/route": 5}); err == nil {
+		t.Error("expected an error for an unregistered pattern")
+	}
+}
+
+func TestCheckRouteAuth(t *testing.T) {
+	api := routeDeps{h: &handlers.Handler{}, streaming: middleware.Streaming(0)}
+	r := chi.NewRouter()
+	r.Route("/v1", v1Routes(api))
+	r.Group(v1Routes(api))
+
+	public, protected, err := checkRouteAuth(r, authMiddleware(api.auth))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, route := range []string{"POST /auth/login", "GET /auth/oauth/{provider}/callback"} {
+		if !slices.Contains(public, route) {
+			t.Errorf("%s not reported as public", route)
+		}
+	}
+	for _, route := range []string{"GET /projects", "GET /projects/{id}", "GET /projects/{id}/tasks", "POST /admin/orgs", "GET /admin/projects", "GET /admin/runtime"} {
+		if !slices.Contains(protected, route) {
+			t.Errorf("%s not reported as protected", route)
+		}
+	}
+	if slices.Contains(public, "GET /v1/auth/login") || slices.Contains(protected, "GET /v1/projects") {
+		t.Error("versioned routes should be reported in their root form")
+	}
+
+	r.Get("/debug/leak", func(http.ResponseWriter, *http.Request) {})
+	if _, _, err := checkRouteAuth(r, authMiddleware(api.auth)); err == nil || !strings.Contains(err.Error(), "GET /debug/leak") {
+		t.Errorf("expected an error naming the unlisted route, got %v", err)
+	}
+}
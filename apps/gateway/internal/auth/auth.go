@@ -4,12 +4,20 @@ package auth
 import (
 	"context"
 	"errors"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/kyros-praxis/gateway/internal/config"
 	"github.com/kyros-praxis/gateway/internal/db"
 	"github.com/kyros-praxis/gateway/internal/models"
@@ -19,24 +27,77 @@ import (
 // Context key for storing user in request context.
 type contextKey string
 
-const UserContextKey contextKey = "user"
+const (
+	UserContextKey   contextKey = "user"
+	ClaimsContextKey contextKey = "claims"
+)
+
+// Token types carried in Claims.TokenType.
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+	// TokenTypeMFAPending marks the challenge issued by a password login for
+	// a user with MFA; it only proves the password step.
+	TokenTypeMFAPending = "mfa_pending"
+)
+
+// MFAChallengeTTL is how long a user has to complete MFA after the password
+// step of a login.
+const MFAChallengeTTL = 5 * time.Minute
+
+// MFAMaxAttempts is how many codes one MFA challenge token may be tried with
+// before it is refused and the user has to log in again.
+const MFAMaxAttempts = 5
+
+// ErrWrongTokenType is returned when a valid token is used where the other
+// token type is required, such as a refresh token sent as a bearer token.
+var ErrWrongTokenType = errors.New("wrong token type")
 
-// Claims represents the JWT claims.
+// Claims represents the JWT claims. Role and Scopes are copied from the user
+// when the token is issued, so a role change takes effect on the next token.
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"sub"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"sub"`
+	Role      string    `json:"role,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	TokenType string    `json:"token_type,omitempty"` // One of the TokenType constants
+	SessionID string    `json:"sid,omitempty"`        // Session the login started; empty without Redis
 	jwt.RegisteredClaims
 }
 
+// roleScopes lists the scopes each role's tokens carry.
+var roleScopes = map[string][]string{
+	"user":    {"projects:read", "projects:write", "tasks:read", "tasks:write"},
+	"service": {"projects:read", "tasks:read", "tasks:write"},
+	"admin":   {"projects:read", "projects:write", "tasks:read", "tasks:write", "admin"},
+}
+
+// ScopesForRole returns the scopes granted to a role; unknown roles get none.
+func ScopesForRole(role string) []string {
+	return roleScopes[role]
+}
+
 // Auth provides authentication services.
 type Auth struct {
-	cfg *config.Config
-	db  *db.DB
+	cfg            *config.Config
+	db             *db.DB
+	trustedProxies []netip.Prefix
+	decisionLog    *slog.Logger                   // Debug log of auth outcomes; nil disables
+	sessions       atomic.Pointer[SessionManager] // Checks tokens' sessions; nil skips the check
 }
 
 // New creates a new Auth service.
 func New(cfg *config.Config, database *db.DB) *Auth {
-	return &Auth{cfg: cfg, db: database}
+	a := &Auth{cfg: cfg, db: database}
+	for _, cidr := range cfg.TrustedProxyCIDRs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			slog.Warn("ignoring invalid trusted proxy CIDR", "cidr", cidr, "error", err)
+			continue
+		}
+		a.trustedProxies = append(a.trustedProxies, prefix)
+	}
+	return a
 }
 
 // HashPassword hashes a password using bcrypt.
@@ -51,31 +112,38 @@ func CheckPassword(password, hash string) bool {
 	return err == nil
 }
 
-// CreateAccessToken creates a new JWT access token.
-func (a *Auth) CreateAccessToken(user *models.User) (string, error) {
-	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(a.cfg.JWTExpireDuration())),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   user.Email,
-		},
-	}
+// CreateAccessToken creates a new JWT access token belonging to sessionID,
+// which may be empty when the login has no session.
+func (a *Auth) CreateAccessToken(user *models.User, sessionID string) (string, error) {
+	return a.createToken(user, TokenTypeAccess, sessionID, a.cfg.JWTExpireDuration())
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(a.cfg.JWTSecretKey))
+// CreateRefreshToken creates a new JWT refresh token belonging to sessionID.
+func (a *Auth) CreateRefreshToken(user *models.User, sessionID string) (string, error) {
+	return a.createToken(user, TokenTypeRefresh, sessionID, a.cfg.JWTRefreshExpireDuration())
 }
 
-// CreateRefreshToken creates a new JWT refresh token.
-func (a *Auth) CreateRefreshToken(user *models.User) (string, error) {
+// CreateMFAChallengeToken creates the token a user with MFA exchanges, along
+// with a code, for real tokens at POST /auth/mfa/verify.
+func (a *Auth) CreateMFAChallengeToken(user *models.User) (string, error) {
+	return a.createToken(user, TokenTypeMFAPending, "", MFAChallengeTTL)
+}
+
+// createToken signs a token of the given type that expires after ttl.
+func (a *Auth) createToken(user *models.User, tokenType, sessionID string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Scopes:    ScopesForRole(user.Role),
+		TokenType: tokenType,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(a.cfg.JWTRefreshExpireDuration())),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
 			Subject:   user.Email,
+			ID:        uuid.NewString(), // Lets a refresh token be marked as used
 		},
 	}
 
@@ -83,15 +151,17 @@ func (a *Auth) CreateRefreshToken(user *models.User) (string, error) {
 	return token.SignedString([]byte(a.cfg.JWTSecretKey))
 }
 
-// ValidateToken validates a JWT token and returns the claims.
+// ValidateToken validates a JWT token and returns the claims. Tokens signed
+// with the current secret or any of JWT_PREVIOUS_SECRETS are accepted, so the
+// secret can be rotated without logging everyone out.
 func (a *Auth) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
+	token, err := a.parseToken(tokenString, a.cfg.JWTSecretKey)
+	for _, secret := range a.cfg.JWTPreviousSecrets {
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break
 		}
-		return []byte(a.cfg.JWTSecretKey), nil
-	})
-
+		token, err = a.parseToken(tokenString, secret)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -103,51 +173,297 @@ func (a *Auth) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
+// parseToken parses and verifies a token against one signing secret.
+func (a *Auth) parseToken(tokenString, secret string) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(secret), nil
+	}, jwt.WithLeeway(a.cfg.JWTLeeway()))
+}
+
+// ValidateAccessToken validates a token and rejects anything but an access token.
+func (a *Auth) ValidateAccessToken(tokenString string) (*Claims, error) {
+	return a.validateTyped(tokenString, TokenTypeAccess)
+}
+
+// ValidateRefreshToken validates a token and rejects anything but a refresh
+// token, for use by the token refresh flow.
+func (a *Auth) ValidateRefreshToken(tokenString string) (*Claims, error) {
+	return a.validateTyped(tokenString, TokenTypeRefresh)
+}
+
+// ValidateMFAChallengeToken validates a token and rejects anything but an MFA
+// challenge token.
+func (a *Auth) ValidateMFAChallengeToken(tokenString string) (*Claims, error) {
+	return a.validateTyped(tokenString, TokenTypeMFAPending)
+}
+
+func (a *Auth) validateTyped(tokenString, want string) (*Claims, error) {
+	claims, err := a.ValidateToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if a.tokenType(claims) != want {
+		return nil, ErrWrongTokenType
+	}
+	return claims, nil
+}
+
+// tokenType returns the claims' token type. Tokens issued before the claim
+// existed are classified by lifetime: anything outliving an access token
+// must be a refresh token.
+func (a *Auth) tokenType(claims *Claims) string {
+	if claims.TokenType != "" {
+		return claims.TokenType
+	}
+	if claims.ExpiresAt != nil && claims.IssuedAt != nil &&
+		claims.ExpiresAt.Sub(claims.IssuedAt.Time) > a.cfg.JWTExpireDuration() {
+		return TokenTypeRefresh
+	}
+	return TokenTypeAccess
+}
+
 // Middleware returns an HTTP middleware that authenticates requests.
 func (a *Auth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Pre-authenticated identity from a trusted edge proxy replaces JWT validation
+		if email, ok := a.trustedIdentity(r); ok {
+			user, err := a.db.GetUserByEmail(r.Context(), email)
+			if err != nil {
+				a.logDecision(r, userLookupOutcome(err), "source", "trusted_header", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !user.Active {
+				a.logDecision(r, "user_inactive", "source", "trusted_header", "user_id", user.ID)
+				next.ServeHTTP(w, r)
+				return
+			}
+			a.logDecision(r, "authenticated", "source", "trusted_header", "user_id", user.ID)
+			ctx := context.WithValue(r.Context(), UserContextKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// Try to get token from Authorization header
 		authHeader := r.Header.Get("Authorization")
-		var tokenString string
+		var tokenString, source string
 
-		if strings.HasPrefix(authHeader, "Bearer ") {
-			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+		if a.cfg.AuthBearer() && strings.HasPrefix(authHeader, "Bearer ") {
+			tokenString, source = strings.TrimPrefix(authHeader, "Bearer "), "bearer"
 		}
 
 		// Try to get token from cookie if not in header
-		if tokenString == "" {
-			if cookie, err := r.Cookie("access_token"); err == nil {
-				tokenString = cookie.Value
+		if tokenString == "" && a.cfg.AuthCookies() {
+			if cookie, err := r.Cookie(a.cfg.AccessTokenCookie); err == nil {
+				tokenString, source = cookie.Value, "cookie"
 			}
 		}
 
 		// If no token found, continue without user context
 		if tokenString == "" {
+			a.logDecision(r, "no_token")
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Validate token
-		claims, err := a.ValidateToken(tokenString)
+		// Validate token; refresh tokens can't authenticate requests
+		claims, err := a.ValidateAccessToken(tokenString)
 		if err != nil {
 			// Token invalid, continue without user context
+			a.logDecision(r, tokenFailureOutcome(err), "source", source, "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A revoked or evicted session signs out its tokens along with it
+		if a.SessionEnded(r.Context(), claims) {
+			a.logDecision(r, "session_ended", "source", source, "user_id", claims.UserID, "session_id", claims.SessionID)
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Get user from database
+		// With trusted claims the user is loaded on first use, so requests
+		// that only need the token's identity skip the database
+		if a.cfg.JWTTrustClaims && claimsIdentify(claims) {
+			a.logDecision(r, "authenticated", "source", source, "user_id", claims.UserID, "trusted_claims", true)
+			ctx := context.WithValue(r.Context(), UserContextKey, a.lazyUser(claims))
+			ctx = context.WithValue(ctx, ClaimsContextKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// Get user from database; deactivated accounts lose access immediately
 		user, err := a.db.GetUserByID(r.Context(), claims.UserID)
 		if err != nil {
+			a.logDecision(r, userLookupOutcome(err), "source", source, "user_id", claims.UserID, "error", err)
 			next.ServeHTTP(w, r)
 			return
 		}
+		if !user.Active {
+			a.logDecision(r, "user_inactive", "source", source, "user_id", user.ID)
+			next.ServeHTTP(w, r)
+			return
+		}
+		a.logDecision(r, "authenticated", "source", source, "user_id", user.ID)
 
-		// Add user to context
+		// Add user and claims to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
+		ctx = context.WithValue(ctx, ClaimsContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// SetDecisionLogger turns on Debug logging of each request's authentication
+// outcome (DEBUG_AUTH). A nil logger, the default, logs nothing.
+func (a *Auth) SetDecisionLogger(log *slog.Logger) {
+	a.decisionLog = log
+}
+
+// SetSessions sets the session manager used to check that a token's session
+// is still live. It is safe to call while serving, so Redis can come up
+// after startup; until then tokens are accepted without the check.
+func (a *Auth) SetSessions(sessions *SessionManager) {
+	a.sessions.Store(sessions)
+}
+
+// SessionEnded reports whether the session that claims belong to has been
+// revoked, evicted or has expired. Tokens issued without a session are never
+// ended, and a failed check lets the token through so a Redis outage doesn't
+// sign everyone out.
+func (a *Auth) SessionEnded(ctx context.Context, claims *Claims) bool {
+	sessions := a.sessions.Load()
+	if claims.SessionID == "" || sessions == nil {
+		return false
+	}
+	live, err := sessions.SessionExists(ctx, claims.SessionID)
+	if err != nil {
+		slog.Warn("failed to check token session", "session_id", claims.SessionID, "error", err)
+		return false
+	}
+	return !live
+}
+
+// logDecision records why a request did or didn't authenticate, for
+// diagnosing "why am I signed out" reports. Callers pass the outcome and the
+// token's source, never the token.
+func (a *Auth) logDecision(r *http.Request, outcome string, attrs ...any) {
+	log := a.decisionLog
+	if log == nil || !log.Enabled(r.Context(), slog.LevelDebug) {
+		return
+	}
+	log.Debug("auth decision", append([]any{
+		"outcome", outcome,
+		"request_id", chimw.GetReqID(r.Context()),
+		"method", r.Method,
+		"path", r.URL.Path,
+	}, attrs...)...)
+}
+
+// tokenFailureOutcome names why a token was rejected.
+func tokenFailureOutcome(err error) string {
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return "invalid_signature"
+	case errors.Is(err, jwt.ErrTokenMalformed):
+		return "malformed"
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return "not_yet_valid"
+	case errors.Is(err, ErrWrongTokenType):
+		return "wrong_token_type"
+	default:
+		return "invalid_token"
+	}
+}
+
+// userLookupOutcome tells a deleted user from a failed lookup.
+func userLookupOutcome(err error) string {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "user_not_found"
+	}
+	return "user_lookup_failed"
+}
+
+// trustedIdentity returns the user email from the trusted user header, but only
+// when the request comes directly from a trusted proxy. The header is stripped
+// from any other request so it can't be spoofed further downstream.
+func (a *Auth) trustedIdentity(r *http.Request) (string, bool) {
+	header := a.cfg.TrustedUserHeader
+	if header == "" {
+		return "", false
+	}
+
+	email := strings.TrimSpace(r.Header.Get(header))
+	if email == "" {
+		return "", false
+	}
+
+	if !a.isTrustedProxy(r.RemoteAddr) {
+		slog.Warn("ignoring trusted user header from untrusted address", "remote_addr", r.RemoteAddr)
+		r.Header.Del(header)
+		return "", false
+	}
+
+	return email, true
+}
+
+// isTrustedProxy reports whether remoteAddr falls within a trusted proxy network.
+func (a *Auth) isTrustedProxy(remoteAddr string) bool {
+	addr, ok := parseAddr(remoteAddr)
+	return ok && a.trustedAddr(addr)
+}
+
+// trustedAddr reports whether addr falls within a trusted proxy network.
+func (a *Auth) trustedAddr(addr netip.Addr) bool {
+	for _, prefix := range a.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent r. X-Forwarded-For is
+// only honoured when the request arrives from a trusted proxy; the list is
+// then walked from the right, skipping trusted hops, so a client can't spoof
+// its address by prepending entries. The zero Addr means it couldn't be parsed.
+func (a *Auth) ClientIP(r *http.Request) netip.Addr {
+	addr, ok := parseAddr(r.RemoteAddr)
+	if !ok || !a.trustedAddr(addr) {
+		return addr
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, ok := parseAddr(strings.TrimSpace(hops[i]))
+		if !ok {
+			break
+		}
+		addr = hop
+		if !a.trustedAddr(hop) {
+			break
+		}
+	}
+	return addr
+}
+
+// parseAddr parses an IP address, with or without a port.
+func parseAddr(s string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(s)
+	if err != nil {
+		host = s
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
 // RequireAuth returns a middleware that requires authentication.
 func (a *Auth) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -160,11 +476,164 @@ func (a *Auth) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
-// GetUserFromContext retrieves the user from the request context.
-func GetUserFromContext(ctx context.Context) *models.User {
-	user, ok := ctx.Value(UserContextKey).(*models.User)
+// RequireIdentity is RequireAuth for handlers that only need the caller's
+// identity: with JWT_TRUST_CLAIMS it accepts the token's claims without
+// loading the user.
+func (a *Auth) RequireIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetIdentityFromContext(r.Context()) == nil {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireAdmin returns a middleware that requires an authenticated admin user.
+func (a *Auth) RequireAdmin(next http.Handler) http.Handler {
+	return a.RequireRole("admin")(next)
+}
+
+// RequireRole returns a middleware that requires an authenticated user with
+// the given role. The role comes from the token's claims when present, and
+// from the loaded user otherwise (trusted-proxy identities, older tokens).
+func (a *Auth) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			if user == nil {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+			if requestRole(r.Context(), user) != role {
+				http.Error(w, `{"error":"`+roleRequiredMessage(role)+`"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope returns a middleware that requires an authenticated user
+// whose token grants scope, falling back to the user's role scopes.
+func (a *Auth) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := GetUserFromContext(r.Context())
+			if user == nil {
+				http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+				return
+			}
+			if !slices.Contains(requestScopes(r.Context(), user), scope) {
+				http.Error(w, `{"error":"Insufficient scope"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestRole returns the role from the request's claims, or the user's.
+func requestRole(ctx context.Context, user *models.User) string {
+	if claims := GetClaimsFromContext(ctx); claims != nil && claims.Role != "" {
+		return claims.Role
+	}
+	return user.Role
+}
+
+// requestScopes returns the scopes from the request's claims, or those of
+// the user's role.
+func requestScopes(ctx context.Context, user *models.User) []string {
+	if claims := GetClaimsFromContext(ctx); claims != nil && claims.Scopes != nil {
+		return claims.Scopes
+	}
+	return ScopesForRole(user.Role)
+}
+
+// roleRequiredMessage keeps the established wording for admin checks.
+func roleRequiredMessage(role string) string {
+	if role == "admin" {
+		return "Admin access required"
+	}
+	return "Insufficient role"
+}
+
+// GetClaimsFromContext retrieves the validated token claims from the request
+// context. It returns nil for requests not authenticated by a JWT.
+func GetClaimsFromContext(ctx context.Context) *Claims {
+	claims, ok := ctx.Value(ClaimsContextKey).(*Claims)
 	if !ok {
 		return nil
 	}
-	return user
+	return claims
+}
+
+// GetUserFromContext retrieves the user from the request context. A user
+// deferred by JWT_TRUST_CLAIMS is loaded from the database on the first call.
+func GetUserFromContext(ctx context.Context) *models.User {
+	switch user := ctx.Value(UserContextKey).(type) {
+	case *models.User:
+		return user
+	case *deferredUser:
+		return user.get(ctx)
+	}
+	return nil
+}
+
+// GetIdentityFromContext returns the authenticated user without forcing a
+// database load: a deferred user that hasn't been loaded yet is built from
+// the token claims and carries only ID, email and role.
+func GetIdentityFromContext(ctx context.Context) *models.User {
+	if user, ok := ctx.Value(UserContextKey).(*deferredUser); ok {
+		return user.identity()
+	}
+	return GetUserFromContext(ctx)
+}
+
+// deferredUser stands in for the user when the token claims are trusted.
+// The database lookup, with its deactivation check, runs at most once.
+type deferredUser struct {
+	claims *Claims
+	load   func(ctx context.Context) *models.User
+
+	mu     sync.Mutex
+	loaded bool
+	user   *models.User
+}
+
+func (a *Auth) lazyUser(claims *Claims) *deferredUser {
+	return &deferredUser{
+		claims: claims,
+		load: func(ctx context.Context) *models.User {
+			user, err := a.db.GetUserByID(ctx, claims.UserID)
+			if err != nil || !user.Active {
+				return nil
+			}
+			return user
+		},
+	}
+}
+
+func (d *deferredUser) get(ctx context.Context) *models.User {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.loaded {
+		d.user, d.loaded = d.load(ctx), true
+	}
+	return d.user
+}
+
+func (d *deferredUser) identity() *models.User {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.loaded {
+		return d.user
+	}
+	return &models.User{ID: d.claims.UserID, Email: d.claims.Email, Role: d.claims.Role, Active: true}
+}
+
+// claimsIdentify reports whether claims carry enough to identify the user
+// without a lookup; tokens issued before roles were embedded don't.
+func claimsIdentify(claims *Claims) bool {
+	return claims.UserID != uuid.Nil && claims.Email != "" && claims.Role != ""
 }
@@ -0,0 +1,501 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestTrustedIdentity(t *testing.T) {
+	const header = "X-Authenticated-User"
+	a := New(&config.Config{
+		TrustedUserHeader: header,
+		TrustedProxyCIDRs: []string{"10.0.0.0/8", "not-a-cidr"},
+	}, nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		value      string
+		wantEmail  string
+		wantOK     bool
+	}{
+		{"trusted proxy", "10.1.2.3:5555", "demo@example.com", "demo@example.com", true},
+		{"ipv4-mapped trusted proxy", "[::ffff:10.1.2.3]:5555", "demo@example.com", "demo@example.com", true},
+		{"untrusted address", "203.0.113.7:5555", "demo@example.com", "", false},
+		{"no header", "10.1.2.3:5555", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.value != "" {
+				r.Header.Set(header, tt.value)
+			}
+
+			email, ok := a.trustedIdentity(r)
+			if ok != tt.wantOK || email != tt.wantEmail {
+				t.Fatalf("got (%q, %v), want (%q, %v)", email, ok, tt.wantEmail, tt.wantOK)
+			}
+			if !ok && r.Header.Get(header) != "" {
+				t.Fatal("untrusted header was not stripped")
+			}
+		})
+	}
+}
+
+func TestTrustedIdentityDisabledByDefault(t *testing.T) {
+	a := New(&config.Config{}, nil)
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Authenticated-User", "demo@example.com")
+
+	if _, ok := a.trustedIdentity(r); ok {
+		t.Fatal("trusted identity should be disabled without configuration")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	a := New(&config.Config{TrustedProxyCIDRs: []string{"10.0.0.0/8"}}, nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5555", "", "203.0.113.7"},
+		{"untrusted peer can't forward", "203.0.113.7:5555", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.2:5555", "198.51.100.1", "198.51.100.1"},
+		{"spoofed prefix ignored", "10.0.0.2:5555", "1.2.3.4, 198.51.100.1, 10.0.0.3", "198.51.100.1"},
+		{"garbage header stops walk", "10.0.0.2:5555", "nonsense", "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := a.ClientIP(r).String(); got != tt.want {
+				t.Errorf("ClientIP = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccessTokenCarriesRoleAndScopes(t *testing.T) {
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15}, nil)
+	user := &models.User{ID: uuid.New(), Email: "admin@example.com", Role: "admin"}
+
+	token, err := a.CreateAccessToken(user, "")
+	if err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	claims, err := a.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("validate token: %v", err)
+	}
+	if claims.Role != "admin" {
+		t.Errorf("role = %q, want admin", claims.Role)
+	}
+	if !slices.Equal(claims.Scopes, ScopesForRole("admin")) {
+		t.Errorf("scopes = %v, want %v", claims.Scopes, ScopesForRole("admin"))
+	}
+}
+
+func TestTokenCarriesSessionID(t *testing.T) {
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15, JWTRefreshExpireDays: 7}, nil)
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+	sessionID := uuid.NewString()
+
+	token, err := a.CreateRefreshToken(user, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := a.ValidateRefreshToken(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SessionID != sessionID {
+		t.Errorf("sid = %q, want %q", claims.SessionID, sessionID)
+	}
+	// Without Redis there is nothing to check the session against
+	if a.SessionEnded(context.Background(), claims) {
+		t.Error("session ended without a session manager")
+	}
+}
+
+func TestRequireRoleAndScopePreferClaims(t *testing.T) {
+	a := New(&config.Config{}, nil)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	// The loaded user is an admin, but the token was issued for a plain user
+	user := &models.User{ID: uuid.New(), Role: "admin"}
+	userClaims := &Claims{Role: "user", Scopes: ScopesForRole("user")}
+
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		claims     *Claims
+		want       int
+	}{
+		{"role from claims", a.RequireRole("admin"), userClaims, http.StatusForbidden},
+		{"role falls back to user", a.RequireRole("admin"), nil, http.StatusOK},
+		{"scope from claims", a.RequireScope("admin"), userClaims, http.StatusForbidden},
+		{"scope granted by claims", a.RequireScope("tasks:write"), userClaims, http.StatusOK},
+		{"scope falls back to role", a.RequireScope("admin"), nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), UserContextKey, user)
+			if tt.claims != nil {
+				ctx = context.WithValue(ctx, ClaimsContextKey, tt.claims)
+			}
+			rec := httptest.NewRecorder()
+			tt.middleware(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTokenClockSkewLeeway(t *testing.T) {
+	const secret = "test-secret"
+	sign := func(exp time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(exp)},
+		})
+		s, err := token.SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		leeway  int
+		expired time.Duration
+		valid   bool
+	}{
+		{"within leeway", 30, 10 * time.Second, true},
+		{"beyond leeway", 30, time.Minute, false},
+		{"no leeway", 0, 10 * time.Second, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := New(&config.Config{JWTSecretKey: secret, JWTLeewaySeconds: tt.leeway}, nil)
+			_, err := a.ValidateToken(sign(time.Now().Add(-tt.expired)))
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateToken error = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestTokenTypeCrossUseRejected(t *testing.T) {
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15, JWTRefreshExpireDays: 7}, nil)
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+
+	access, err := a.CreateAccessToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refresh, err := a.CreateRefreshToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateAccessToken(access); err != nil {
+		t.Errorf("access token rejected as access token: %v", err)
+	}
+	if _, err := a.ValidateRefreshToken(refresh); err != nil {
+		t.Errorf("refresh token rejected as refresh token: %v", err)
+	}
+	if _, err := a.ValidateAccessToken(refresh); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("refresh token used as access token: err = %v, want ErrWrongTokenType", err)
+	}
+	if _, err := a.ValidateRefreshToken(access); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("access token used as refresh token: err = %v, want ErrWrongTokenType", err)
+	}
+
+	// An MFA challenge only works for the second login step
+	challenge, err := a.CreateMFAChallengeToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ValidateMFAChallengeToken(challenge); err != nil {
+		t.Errorf("MFA challenge rejected as MFA challenge: %v", err)
+	}
+	if _, err := a.ValidateAccessToken(challenge); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("MFA challenge used as access token: err = %v, want ErrWrongTokenType", err)
+	}
+	if _, err := a.ValidateMFAChallengeToken(access); !errors.Is(err, ErrWrongTokenType) {
+		t.Errorf("access token used as MFA challenge: err = %v, want ErrWrongTokenType", err)
+	}
+
+	// Refresh tokens carry a unique ID so each can be used once
+	again, err := a.CreateRefreshToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := a.ValidateRefreshToken(refresh)
+	second, _ := a.ValidateRefreshToken(again)
+	if first == nil || second == nil || first.ID == "" || first.ID == second.ID {
+		t.Errorf("refresh token IDs not unique: %v, %v", first, second)
+	}
+
+	// The request middleware must not authenticate a refresh token
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+refresh)
+	a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetUserFromContext(r.Context()) != nil {
+			t.Error("refresh token authenticated a request")
+		}
+	})).ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestUntypedTokensClassifiedByLifetime(t *testing.T) {
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15}, nil)
+	now := time.Now()
+	claims := func(ttl time.Duration) *Claims {
+		return &Claims{RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		}}
+	}
+
+	if got := a.tokenType(claims(15 * time.Minute)); got != TokenTypeAccess {
+		t.Errorf("15m token = %q, want access", got)
+	}
+	if got := a.tokenType(claims(7 * 24 * time.Hour)); got != TokenTypeRefresh {
+		t.Errorf("7d token = %q, want refresh", got)
+	}
+}
+
+func TestOrgScopeAndRequireOrgAdmin(t *testing.T) {
+	a := New(&config.Config{}, nil)
+	orgID := uuid.New()
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		wantOrg *uuid.UUID
+		status  int
+	}{
+		{"anonymous", nil, nil, http.StatusUnauthorized},
+		{"no organization", &models.User{ID: uuid.New(), OrgRole: models.OrgRoleAdmin}, nil, http.StatusForbidden},
+		{"org member", &models.User{ID: uuid.New(), OrgID: &orgID, OrgRole: models.OrgRoleMember}, &orgID, http.StatusForbidden},
+		{"org admin", &models.User{ID: uuid.New(), OrgID: &orgID, OrgRole: models.OrgRoleAdmin}, &orgID, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.user != nil {
+				r = r.WithContext(context.WithValue(r.Context(), UserContextKey, tt.user))
+			}
+
+			var gotOrg *uuid.UUID
+			h := a.OrgScope(a.RequireOrgAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+			probe := a.OrgScope(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotOrg = GetOrgIDFromContext(r.Context())
+			}))
+			probe.ServeHTTP(httptest.NewRecorder(), r)
+			if (gotOrg == nil) != (tt.wantOrg == nil) || (gotOrg != nil && *gotOrg != *tt.wantOrg) {
+				t.Errorf("org = %v, want %v", gotOrg, tt.wantOrg)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestTrustedClaimsDeferUserLoad(t *testing.T) {
+	// No database: any user lookup would panic
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15, JWTTrustClaims: true}, nil)
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+	token, err := a.CreateAccessToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/auth/me", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	a.Middleware(a.RequireIdentity(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := GetIdentityFromContext(r.Context())
+		if got.ID != user.ID || got.Email != user.Email || got.Role != user.Role {
+			t.Errorf("identity = %+v, want claims of %+v", got, user)
+		}
+	}))).ServeHTTP(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	// The deferred lookup runs once, and its result wins over the claims
+	loads := 0
+	stored := &models.User{ID: user.ID, Email: user.Email, Role: "admin", Active: true}
+	d := &deferredUser{claims: &Claims{UserID: user.ID, Email: user.Email, Role: "user"}, load: func(context.Context) *models.User {
+		loads++
+		return stored
+	}}
+	ctx := context.WithValue(context.Background(), UserContextKey, d)
+	if GetIdentityFromContext(ctx).Role != "user" {
+		t.Error("identity before load should come from claims")
+	}
+	GetUserFromContext(ctx)
+	if GetUserFromContext(ctx) != stored || GetIdentityFromContext(ctx) != stored || loads != 1 {
+		t.Errorf("loads = %d, want one load returning the stored user", loads)
+	}
+}
+
+func TestAuthModeSelectsTokenSources(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+
+	tests := []struct {
+		mode   string
+		bearer bool
+		cookie bool
+	}{
+		{"both", true, true},
+		{"token", true, false},
+		{"cookie", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			// Trusted claims keep the user lookup off the (absent) database
+			a := New(&config.Config{
+				JWTSecretKey:      "test-secret",
+				JWTExpireMinutes:  15,
+				JWTTrustClaims:    true,
+				AuthMode:          tt.mode,
+				AccessTokenCookie: "access_token",
+			}, nil)
+			token, err := a.CreateAccessToken(user, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			authenticated := func(r *http.Request) bool {
+				var ok bool
+				a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					ok = GetClaimsFromContext(r.Context()) != nil
+				})).ServeHTTP(httptest.NewRecorder(), r)
+				return ok
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/projects", nil)
+			r.Header.Set("Authorization", "Bearer "+token)
+			if got := authenticated(r); got != tt.bearer {
+				t.Errorf("bearer accepted = %v, want %v", got, tt.bearer)
+			}
+
+			r = httptest.NewRequest(http.MethodGet, "/projects", nil)
+			r.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+			if got := authenticated(r); got != tt.cookie {
+				t.Errorf("cookie accepted = %v, want %v", got, tt.cookie)
+			}
+		})
+	}
+}
+
+func TestMiddlewareLogsAuthDecisions(t *testing.T) {
+	var buf bytes.Buffer
+	// Trusted claims keep the user lookup off the (absent) database
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15, JWTTrustClaims: true}, nil)
+	a.SetDecisionLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+	valid, err := a.CreateAccessToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := a.createToken(user, TokenTypeAccess, "", -time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged, err := New(&config.Config{JWTSecretKey: "other-secret", JWTExpireMinutes: 15}, nil).CreateAccessToken(user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"no token", "", "outcome=no_token"},
+		{"expired", expired, "outcome=expired"},
+		{"bad signature", forged, "outcome=invalid_signature"},
+		{"valid", valid, "outcome=authenticated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			r := httptest.NewRequest(http.MethodGet, "/projects", nil)
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			a.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(httptest.NewRecorder(), r)
+
+			logged := buf.String()
+			if !strings.Contains(logged, tt.want) {
+				t.Errorf("log %q missing %s", logged, tt.want)
+			}
+			if tt.token != "" && strings.Contains(logged, tt.token) {
+				t.Error("log contains the token")
+			}
+		})
+	}
+}
+
+func TestValidateTokenAcceptsPreviousSecrets(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+	issue := func(secret string) string {
+		token, err := New(&config.Config{JWTSecretKey: secret, JWTExpireMinutes: 15}, nil).CreateAccessToken(user, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	a := New(&config.Config{JWTSecretKey: "new-secret", JWTPreviousSecrets: []string{"old-secret"}}, nil)
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"current secret", issue("new-secret"), true},
+		{"previous secret", issue("old-secret"), true},
+		{"dropped secret", issue("older-secret"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := a.ValidateToken(tt.token)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateToken error = %v, want valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backup code download errors.
+var (
+	ErrDownloadInvalid = errors.New("invalid backup code download token")
+	ErrDownloadExpired = errors.New("backup code download token expired")
+)
+
+// BackupCodeDownloads seals freshly generated backup codes into a token that
+// only the same user can redeem for a short time. Codes are stored hashed, so
+// this is the only way to fetch them as a file, and the plaintext never
+// touches the database or Redis.
+type BackupCodeDownloads struct {
+	aead cipher.AEAD
+	ttl  time.Duration
+}
+
+// downloadPayload is the sealed body of a download token.
+type downloadPayload struct {
+	UserID  uuid.UUID `json:"u"`
+	Codes   []string  `json:"c"`
+	Expires int64     `json:"e"`
+}
+
+// NewBackupCodeDownloads creates a download token issuer. The key is derived
+// from secret so the JWT secret can be reused without sharing ciphertexts.
+func NewBackupCodeDownloads(secret string, ttl time.Duration) *BackupCodeDownloads {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("mfa-backup-codes"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		panic(err) // A 32-byte key is always valid
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return &BackupCodeDownloads{aead: aead, ttl: ttl}
+}
+
+// Issue seals codes into a URL-safe token redeemable by userID until the TTL
+// passes.
+func (d *BackupCodeDownloads) Issue(userID uuid.UUID, codes []string) (string, error) {
+	body, err := json.Marshal(downloadPayload{
+		UserID:  userID,
+		Codes:   codes,
+		Expires: time.Now().Add(d.ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, d.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := d.aead.Seal(nonce, nonce, body, userID[:])
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Open returns the codes sealed in token if it was issued to userID and has
+// not expired.
+func (d *BackupCodeDownloads) Open(token string, userID uuid.UUID) ([]string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(sealed) < d.aead.NonceSize() {
+		return nil, ErrDownloadInvalid
+	}
+
+	nonce, ciphertext := sealed[:d.aead.NonceSize()], sealed[d.aead.NonceSize():]
+	body, err := d.aead.Open(nil, nonce, ciphertext, userID[:])
+	if err != nil {
+		return nil, ErrDownloadInvalid // Tampered, or issued to another user
+	}
+
+	var payload downloadPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.UserID != userID {
+		return nil, ErrDownloadInvalid
+	}
+	if time.Now().Unix() >= payload.Expires {
+		return nil, ErrDownloadExpired
+	}
+	return payload.Codes, nil
+}
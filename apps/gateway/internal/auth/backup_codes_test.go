@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBackupCodeDownloads(t *testing.T) {
+	d := NewBackupCodeDownloads("test-secret", time.Minute)
+	owner, other := uuid.New(), uuid.New()
+	codes := []string{"AAAA-1111", "BBBB-2222"}
+
+	token, err := d.Issue(owner, codes)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+
+	got, err := d.Open(token, owner)
+	if err != nil || !slices.Equal(got, codes) {
+		t.Fatalf("Open(owner) = %v, %v; want %v", got, err, codes)
+	}
+	if _, err := d.Open(token, other); !errors.Is(err, ErrDownloadInvalid) {
+		t.Errorf("Open(other user) error = %v, want ErrDownloadInvalid", err)
+	}
+	if _, err := d.Open(token[:len(token)-2]+"xx", owner); !errors.Is(err, ErrDownloadInvalid) {
+		t.Errorf("Open(tampered) error = %v, want ErrDownloadInvalid", err)
+	}
+	if _, err := NewBackupCodeDownloads("other-secret", time.Minute).Open(token, owner); !errors.Is(err, ErrDownloadInvalid) {
+		t.Errorf("Open(other key) error = %v, want ErrDownloadInvalid", err)
+	}
+
+	expired, err := NewBackupCodeDownloads("test-secret", -time.Second).Issue(owner, codes)
+	if err != nil {
+		t.Fatalf("issue failed: %v", err)
+	}
+	if _, err := d.Open(expired, owner); !errors.Is(err, ErrDownloadExpired) {
+		t.Errorf("Open(expired) error = %v, want ErrDownloadExpired", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,13 +22,14 @@ import (
 
 // OAuthUser represents a user returned from an OAuth provider.
 type OAuthUser struct {
-	ProviderID   string `json:"provider_id"`
-	Provider     string `json:"provider"`
-	Email        string `json:"email"`
-	Name         string `json:"name"`
-	AvatarURL    string `json:"avatar_url"`
-	AccessToken  string `json:"-"`
-	RefreshToken string `json:"-"`
+	ProviderID    string `json:"provider_id"`
+	Provider      string `json:"provider"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"` // The provider vouches that Email belongs to this account
+	Name          string `json:"name"`
+	AvatarURL     string `json:"avatar_url"`
+	AccessToken   string `json:"-"`
+	RefreshToken  string `json:"-"`
 }
 
 // OAuthProvider defines the interface for OAuth providers.
@@ -97,6 +99,11 @@ func (m *OAuthManager) GetProvider(name string) (OAuthProvider, error) {
 	return p, nil
 }
 
+// Register adds a provider, replacing any with the same name.
+func (m *OAuthManager) Register(p OAuthProvider) {
+	m.providers[p.Name()] = p
+}
+
 // ListProviders returns the names of all configured providers.
 func (m *OAuthManager) ListProviders() []string {
 	names := make([]string, 0, len(m.providers))
@@ -150,23 +157,25 @@ func (p *GoogleProvider) ExchangeCode(ctx context.Context, code string) (*OAuthU
 	}
 
 	var info struct {
-		ID      string `json:"id"`
-		Email   string `json:"email"`
-		Name    string `json:"name"`
-		Picture string `json:"picture"`
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
 	}
 	if err := json.Unmarshal(body, &info); err != nil {
 		return nil, fmt.Errorf("failed to parse user info: %w", err)
 	}
 
 	return &OAuthUser{
-		ProviderID:   info.ID,
-		Provider:     "google",
-		Email:        info.Email,
-		Name:         info.Name,
-		AvatarURL:    info.Picture,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
+		ProviderID:    info.ID,
+		Provider:      "google",
+		Email:         info.Email,
+		EmailVerified: info.VerifiedEmail,
+		Name:          info.Name,
+		AvatarURL:     info.Picture,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
 	}, nil
 }
 
@@ -227,13 +236,14 @@ func (p *GitHubProvider) ExchangeCode(ctx context.Context, code string) (*OAuthU
 	}
 
 	return &OAuthUser{
-		ProviderID:   fmt.Sprintf("%d", info.ID),
-		Provider:     "github",
-		Email:        email,
-		Name:         name,
-		AvatarURL:    info.AvatarURL,
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
+		ProviderID:    fmt.Sprintf("%d", info.ID),
+		Provider:      "github",
+		Email:         email,
+		EmailVerified: true, // fetchPrimaryEmail only returns verified addresses
+		Name:          name,
+		AvatarURL:     info.AvatarURL,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
 	}, nil
 }
 
@@ -269,19 +279,35 @@ func (p *GitHubProvider) fetchPrimaryEmail(client *http.Client) (string, error)
 
 // ---- State Store ----
 
+// OAuthStateManager issues and verifies OAuth state tokens.
+type OAuthStateManager interface {
+	// Issue creates a state token for a login flow with the given provider,
+	// carrying where to send the browser afterwards ("" for the default).
+	Issue(provider, returnTo string) (string, error)
+	// Verify checks a state token returned on the provider's callback and
+	// returns the return_to it was issued with.
+	Verify(state, provider string) (returnTo string, ok bool)
+}
+
 // OAuthStateStore stores OAuth state tokens in Redis for persistence and thread-safety.
 // Falls back to in-memory if Redis is not available.
 type OAuthStateStore struct {
 	redis    *redis.Client
-	fallback map[string]time.Time
+	fallback map[string]storedState
 	mu       sync.RWMutex // Only used for fallback
 }
 
+// storedState is an in-memory state token's expiry and return_to.
+type storedState struct {
+	expires  time.Time
+	returnTo string
+}
+
 // NewOAuthStateStore creates a new state store.
 // If redisURL is provided, uses Redis; otherwise falls back to in-memory.
 func NewOAuthStateStore() *OAuthStateStore {
 	return &OAuthStateStore{
-		fallback: make(map[string]time.Time),
+		fallback: make(map[string]storedState),
 	}
 }
 
@@ -290,15 +316,16 @@ func (s *OAuthStateStore) SetRedis(client *redis.Client) {
 	s.redis = client
 }
 
-// Store saves a state token with 10 minute expiration.
-func (s *OAuthStateStore) Store(state string) {
+// Store saves a state token and its return_to with 10 minute expiration.
+func (s *OAuthStateStore) Store(state, returnTo string) {
 	ctx := context.Background()
 	ttl := 10 * time.Minute
 
 	// Use Redis if available
 	if s.redis != nil {
 		key := "oauth_state:" + state
-		err := s.redis.Set(ctx, key, "1", ttl).Err()
+		// "1" marks the key as a state; return_to follows it
+		err := s.redis.Set(ctx, key, "1"+returnTo, ttl).Err()
 		if err == nil {
 			return
 		}
@@ -307,20 +334,21 @@ func (s *OAuthStateStore) Store(state string) {
 
 	// Fallback to in-memory
 	s.mu.Lock()
-	s.fallback[state] = time.Now().Add(ttl)
+	s.fallback[state] = storedState{expires: time.Now().Add(ttl), returnTo: returnTo}
 	s.mu.Unlock()
 }
 
-// Validate checks and removes a state token. Returns true if valid.
-func (s *OAuthStateStore) Validate(state string) bool {
+// Validate checks and removes a state token, returning its return_to and
+// whether it was valid.
+func (s *OAuthStateStore) Validate(state string) (string, bool) {
 	ctx := context.Background()
 
 	// Try Redis first
 	if s.redis != nil {
 		key := "oauth_state:" + state
 		result, err := s.redis.GetDel(ctx, key).Result()
-		if err == nil && result == "1" {
-			return true
+		if returnTo, ok := strings.CutPrefix(result, "1"); err == nil && ok {
+			return returnTo, true
 		}
 		if err != nil && err != redis.Nil {
 			// Log error but fall through to in-memory check
@@ -331,12 +359,30 @@ func (s *OAuthStateStore) Validate(state string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	exp, ok := s.fallback[state]
+	stored, ok := s.fallback[state]
 	if !ok {
-		return false
+		return "", false
 	}
 	delete(s.fallback, state)
-	return time.Now().Before(exp)
+	if !time.Now().Before(stored.expires) {
+		return "", false
+	}
+	return stored.returnTo, true
+}
+
+// Issue generates and stores a new state token.
+func (s *OAuthStateStore) Issue(provider, returnTo string) (string, error) {
+	state, err := GenerateState()
+	if err != nil {
+		return "", err
+	}
+	s.Store(state, returnTo)
+	return state, nil
+}
+
+// Verify validates and consumes a stored state token.
+func (s *OAuthStateStore) Verify(state, provider string) (string, bool) {
+	return s.Validate(state)
 }
 
 // Cleanup removes expired states from the in-memory fallback.
@@ -345,8 +391,8 @@ func (s *OAuthStateStore) Cleanup() {
 	defer s.mu.Unlock()
 
 	now := time.Now()
-	for state, exp := range s.fallback {
-		if now.After(exp) {
+	for state, stored := range s.fallback {
+		if now.After(stored.expires) {
 			delete(s.fallback, state)
 		}
 	}
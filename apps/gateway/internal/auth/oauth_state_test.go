@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignedOAuthState(t *testing.T) {
+	s := NewSignedOAuthState("secret", time.Minute)
+
+	state, err := s.Issue("github", "https://app.example.com/projects")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	if returnTo, ok := s.Verify(state, "github"); !ok || returnTo != "https://app.example.com/projects" {
+		t.Fatalf("Verify() = %q, %v, want the issued return_to", returnTo, ok)
+	}
+	if _, ok := s.Verify(state, "google"); ok {
+		t.Fatal("state accepted for a different provider")
+	}
+	if _, ok := NewSignedOAuthState("other-secret", time.Minute).Verify(state, "github"); ok {
+		t.Fatal("state accepted with a different key")
+	}
+	_, tampered := s.Verify(state+"x", "github")
+	_, garbage := s.Verify("garbage", "github")
+	if tampered || garbage {
+		t.Fatal("tampered state accepted")
+	}
+
+	expired := NewSignedOAuthState("secret", -time.Second)
+	old, _ := expired.Issue("github", "")
+	if _, ok := s.Verify(old, "github"); ok {
+		t.Fatal("expired state accepted")
+	}
+}
+
+func TestOAuthStateStoreCarriesReturnTo(t *testing.T) {
+	s := NewOAuthStateStore()
+	state, err := s.Issue("github", "https://app.example.com/projects")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if returnTo, ok := s.Verify(state, "github"); !ok || returnTo != "https://app.example.com/projects" {
+		t.Fatalf("Verify() = %q, %v, want the issued return_to", returnTo, ok)
+	}
+	if _, ok := s.Verify(state, "github"); ok {
+		t.Fatal("state accepted twice")
+	}
+}
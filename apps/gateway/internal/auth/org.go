@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// OrgContextKey stores the request's tenant, set by OrgScope.
+const OrgContextKey contextKey = "org"
+
+// orgScope is the tenant a request is confined to. A nil id is the shared
+// namespace of users and projects outside any organization.
+type orgScope struct {
+	id       *uuid.UUID
+	deferred *deferredUser // resolves id on first use when the user isn't loaded yet
+}
+
+func (s orgScope) orgID(ctx context.Context) *uuid.UUID {
+	if s.deferred != nil {
+		if user := s.deferred.get(ctx); user != nil {
+			return user.OrgID
+		}
+		return nil
+	}
+	return s.id
+}
+
+// OrgScope derives the request's tenant from the authenticated user and
+// stores it for GetOrgIDFromContext. Mount it after Middleware; anonymous
+// requests are confined to the untenanted namespace.
+func (a *Auth) OrgScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var scope orgScope
+		if deferred, ok := r.Context().Value(UserContextKey).(*deferredUser); ok {
+			scope.deferred = deferred
+		} else if user := GetUserFromContext(r.Context()); user != nil {
+			scope.id = user.OrgID
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), OrgContextKey, scope)))
+	})
+}
+
+// GetOrgIDFromContext returns the organization the request is scoped to, or
+// nil for the untenanted namespace. Without OrgScope it falls back to the
+// user's organization, so a missing middleware never widens access.
+func GetOrgIDFromContext(ctx context.Context) *uuid.UUID {
+	if scope, ok := ctx.Value(OrgContextKey).(orgScope); ok {
+		return scope.orgID(ctx)
+	}
+	if user := GetUserFromContext(ctx); user != nil {
+		return user.OrgID
+	}
+	return nil
+}
+
+// RequireOrgAdmin returns a middleware that requires an authenticated user
+// holding the admin role within their organization.
+func (a *Auth) RequireOrgAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := GetUserFromContext(r.Context())
+		if user == nil {
+			http.Error(w, `{"error":"Authentication required"}`, http.StatusUnauthorized)
+			return
+		}
+		if user.OrgID == nil || user.OrgRole != models.OrgRoleAdmin {
+			http.Error(w, `{"error":"Organization admin access required"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// RedirectAllowlist holds the URL prefixes the OAuth flow may send a browser
+// to after login (OAUTH_ALLOWED_REDIRECTS), so a crafted return_to can't turn
+// the gateway into an open redirect.
+type RedirectAllowlist struct {
+	prefixes []*url.URL
+}
+
+// NewRedirectAllowlist parses prefixes such as "https://app.example.com" or
+// "https://example.com/app". Each must be an absolute http(s) URL with a host
+// and nothing after the path.
+func NewRedirectAllowlist(prefixes []string) (*RedirectAllowlist, error) {
+	list := &RedirectAllowlist{}
+	for _, prefix := range prefixes {
+		u, err := parseRedirect(strings.TrimSpace(prefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid redirect prefix %q: %w", prefix, err)
+		}
+		if u.RawQuery != "" || u.Fragment != "" {
+			return nil, fmt.Errorf("invalid redirect prefix %q: query and fragment not allowed", prefix)
+		}
+		list.prefixes = append(list.prefixes, u)
+	}
+	return list, nil
+}
+
+// Allows reports whether target starts with an allowed prefix. Scheme and
+// host must match exactly and the path must sit at or below the prefix's on a
+// segment boundary, so "https://app.example.com.evil.io" and "/app-evil" don't
+// match "https://app.example.com" and "/app". Dot segments are refused outright.
+func (l *RedirectAllowlist) Allows(target string) bool {
+	u, err := parseRedirect(target)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range l.prefixes {
+		if u.Scheme != prefix.Scheme || !strings.EqualFold(u.Host, prefix.Host) {
+			continue
+		}
+		base := strings.TrimSuffix(prefix.Path, "/")
+		if base == "" || u.Path == base || strings.HasPrefix(u.Path, base+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRedirect parses an absolute http(s) URL, refusing the forms browsers
+// interpret differently from net/url: backslashes, control characters,
+// credentials and dot segments.
+func parseRedirect(raw string) (*url.URL, error) {
+	if strings.ContainsAny(raw, "\\\t\r\n") {
+		return nil, fmt.Errorf("unexpected character")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("scheme must be http or https")
+	}
+	if u.Host == "" || u.User != nil || u.Opaque != "" {
+		return nil, fmt.Errorf("must be an absolute URL with a host and no credentials")
+	}
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == "." || segment == ".." {
+			return nil, fmt.Errorf("dot segments not allowed")
+		}
+	}
+	return u, nil
+}
@@ -0,0 +1,43 @@
+package auth
+
+import "testing"
+
+func TestRedirectAllowlist(t *testing.T) {
+	list, err := NewRedirectAllowlist([]string{"https://app.example.com", "https://example.com/console/"})
+	if err != nil {
+		t.Fatalf("NewRedirectAllowlist: %v", err)
+	}
+
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://app.example.com/dashboard", true},
+		{"https://APP.example.com/dashboard?tab=1", true},
+		{"https://example.com/console", true},
+		{"https://example.com/console/projects", true},
+		{"http://app.example.com/dashboard", false},     // Scheme downgrade
+		{"https://app.example.com.evil.io/", false},     // Lookalike host
+		{"https://app.example.com@evil.io/", false},     // Credentials
+		{"https://example.com/console-evil", false},     // Not a segment boundary
+		{"https://example.com/console/../admin", false}, // Dot segments
+		{"https://example.com/console/%2e%2e/admin", false},
+		{"//evil.io/", false},        // Scheme-relative
+		{"/dashboard", false},        // Relative
+		{"https:\\\\evil.io", false}, // Backslashes
+		{"javascript:alert(1)", false},
+	}
+	for _, tt := range tests {
+		if got := list.Allows(tt.target); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestNewRedirectAllowlistRejectsBadPrefixes(t *testing.T) {
+	for _, prefix := range []string{"*", "app.example.com", "ftp://example.com", "https://example.com/?next=x"} {
+		if _, err := NewRedirectAllowlist([]string{prefix}); err == nil {
+			t.Errorf("NewRedirectAllowlist(%q) succeeded, want an error", prefix)
+		}
+	}
+}
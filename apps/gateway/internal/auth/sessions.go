@@ -4,10 +4,15 @@ package auth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/models"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -23,6 +28,25 @@ type Session struct {
 	ExpiresAt  time.Time `json:"expires_at"`
 }
 
+// MarshalJSON formats timestamps with models.TimeFormat, both in API
+// responses and in the Redis copy.
+func (s Session) MarshalJSON() ([]byte, error) {
+	type session Session
+	return json.Marshal(struct {
+		session
+		CreatedAt  models.JSONTime `json:"created_at"`
+		LastActive models.JSONTime `json:"last_active"`
+		ExpiresAt  models.JSONTime `json:"expires_at"`
+	}{session(s), models.JSONTime(s.CreatedAt), models.JSONTime(s.LastActive), models.JSONTime(s.ExpiresAt)})
+}
+
+// ErrInvalidRedisURL is returned by NewSessionManager when the Redis URL
+// can't be parsed. Retrying won't help, so the connect loops stop on it.
+var ErrInvalidRedisURL = errors.New("invalid Redis URL")
+
+// maxRedisBackoff caps the wait between Redis connection attempts.
+const maxRedisBackoff = 30 * time.Second
+
 // SessionManager manages user sessions in Redis.
 type SessionManager struct {
 	client     *redis.Client
@@ -37,7 +61,7 @@ func NewSessionManager(redisURL string, sessionTTL time.Duration) (*SessionManag
 
 	opts, err := redis.ParseURL(redisURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRedisURL, err)
 	}
 
 	client := redis.NewClient(opts)
@@ -47,6 +71,7 @@ func NewSessionManager(redisURL string, sessionTTL time.Duration) (*SessionManag
 	defer cancel()
 
 	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
@@ -56,6 +81,58 @@ func NewSessionManager(redisURL string, sessionTTL time.Duration) (*SessionManag
 	}, nil
 }
 
+// ConnectSessionManager calls NewSessionManager, retrying up to retries
+// more times while Redis is unreachable. The wait starts at interval and
+// doubles after each attempt, capped at 30 seconds.
+func ConnectSessionManager(ctx context.Context, redisURL string, sessionTTL time.Duration, retries int, interval time.Duration, log *slog.Logger) (*SessionManager, error) {
+	wait := interval
+	for attempt := 1; ; attempt++ {
+		m, err := NewSessionManager(redisURL, sessionTTL)
+		if err == nil || errors.Is(err, ErrInvalidRedisURL) || attempt > retries {
+			return m, err
+		}
+
+		log.Warn("redis not reachable, retrying",
+			"attempt", attempt,
+			"max_attempts", retries+1,
+			"retry_in", wait,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, max(interval, maxRedisBackoff))
+	}
+}
+
+// ReconnectSessionManager keeps calling NewSessionManager in the background
+// until Redis is reachable, then passes the manager to onConnect. It gives
+// up when ctx is cancelled or the URL is invalid.
+func ReconnectSessionManager(ctx context.Context, redisURL string, sessionTTL time.Duration, interval time.Duration, log *slog.Logger, onConnect func(*SessionManager)) {
+	wait := max(interval, time.Second)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		m, err := NewSessionManager(redisURL, sessionTTL)
+		if err == nil {
+			onConnect(m)
+			return
+		}
+		if errors.Is(err, ErrInvalidRedisURL) {
+			log.Error("session manager reconnect stopped", "error", err)
+			return
+		}
+		log.Debug("redis still not reachable", "retry_in", wait, "error", err)
+		wait = min(wait*2, max(interval, maxRedisBackoff))
+	}
+}
+
 // Close closes the Redis connection.
 func (m *SessionManager) Close() error {
 	if m.client != nil {
@@ -69,6 +146,28 @@ func sessionKey(sessionID string) string {
 	return fmt.Sprintf("session:%s", sessionID)
 }
 
+// usedRefreshTokenKey returns the Redis key marking a refresh token as
+// exchanged.
+func usedRefreshTokenKey(tokenID string) string {
+	return fmt.Sprintf("refresh_used:%s", tokenID)
+}
+
+// mfaAttemptsKey returns the Redis key counting the codes tried against an
+// MFA challenge token.
+func mfaAttemptsKey(challengeID string) string {
+	return fmt.Sprintf("mfa_attempts:%s", challengeID)
+}
+
+// loginFailuresKey and loginLockKey return the Redis keys holding an email's
+// consecutive failed logins and its lockout.
+func loginFailuresKey(email string) string {
+	return fmt.Sprintf("login_failures:%s", strings.ToLower(email))
+}
+
+func loginLockKey(email string) string {
+	return fmt.Sprintf("login_lock:%s", strings.ToLower(email))
+}
+
 // userSessionsKey returns the Redis key for a user's session list.
 func userSessionsKey(userID string) string {
 	return fmt.Sprintf("user_sessions:%s", userID)
@@ -134,6 +233,20 @@ func (m *SessionManager) GetSession(ctx context.Context, sessionID string) (*Ses
 	return &session, nil
 }
 
+// SessionExists reports whether a session is still live, i.e. it has
+// neither expired nor been revoked.
+func (m *SessionManager) SessionExists(ctx context.Context, sessionID string) (bool, error) {
+	if m == nil {
+		return false, nil
+	}
+
+	n, err := m.client.Exists(ctx, sessionKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+	return n > 0, nil
+}
+
 // UpdateLastActive updates the last active time of a session.
 func (m *SessionManager) UpdateLastActive(ctx context.Context, sessionID string) error {
 	if m == nil {
@@ -189,6 +302,158 @@ func (m *SessionManager) ListUserSessions(ctx context.Context, userID string) ([
 	return sessions, nil
 }
 
+// PruneStaleSessionRefs removes IDs of expired sessions from every user's
+// session set and returns how many it removed. ListUserSessions only cleans
+// up the set of the user asking, so sets of users who never list their
+// sessions would otherwise keep growing.
+func (m *SessionManager) PruneStaleSessionRefs(ctx context.Context) (int, error) {
+	if m == nil {
+		return 0, nil
+	}
+
+	pruned := 0
+	iter := m.client.Scan(ctx, 0, userSessionsKey("*"), 100).Iterator()
+	for iter.Next(ctx) {
+		n, err := m.pruneSessionSet(ctx, iter.Val())
+		pruned += n
+		if err != nil {
+			return pruned, err
+		}
+	}
+	return pruned, iter.Err()
+}
+
+// pruneSessionSet removes the members of one user's session set whose
+// session key no longer exists. Sessions are stored before they are added
+// to the set, so a session being created is never removed.
+func (m *SessionManager) pruneSessionSet(ctx context.Context, setKey string) (int, error) {
+	ids, err := m.client.SMembers(ctx, setKey).Result()
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	pipe := m.client.Pipeline()
+	exists := make([]*redis.IntCmd, len(ids))
+	for i, id := range ids {
+		exists[i] = pipe.Exists(ctx, sessionKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to check sessions: %w", err)
+	}
+
+	var stale []interface{}
+	for i, cmd := range exists {
+		if cmd.Val() == 0 {
+			stale = append(stale, ids[i])
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	removed, err := m.client.SRem(ctx, setKey, stale...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune sessions: %w", err)
+	}
+	return int(removed), nil
+}
+
+// LoginLockedFor returns how much longer logins for email are locked, or 0.
+func (m *SessionManager) LoginLockedFor(ctx context.Context, email string) (time.Duration, error) {
+	if m == nil {
+		return 0, nil
+	}
+
+	ttl, err := m.client.PTTL(ctx, loginLockKey(email)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to check login lock: %w", err)
+	}
+	return max(ttl, 0), nil // Negative when there is no lock
+}
+
+// RecordLoginFailure counts a failed login for email. On the maxAttempts-th
+// consecutive failure it locks the email for lockout and reports true. The
+// count is forgotten after lockout without failures.
+func (m *SessionManager) RecordLoginFailure(ctx context.Context, email string, maxAttempts int, lockout time.Duration) (bool, error) {
+	if m == nil {
+		return false, nil
+	}
+
+	pipe := m.client.TxPipeline()
+	failures := pipe.Incr(ctx, loginFailuresKey(email))
+	pipe.Expire(ctx, loginFailuresKey(email), lockout)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	if failures.Val() < int64(maxAttempts) {
+		return false, nil
+	}
+
+	pipe = m.client.TxPipeline()
+	pipe.Set(ctx, loginLockKey(email), time.Now().Unix(), lockout)
+	pipe.Del(ctx, loginFailuresKey(email))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, fmt.Errorf("failed to lock login: %w", err)
+	}
+	return true, nil
+}
+
+// ResetLoginFailures clears email's failed login count after a successful
+// login.
+func (m *SessionManager) ResetLoginFailures(ctx context.Context, email string) error {
+	if m == nil {
+		return nil
+	}
+	return m.client.Del(ctx, loginFailuresKey(email)).Err()
+}
+
+// ErrRefreshTokenReused is returned by ConsumeRefreshToken for a refresh token
+// that has already been exchanged.
+var ErrRefreshTokenReused = errors.New("refresh token already used")
+
+// ConsumeRefreshToken marks the refresh token with the given ID (its jti) as
+// exchanged, remembering it for ttl, which should cover the token's remaining
+// lifetime. It returns ErrRefreshTokenReused if the token was already used.
+func (m *SessionManager) ConsumeRefreshToken(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if m == nil {
+		return nil
+	}
+
+	fresh, err := m.client.SetNX(ctx, usedRefreshTokenKey(tokenID), time.Now().Unix(), max(ttl, time.Second)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to record refresh token: %w", err)
+	}
+	if !fresh {
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// ErrMFAChallengeExhausted is returned by CountMFAAttempt once a challenge
+// token has had all its attempts.
+var ErrMFAChallengeExhausted = errors.New("MFA challenge has no attempts left")
+
+// CountMFAAttempt counts a code tried against the MFA challenge token with the
+// given ID (its jti), remembering the count for ttl, which should cover the
+// token's remaining lifetime. It returns ErrMFAChallengeExhausted once
+// maxAttempts codes have been tried. The attempt is counted before the code
+// is checked, so parallel guesses can't get past the limit.
+func (m *SessionManager) CountMFAAttempt(ctx context.Context, challengeID string, maxAttempts int, ttl time.Duration) error {
+	if m == nil {
+		return nil
+	}
+
+	pipe := m.client.TxPipeline()
+	attempts := pipe.Incr(ctx, mfaAttemptsKey(challengeID))
+	pipe.Expire(ctx, mfaAttemptsKey(challengeID), max(ttl, time.Second))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record MFA attempt: %w", err)
+	}
+	if attempts.Val() > int64(maxAttempts) {
+		return ErrMFAChallengeExhausted
+	}
+	return nil
+}
+
 // RevokeSession revokes a specific session.
 func (m *SessionManager) RevokeSession(ctx context.Context, sessionID, userID string) error {
 	if m == nil {
@@ -226,6 +491,94 @@ func (m *SessionManager) RevokeAllSessions(ctx context.Context, userID, exceptSe
 	return err
 }
 
+// SessionFilter selects sessions by IP address and/or device. Empty fields
+// match anything; device comparison ignores case.
+type SessionFilter struct {
+	IPAddress  string
+	DeviceInfo string
+}
+
+// Matches reports whether a session satisfies every set field of the filter.
+func (f SessionFilter) Matches(s Session) bool {
+	if f.IPAddress != "" && s.IPAddress != f.IPAddress {
+		return false
+	}
+	if f.DeviceInfo != "" && !strings.EqualFold(s.DeviceInfo, f.DeviceInfo) {
+		return false
+	}
+	return true
+}
+
+// RevokeMatchingSessions revokes a user's sessions that match the filter,
+// except exceptSessionID, and returns how many were revoked.
+func (m *SessionManager) RevokeMatchingSessions(ctx context.Context, userID string, filter SessionFilter, exceptSessionID string) (int, error) {
+	if m == nil {
+		return 0, nil
+	}
+
+	sessions, err := m.ListUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	pipe := m.client.Pipeline()
+	revoked := 0
+	for _, s := range sessions {
+		if s.ID == exceptSessionID || !filter.Matches(s) {
+			continue
+		}
+		pipe.Del(ctx, sessionKey(s.ID))
+		pipe.SRem(ctx, userSessionsKey(userID), s.ID)
+		revoked++
+	}
+	if revoked == 0 {
+		return 0, nil
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return revoked, nil
+}
+
+// EvictExcessSessions signs a user out of their oldest sessions until at most
+// max remain, never evicting keepSessionID, and returns the evicted sessions.
+// A max <= 0 means no limit.
+func (m *SessionManager) EvictExcessSessions(ctx context.Context, userID string, max int, keepSessionID string) ([]Session, error) {
+	if m == nil || max <= 0 {
+		return nil, nil
+	}
+
+	sessions, err := m.ListUserSessions(ctx, userID)
+	if err != nil || len(sessions) <= max {
+		return nil, err
+	}
+
+	slices.SortFunc(sessions, func(a, b Session) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	excess := len(sessions) - max
+	pipe := m.client.Pipeline()
+	var evicted []Session
+	for _, s := range sessions {
+		if len(evicted) == excess {
+			break
+		}
+		if s.ID == keepSessionID {
+			continue
+		}
+		pipe.Del(ctx, sessionKey(s.ID))
+		pipe.SRem(ctx, userSessionsKey(userID), s.ID)
+		evicted = append(evicted, s)
+	}
+	if len(evicted) == 0 {
+		return nil, nil
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to evict sessions: %w", err)
+	}
+	return evicted, nil
+}
+
 // RevokeAllUserSessions revokes ALL sessions for a user (used on password change).
 func (m *SessionManager) RevokeAllUserSessions(ctx context.Context, userID string) error {
 	if m == nil {
@@ -0,0 +1,283 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestSessionFilterMatches(t *testing.T) {
+	session := Session{IPAddress: "203.0.113.7", DeviceInfo: "Old Laptop"}
+
+	tests := []struct {
+		name   string
+		filter SessionFilter
+		want   bool
+	}{
+		{"ip match", SessionFilter{IPAddress: "203.0.113.7"}, true},
+		{"ip mismatch", SessionFilter{IPAddress: "203.0.113.8"}, false},
+		{"device ignores case", SessionFilter{DeviceInfo: "old laptop"}, true},
+		{"device mismatch", SessionFilter{DeviceInfo: "phone"}, false},
+		{"both must match", SessionFilter{IPAddress: "203.0.113.7", DeviceInfo: "phone"}, false},
+		{"both match", SessionFilter{IPAddress: "203.0.113.7", DeviceInfo: "Old Laptop"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(session); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConnectSessionManagerInvalidURLFailsFast(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	start := time.Now()
+	_, err := ConnectSessionManager(context.Background(), "not a url", time.Hour, 5, time.Second, log)
+	if !errors.Is(err, ErrInvalidRedisURL) {
+		t.Fatalf("err = %v, want ErrInvalidRedisURL", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("invalid URL was retried")
+	}
+}
+
+func TestConnectSessionManagerGivesUpAfterRetries(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	// Nothing listens on port 1, so every attempt fails quickly
+	m, err := ConnectSessionManager(context.Background(), "redis://127.0.0.1:1?max_retries=-1", time.Hour, 1, 10*time.Millisecond, log)
+	if err == nil || m != nil {
+		t.Fatalf("ConnectSessionManager() = %v, %v; want an error", m, err)
+	}
+	if errors.Is(err, ErrInvalidRedisURL) {
+		t.Errorf("unreachable Redis reported as invalid URL: %v", err)
+	}
+}
+
+func TestReconnectSessionManagerStopsOnCancel(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		ReconnectSessionManager(ctx, "redis://127.0.0.1:1?max_retries=-1", time.Hour, 10*time.Millisecond, log, func(*SessionManager) {
+			t.Error("onConnect called without Redis")
+		})
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("ReconnectSessionManager did not stop after cancel")
+	}
+}
+
+// TestConsumeRefreshTokenRejectsReuse needs Redis:
+//
+//	TEST_REDIS_URL=redis://localhost:6379 go test -run=ConsumeRefreshToken ./internal/auth
+func TestConsumeRefreshTokenRejectsReuse(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	m, err := NewSessionManager(url, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	tokenID := uuid.NewString()
+	if err := m.ConsumeRefreshToken(ctx, tokenID, time.Minute); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	if err := m.ConsumeRefreshToken(ctx, tokenID, time.Minute); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("second use: err = %v, want ErrRefreshTokenReused", err)
+	}
+	if err := m.ConsumeRefreshToken(ctx, uuid.NewString(), time.Minute); err != nil {
+		t.Errorf("another token: %v", err)
+	}
+}
+
+// TestCountMFAAttemptExhaustsChallenge needs Redis, like
+// TestConsumeRefreshTokenRejectsReuse.
+func TestCountMFAAttemptExhaustsChallenge(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	m, err := NewSessionManager(url, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	challengeID := uuid.NewString()
+	for i := 0; i < 3; i++ {
+		if err := m.CountMFAAttempt(ctx, challengeID, 3, time.Minute); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	if err := m.CountMFAAttempt(ctx, challengeID, 3, time.Minute); !errors.Is(err, ErrMFAChallengeExhausted) {
+		t.Errorf("fourth attempt: err = %v, want ErrMFAChallengeExhausted", err)
+	}
+	if err := m.CountMFAAttempt(ctx, uuid.NewString(), 3, time.Minute); err != nil {
+		t.Errorf("another challenge: %v", err)
+	}
+}
+
+// TestEvictExcessSessionsKeepsNewest needs Redis, like
+// TestConsumeRefreshTokenRejectsReuse.
+func TestEvictExcessSessionsKeepsNewest(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	m, err := NewSessionManager(url, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	userID := uuid.NewString()
+	var ids []string
+	for i := 0; i < 4; i++ {
+		s, err := m.CreateSession(ctx, userID, "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, s.ID)
+		time.Sleep(time.Millisecond) // Distinct creation times
+	}
+
+	evicted, err := m.EvictExcessSessions(ctx, userID, 2, ids[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 2 || evicted[0].ID != ids[0] || evicted[1].ID != ids[1] {
+		t.Fatalf("evicted %v, want the two oldest sessions", evicted)
+	}
+	left, err := m.ListUserSessions(ctx, userID)
+	if err != nil || len(left) != 2 {
+		t.Errorf("sessions left = %d, %v, want 2", len(left), err)
+	}
+	if evicted, _ := m.EvictExcessSessions(ctx, userID, 2, ids[3]); len(evicted) != 0 {
+		t.Errorf("evicted %v at the limit, want none", evicted)
+	}
+}
+
+// TestLoginLockout needs Redis, like TestConsumeRefreshTokenRejectsReuse.
+func TestLoginLockout(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	m, err := NewSessionManager(url, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	ctx := context.Background()
+	email := "lockout-" + uuid.NewString() + "@example.com"
+	fail := func() bool {
+		t.Helper()
+		locked, err := m.RecordLoginFailure(ctx, email, 3, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return locked
+	}
+
+	// A success in between starts the count again
+	fail()
+	fail()
+	if err := m.ResetLoginFailures(ctx, email); err != nil {
+		t.Fatal(err)
+	}
+	if fail() || fail() {
+		t.Fatal("locked before three consecutive failures")
+	}
+	if locked, _ := m.LoginLockedFor(ctx, email); locked != 0 {
+		t.Fatalf("locked for %v before the limit", locked)
+	}
+	if !fail() {
+		t.Fatal("third consecutive failure did not lock")
+	}
+	// Email case doesn't give an attacker a fresh count
+	if locked, err := m.LoginLockedFor(ctx, strings.ToUpper(email)); err != nil || locked <= 0 || locked > time.Minute {
+		t.Errorf("locked for %v, %v, want up to a minute", locked, err)
+	}
+}
+
+// TestEvictedSessionSignsOutTokens needs Redis, like
+// TestConsumeRefreshTokenRejectsReuse.
+func TestEvictedSessionSignsOutTokens(t *testing.T) {
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL not set")
+	}
+	m, err := NewSessionManager(url, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// Trusted claims keep the database out of the request path
+	a := New(&config.Config{JWTSecretKey: "test-secret", JWTExpireMinutes: 15, JWTTrustClaims: true}, nil)
+	a.SetSessions(m)
+	ctx := context.Background()
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+	authenticated := func(sessionID string) bool {
+		t.Helper()
+		token, err := a.CreateAccessToken(user, sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		var ok bool
+		a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok = GetIdentityFromContext(r.Context()) != nil
+		})).ServeHTTP(httptest.NewRecorder(), r)
+		return ok
+	}
+
+	old, err := m.CreateSession(ctx, user.ID.String(), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond) // Distinct creation times
+	current, err := m.CreateSession(ctx, user.ID.String(), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !authenticated(old.ID) {
+		t.Fatal("token for a live session rejected")
+	}
+	if _, err := m.EvictExcessSessions(ctx, user.ID.String(), 1, current.ID); err != nil {
+		t.Fatal(err)
+	}
+	if authenticated(old.ID) {
+		t.Error("token for the evicted session still authenticates")
+	}
+	if !authenticated(current.ID) || !authenticated("") {
+		t.Error("tokens for the kept session or without one were rejected")
+	}
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AccessTokenCookie returns the cookie that carries an access token for
+// browser clients.
+func (a *Auth) AccessTokenCookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     a.cfg.AccessTokenCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   a.cfg.IsProduction(),
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   a.cfg.JWTExpireMinutes * 60,
+	}
+}
+
+// RefreshTokenCookie returns the cookie carrying a refresh token to browser
+// clients; it lasts as long as the token.
+func (a *Auth) RefreshTokenCookie(token string) *http.Cookie {
+	return &http.Cookie{
+		Name:     a.cfg.RefreshTokenCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   a.cfg.IsProduction(),
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   a.cfg.JWTRefreshExpireDays * 24 * 60 * 60,
+	}
+}
+
+// SlidingRefresh reissues the access token cookie when the token that
+// authenticated the request expires within JWT_SLIDING_REFRESH_MINUTES, so a
+// browser session in use doesn't lapse between refresh calls. Bearer tokens
+// are left alone: their holder manages them. Mount it after Middleware.
+func (a *Auth) SlidingRefresh(next http.Handler) http.Handler {
+	window := time.Duration(a.cfg.JWTSlidingRefreshMinutes) * time.Minute
+	if window <= 0 || !a.cfg.AuthCookies() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.cookieTokenExpiresWithin(r, window) {
+			// Loads a deferred user, so a deactivated account isn't renewed
+			if user := GetUserFromContext(r.Context()); user != nil {
+				token, err := a.CreateAccessToken(user, GetClaimsFromContext(r.Context()).SessionID)
+				if err != nil {
+					slog.Warn("failed to slide access token", "user_id", user.ID, "error", err)
+				} else {
+					http.SetCookie(w, a.AccessTokenCookie(token))
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// cookieTokenExpiresWithin reports whether r was authenticated by the access
+// token cookie and that token expires within window. Middleware prefers an
+// accepted Bearer header, so without one the request's claims came from the
+// cookie.
+func (a *Auth) cookieTokenExpiresWithin(r *http.Request, window time.Duration) bool {
+	if a.cfg.AuthBearer() && strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		return false
+	}
+	if _, err := r.Cookie(a.cfg.AccessTokenCookie); err != nil {
+		return false
+	}
+	claims := GetClaimsFromContext(r.Context())
+	if claims == nil || claims.ExpiresAt == nil {
+		return false
+	}
+	return time.Until(claims.ExpiresAt.Time) <= window
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestSlidingRefresh(t *testing.T) {
+	cfg := &config.Config{
+		JWTSecretKey:             "test-secret",
+		JWTExpireMinutes:         15,
+		JWTSlidingRefreshMinutes: 5,
+		AccessTokenCookie:        "access_token",
+	}
+	a := New(cfg, nil)
+	user := &models.User{ID: uuid.New(), Email: "demo@example.com", Role: "user"}
+	expiring := &Claims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Minute))}}
+	fresh := &Claims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(12 * time.Minute))}}
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		cookie bool
+		bearer bool
+		want   bool
+	}{
+		{"cookie near expiry", expiring, true, false, true},
+		{"cookie outside window", fresh, true, false, false},
+		{"bearer near expiry", expiring, true, true, false},
+		{"unauthenticated", nil, true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = context.WithValue(ctx, UserContextKey, user)
+				ctx = context.WithValue(ctx, ClaimsContextKey, tt.claims)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/projects", nil).WithContext(ctx)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: cfg.AccessTokenCookie, Value: "old"})
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer old")
+			}
+
+			rec := httptest.NewRecorder()
+			a.SlidingRefresh(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP(rec, req)
+
+			var slid *http.Cookie
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == cfg.AccessTokenCookie {
+					slid = c
+				}
+			}
+			if (slid != nil) != tt.want {
+				t.Fatalf("cookie reissued = %v, want %v", slid != nil, tt.want)
+			}
+			if slid == nil {
+				return
+			}
+			claims, err := a.ValidateAccessToken(slid.Value)
+			if err != nil {
+				t.Fatalf("reissued token invalid: %v", err)
+			}
+			if claims.UserID != user.ID || time.Until(claims.ExpiresAt.Time) < 14*time.Minute {
+				t.Errorf("reissued token for %s expiring %s, want a full lifetime for %s", claims.UserID, claims.ExpiresAt, user.ID)
+			}
+		})
+	}
+}
+
+func TestSlidingRefreshDisabledByDefault(t *testing.T) {
+	a := New(&config.Config{}, nil)
+	next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	ctx := context.WithValue(context.Background(), ClaimsContextKey, &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second))},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	a.SlidingRefresh(next).ServeHTTP(rec, req)
+	if len(rec.Result().Cookies()) != 0 {
+		t.Error("cookie reissued with sliding refresh disabled")
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// WorkerSignatureHeader carries the HMAC of a worker callback's body.
+const WorkerSignatureHeader = "X-Worker-Signature"
+
+// workerSignaturePrefix names the algorithm, as in "sha256=<hex>".
+const workerSignaturePrefix = "sha256="
+
+// SignWorkerBody returns the X-Worker-Signature value for body: the
+// hex-encoded HMAC-SHA256 of the raw bytes, prefixed with "sha256=".
+func SignWorkerBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return workerSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWorkerSignature reports whether signature is a valid signature of
+// body under secret. The comparison is constant-time. An empty secret
+// verifies nothing.
+func VerifyWorkerSignature(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+	sum, ok := strings.CutPrefix(strings.TrimSpace(signature), workerSignaturePrefix)
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(sum)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
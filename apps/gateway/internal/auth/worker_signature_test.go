@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyWorkerSignature(t *testing.T) {
+	body := []byte(`{"event_type":"task_updated"}`)
+	valid := SignWorkerBody("s3cret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"valid", "s3cret", body, valid, true},
+		{"uppercase hex", "s3cret", body, "sha256=" + strings.ToUpper(strings.TrimPrefix(valid, "sha256=")), true},
+		{"missing", "s3cret", body, "", false},
+		{"wrong secret", "other", body, valid, false},
+		{"tampered body", "s3cret", []byte(`{"event_type":"task_created"}`), valid, false},
+		{"no algorithm prefix", "s3cret", body, strings.TrimPrefix(valid, "sha256="), false},
+		{"not hex", "s3cret", body, "sha256=zz", false},
+		{"unconfigured secret", "", body, SignWorkerBody("", body), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWorkerSignature(tt.secret, tt.body, tt.signature); got != tt.want {
+				t.Errorf("VerifyWorkerSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// acquireStartKey carries the time an Acquire began from TraceAcquireStart
+// to TraceAcquireEnd.
+type acquireStartKey struct{}
+
+// acquireTracer records how long each pool Acquire waited for a connection.
+// pgxpool calls it around every acquire, including the implicit ones behind
+// pool.Query and pool.Exec, so no call site needs wrapping. The cost is one
+// context value and one histogram observation per acquire.
+type acquireTracer struct {
+	wait prometheus.Observer
+}
+
+func (t acquireTracer) TraceAcquireStart(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireStartData) context.Context {
+	return context.WithValue(ctx, acquireStartKey{}, time.Now())
+}
+
+func (t acquireTracer) TraceAcquireEnd(ctx context.Context, _ *pgxpool.Pool, _ pgxpool.TraceAcquireEndData) {
+	if start, ok := ctx.Value(acquireStartKey{}).(time.Time); ok {
+		t.wait.Observe(time.Since(start).Seconds())
+	}
+}
+
+// ConnConfig.Tracer must be a pgx.QueryTracer for pgxpool to find the
+// acquire hooks on it; queries themselves are not traced.
+func (acquireTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (acquireTracer) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type recordedWaits []float64
+
+func (r *recordedWaits) Observe(v float64) { *r = append(*r, v) }
+
+func TestAcquireTracerObservesWait(t *testing.T) {
+	var waits recordedWaits
+	tracer := acquireTracer{wait: &waits}
+
+	ctx := tracer.TraceAcquireStart(context.Background(), nil, pgxpool.TraceAcquireStartData{})
+	time.Sleep(5 * time.Millisecond)
+	tracer.TraceAcquireEnd(ctx, nil, pgxpool.TraceAcquireEndData{})
+
+	if len(waits) != 1 || waits[0] < 0.005 {
+		t.Fatalf("observed waits = %v, want one of at least 5ms", waits)
+	}
+
+	// An end without a matching start records nothing
+	tracer.TraceAcquireEnd(context.Background(), nil, pgxpool.TraceAcquireEndData{})
+	if len(waits) != 1 {
+		t.Errorf("observed waits = %v after an unmatched end, want still one", waits)
+	}
+}
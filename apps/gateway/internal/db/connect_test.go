@@ -0,0 +1,38 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectRetriesThenGivesUp(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+
+	// Nothing listens on port 1, so every attempt is refused
+	_, err := Connect(context.Background(), "postgres://u:p@127.0.0.1:1/db?sslmode=disable&connect_timeout=1", "", 2, time.Millisecond, log)
+	if err == nil {
+		t.Fatal("Connect succeeded without a database")
+	}
+	if got := strings.Count(logs.String(), "database not reachable"); got != 2 {
+		t.Errorf("logged %d retries, want 2", got)
+	}
+}
+
+func TestConnectFailsFastOnInvalidURL(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+
+	_, err := Connect(context.Background(), "postgres://u:p@host:notaport/db", "", 5, time.Hour, log)
+	if !errors.Is(err, ErrInvalidURL) {
+		t.Fatalf("err = %v, want ErrInvalidURL", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("invalid URL was retried: %s", logs.String())
+	}
+}
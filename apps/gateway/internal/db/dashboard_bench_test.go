@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// BenchmarkDashboardCounts compares the single grouped count query with the
+// two separate queries it replaced. It needs a migrated database:
+//
+//	TEST_DATABASE_URL=postgres://... go test -run=^$ -bench=DashboardCounts ./internal/db
+func BenchmarkDashboardCounts(b *testing.B) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		b.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := New(url, "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	projectID := uuid.New()
+
+	b.Run("separate", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var completed, running int
+			if err := db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'completed'`, projectID).Scan(&completed); err != nil {
+				b.Fatal(err)
+			}
+			if err := db.pool.QueryRow(ctx, `
+				SELECT COUNT(*) FROM crew_runs cr
+				JOIN tasks t ON t.crew_run_id = cr.id
+				WHERE t.project_id = $1 AND cr.status = 'running'`, projectID).Scan(&running); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("grouped", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, _, err := db.CountDashboardStats(ctx, projectID); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
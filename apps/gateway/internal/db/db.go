@@ -4,30 +4,41 @@ package db
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
 )
 
 // DB wraps the database connection pool.
 type DB struct {
-	pool *pgxpool.Pool
+	pool       *pgxpool.Pool
+	maxRetries int
 }
 
-// New creates a new database connection.
-func New(databaseURL string) (*DB, error) {
-	config, err := pgxpool.ParseConfig(databaseURL)
+// ErrInvalidURL is returned by New when the database URL can't be parsed.
+var ErrInvalidURL = errors.New("invalid database URL")
+
+// New creates a new database connection. When sslRootCert is set, the
+// server certificate is verified against that CA bundle.
+func New(databaseURL, sslRootCert string) (*DB, error) {
+	config, err := pgxpool.ParseConfig(withRootCert(databaseURL, sslRootCert))
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
 	}
 
 	config.MaxConns = 20
 	config.MinConns = 2
 	config.MaxConnLifetime = time.Hour
 	config.MaxConnIdleTime = 30 * time.Minute
+	config.ConnConfig.Tracer = acquireTracer{wait: observability.Metrics.DBAcquireWait}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -36,10 +47,70 @@ func New(databaseURL string) (*DB, error) {
 
 	// Test connection
 	if err := pool.Ping(context.Background()); err != nil {
+		pool.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	return &DB{pool: pool}, nil
+	return &DB{pool: pool, maxRetries: DefaultMaxRetries}, nil
+}
+
+// maxConnectBackoff caps the wait between startup connection attempts.
+const maxConnectBackoff = 30 * time.Second
+
+// Connect calls New until the database answers, so a gateway deployed
+// alongside its database waits for it instead of crash-looping. It makes up
+// to retries extra attempts, waiting interval before the first and doubling
+// the wait each time up to 30s. An unparseable URL fails immediately.
+func Connect(ctx context.Context, databaseURL, sslRootCert string, retries int, interval time.Duration, log *slog.Logger) (*DB, error) {
+	wait := interval
+	for attempt := 1; ; attempt++ {
+		db, err := New(databaseURL, sslRootCert)
+		if err == nil || errors.Is(err, ErrInvalidURL) || attempt > retries {
+			return db, err
+		}
+
+		log.Warn("database not reachable, retrying",
+			"attempt", attempt,
+			"max_attempts", retries+1,
+			"retry_in", wait,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, max(interval, maxConnectBackoff))
+	}
+}
+
+// Ping checks that the database is reachable.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.pool.Ping(ctx)
+}
+
+// PoolStats returns a snapshot of the connection pool.
+func (db *DB) PoolStats() models.DBPoolStats {
+	stat := db.pool.Stat()
+	return models.DBPoolStats{
+		AcquiredConns:        stat.AcquiredConns(),
+		IdleConns:            stat.IdleConns(),
+		TotalConns:           stat.TotalConns(),
+		MaxConns:             stat.MaxConns(),
+		AcquireCount:         stat.AcquireCount(),
+		EmptyAcquireCount:    stat.EmptyAcquireCount(),
+		CanceledAcquireCount: stat.CanceledAcquireCount(),
+		AcquireWaitMS:        float64(stat.AcquireDuration()) / float64(time.Millisecond),
+	}
+}
+
+// SetMaxRetries sets how many times transient errors are retried for reads
+// and transactional writes. Zero disables retries.
+func (db *DB) SetMaxRetries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	db.maxRetries = n
 }
 
 // Close closes the database connection pool.
@@ -47,32 +118,97 @@ func (db *DB) Close() {
 	db.pool.Close()
 }
 
+// appendLimitOffset adds LIMIT/OFFSET placeholders to query when limit > 0.
+func appendLimitOffset(query string, args []interface{}, limit, offset int) (string, []interface{}) {
+	if limit <= 0 {
+		return query, args
+	}
+	n := len(args)
+	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", n+1, n+2)
+	return query, append(args, limit, offset)
+}
+
+// HasMFAColumns reports whether the users table has the MFA columns added by
+// migration 0006.
+func (db *DB) HasMFAColumns(ctx context.Context) (bool, error) {
+	query := `
+		SELECT COUNT(*) FROM information_schema.columns
+		WHERE table_name = 'users' AND column_name IN ('mfa_enabled', 'mfa_secret', 'backup_codes')
+	`
+	var count int
+	if err := db.pool.QueryRow(ctx, query).Scan(&count); err != nil {
+		return false, err
+	}
+	return count == 3, nil
+}
+
 // ---- User Queries ----
 
 // CreateUser inserts a new user into the database.
 func (db *DB) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
-		INSERT INTO users (id, username, email, password_hash, role, active, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO users (id, username, email, password_hash, role, active, email_verified_at, org_id, org_role, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
+	orgRole := user.OrgRole
+	if orgRole == "" {
+		orgRole = models.OrgRoleMember
+	}
 	_, err := db.pool.Exec(ctx, query,
 		user.ID, user.Username, user.Email, user.PasswordHash,
-		user.Role, user.Active, user.CreatedAt,
+		user.Role, user.Active, user.EmailVerifiedAt, user.OrgID, orgRole, user.CreatedAt,
 	)
 	return err
 }
 
+// RecordLogin stamps the user's last successful login time.
+func (db *DB) RecordLogin(ctx context.Context, userID uuid.UUID) error {
+	_, err := db.pool.Exec(ctx, `UPDATE users SET last_login_at = NOW() WHERE id = $1`, userID)
+	return err
+}
+
+// DeactivateDormantUsers marks active accounts inactive when they have not
+// logged in (or, if they never have, were created) since before idleSince.
+// Accounts with an exempt role or email are skipped. Returns the number of
+// accounts affected.
+func (db *DB) DeactivateDormantUsers(ctx context.Context, idleSince time.Time, exemptRoles, exemptEmails []string) (int64, error) {
+	query := `
+		UPDATE users
+		SET active = false, updated_at = NOW()
+		WHERE active
+		  AND deleted_at IS NULL
+		  AND COALESCE(last_login_at, created_at) < $1
+		  AND role <> ALL($2)
+		  AND lower(email) <> ALL($3)
+	`
+	tag, err := db.pool.Exec(ctx, query, idleSince, exemptRoles, lowerAll(exemptEmails))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func lowerAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
+
 // GetUserByEmail retrieves a user by email.
 func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, role, active, created_at
+		SELECT id, username, email, password_hash, role, active, email_verified_at, org_id, org_role, created_at
 		FROM users WHERE email = $1
 	`
 	var user models.User
-	err := db.pool.QueryRow(ctx, query, email).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.Active, &user.CreatedAt,
-	)
+	err := db.withRetry(ctx, "get_user_by_email", func() error {
+		return db.pool.QueryRow(ctx, query, email).Scan(
+			&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.Role, &user.Active, &user.EmailVerifiedAt, &user.OrgID, &user.OrgRole, &user.CreatedAt,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -82,14 +218,16 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 // GetUserByUsername retrieves a user by username.
 func (db *DB) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, role, active, created_at
+		SELECT id, username, email, password_hash, role, active, email_verified_at, org_id, org_role, created_at
 		FROM users WHERE username = $1
 	`
 	var user models.User
-	err := db.pool.QueryRow(ctx, query, username).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.Active, &user.CreatedAt,
-	)
+	err := db.withRetry(ctx, "get_user_by_username", func() error {
+		return db.pool.QueryRow(ctx, query, username).Scan(
+			&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.Role, &user.Active, &user.EmailVerifiedAt, &user.OrgID, &user.OrgRole, &user.CreatedAt,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -99,20 +237,51 @@ func (db *DB) GetUserByUsername(ctx context.Context, username string) (*models.U
 // GetUserByID retrieves a user by ID.
 func (db *DB) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, role, active, created_at
+		SELECT id, username, email, password_hash, role, active, email_verified_at, org_id, org_role, created_at
 		FROM users WHERE id = $1
 	`
 	var user models.User
-	err := db.pool.QueryRow(ctx, query, id).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.Role, &user.Active, &user.CreatedAt,
-	)
+	err := db.withRetry(ctx, "get_user_by_id", func() error {
+		return db.pool.QueryRow(ctx, query, id).Scan(
+			&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.Role, &user.Active, &user.EmailVerifiedAt, &user.OrgID, &user.OrgRole, &user.CreatedAt,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+// UpdateUserPassword replaces a user's password hash.
+func (db *DB) UpdateUserPassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET password_hash = $2, updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := db.pool.Exec(ctx, query, userID, passwordHash)
+	return err
+}
+
+// ClaimUnverifiedUser marks an account's email verified when an OAuth sign-in
+// proves who owns it. Whoever registered the account with a password never
+// proved that, so the password and any MFA they set up are cleared. Reports
+// false if the account was already verified, leaving it untouched.
+func (db *DB) ClaimUnverifiedUser(ctx context.Context, userID uuid.UUID) (bool, error) {
+	query := `
+		UPDATE users
+		SET email_verified_at = NOW(), password_hash = '',
+			mfa_enabled = false, mfa_secret = NULL, backup_codes = NULL, updated_at = NOW()
+		WHERE id = $1 AND email_verified_at IS NULL
+	`
+	tag, err := db.pool.Exec(ctx, query, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
 // UpdateUserMFA updates the MFA settings for a user.
 func (db *DB) UpdateUserMFA(ctx context.Context, userID uuid.UUID, enabled bool, secret *string, backupCodes []string) error {
 	query := `
@@ -130,17 +299,21 @@ func (db *DB) GetUserMFA(ctx context.Context, userID uuid.UUID) (enabled bool, s
 		SELECT mfa_enabled, mfa_secret, backup_codes
 		FROM users WHERE id = $1
 	`
-	err = db.pool.QueryRow(ctx, query, userID).Scan(&enabled, &secret, &backupCodes)
+	err = db.withRetry(ctx, "get_user_mfa", func() error {
+		return db.pool.QueryRow(ctx, query, userID).Scan(&enabled, &secret, &backupCodes)
+	})
 	return
 }
 
-// LinkOAuthAccount links an OAuth provider account to a user.
+// LinkOAuthAccount links an OAuth provider account to a user. An existing
+// link keeps its user; only the provider's email and tokens are refreshed.
 func (db *DB) LinkOAuthAccount(ctx context.Context, userID uuid.UUID, provider, providerUserID, email, accessToken, refreshToken string) error {
 	query := `
 		INSERT INTO oauth_accounts (id, user_id, provider, provider_user_id, provider_email, access_token, refresh_token, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
 		ON CONFLICT (provider, provider_user_id) DO UPDATE
-		SET access_token = EXCLUDED.access_token, refresh_token = EXCLUDED.refresh_token, updated_at = NOW()
+		SET provider_email = EXCLUDED.provider_email, access_token = EXCLUDED.access_token,
+			refresh_token = EXCLUDED.refresh_token, updated_at = NOW()
 	`
 	_, err := db.pool.Exec(ctx, query,
 		uuid.New(), userID, provider, providerUserID, email,
@@ -149,16 +322,144 @@ func (db *DB) LinkOAuthAccount(ctx context.Context, userID uuid.UUID, provider,
 	return err
 }
 
+// GetUserByOAuthAccount retrieves the user a provider account is linked to.
+func (db *DB) GetUserByOAuthAccount(ctx context.Context, provider, providerUserID string) (*models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email, u.password_hash, u.role, u.active, u.email_verified_at, u.org_id, u.org_role, u.created_at
+		FROM oauth_accounts a JOIN users u ON u.id = a.user_id
+		WHERE a.provider = $1 AND a.provider_user_id = $2
+	`
+	var user models.User
+	err := db.withRetry(ctx, "get_user_by_oauth_account", func() error {
+		return db.pool.QueryRow(ctx, query, provider, providerUserID).Scan(
+			&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.Role, &user.Active, &user.EmailVerifiedAt, &user.OrgID, &user.OrgRole, &user.CreatedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// ListOAuthProviders returns the providers linked to a user, sorted by name.
+func (db *DB) ListOAuthProviders(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT DISTINCT provider FROM oauth_accounts
+		WHERE user_id = $1
+		ORDER BY provider
+	`
+	var providers []string
+	err := db.withRetry(ctx, "list_oauth_providers", func() error {
+		rows, err := db.pool.Query(ctx, query, userID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		providers = providers[:0]
+		for rows.Next() {
+			var provider string
+			if err := rows.Scan(&provider); err != nil {
+				return err
+			}
+			providers = append(providers, provider)
+		}
+		return rows.Err()
+	})
+	return providers, err
+}
+
+// ErrStale is returned by a conditional update when the row changed, or
+// was deleted, after it was read.
+var ErrStale = errors.New("row changed since it was read")
+
+// ---- Organization Queries ----
+
+var (
+	// ErrOrgSlugTaken is returned when another organization already uses the slug.
+	ErrOrgSlugTaken = errors.New("organization slug already in use")
+	// ErrUserInOtherOrg is returned when a user already belongs to a different
+	// organization; membership is never moved implicitly.
+	ErrUserInOtherOrg = errors.New("user belongs to another organization")
+)
+
+// CreateOrganization inserts an organization and makes adminID its first org
+// admin, in one transaction.
+func (db *DB) CreateOrganization(ctx context.Context, org *models.Organization, adminID uuid.UUID) error {
+	tx, err := db.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO organizations (id, name, slug, created_at) VALUES ($1, $2, $3, $4)`,
+		org.ID, org.Name, org.Slug, org.CreatedAt,
+	)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrOrgSlugTaken
+	}
+	if err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE users SET org_id = $2, org_role = $3, updated_at = NOW()
+		WHERE id = $1 AND org_id IS NULL
+	`, adminID, org.ID, models.OrgRoleAdmin)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserInOtherOrg
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetOrganizationByID retrieves an organization by ID.
+func (db *DB) GetOrganizationByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	var org models.Organization
+	err := db.withRetry(ctx, "get_organization", func() error {
+		return db.pool.QueryRow(ctx,
+			`SELECT id, name, slug, created_at FROM organizations WHERE id = $1`, id,
+		).Scan(&org.ID, &org.Name, &org.Slug, &org.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// SetOrgMembership adds a user to orgID with role, or changes the role of an
+// existing member. Users in another organization are left untouched and
+// ErrUserInOtherOrg is returned.
+func (db *DB) SetOrgMembership(ctx context.Context, userID, orgID uuid.UUID, role string) error {
+	tag, err := db.pool.Exec(ctx, `
+		UPDATE users SET org_id = $2, org_role = $3, updated_at = NOW()
+		WHERE id = $1 AND (org_id IS NULL OR org_id = $2::text)
+	`, userID, orgID, role)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserInOtherOrg
+	}
+	return nil
+}
+
 // ---- Project Queries ----
 
 // CreateProject inserts a new project into the database.
 func (db *DB) CreateProject(ctx context.Context, project *models.Project) error {
 	query := `
-		INSERT INTO projects (id, user_id, name, description, status, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO projects (id, user_id, org_id, name, description, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 	_, err := db.pool.Exec(ctx, query,
-		project.ID, project.UserID, project.Name, project.Description,
+		project.ID, project.UserID, project.OrgID, project.Name, project.Description,
 		project.Status, project.CreatedAt, project.UpdatedAt,
 	)
 	return err
@@ -167,93 +468,217 @@ func (db *DB) CreateProject(ctx context.Context, project *models.Project) error
 // GetProjectByID retrieves a project by ID (admin only, no ownership check).
 func (db *DB) GetProjectByID(ctx context.Context, id uuid.UUID) (*models.Project, error) {
 	query := `
-		SELECT id, user_id, name, description, status, created_at, updated_at
+		SELECT id, user_id, org_id, name, description, status, created_at, updated_at
 		FROM projects WHERE id = $1
 	`
 	var project models.Project
-	err := db.pool.QueryRow(ctx, query, id).Scan(
-		&project.ID, &project.UserID, &project.Name, &project.Description,
-		&project.Status, &project.CreatedAt, &project.UpdatedAt,
-	)
+	err := db.withRetry(ctx, "get_project", func() error {
+		return db.pool.QueryRow(ctx, query, id).Scan(
+			&project.ID, &project.UserID, &project.OrgID, &project.Name, &project.Description,
+			&project.Status, &project.CreatedAt, &project.UpdatedAt,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &project, nil
 }
 
+// GetProjectInOrg retrieves a project by ID only if it belongs to orgID; a nil
+// orgID matches only projects outside any organization. A project in another
+// organization is reported as not found, so its ID can't be probed.
+func (db *DB) GetProjectInOrg(ctx context.Context, id uuid.UUID, orgID *uuid.UUID) (*models.Project, error) {
+	query := `
+		SELECT id, user_id, org_id, name, description, status, created_at, updated_at
+		FROM projects WHERE id = $1 AND ` + orgMatch("org_id", 2)
+	var project models.Project
+	err := db.withRetry(ctx, "get_project_in_org", func() error {
+		return db.pool.QueryRow(ctx, query, id, orgID).Scan(
+			&project.ID, &project.UserID, &project.OrgID, &project.Name, &project.Description,
+			&project.Status, &project.CreatedAt, &project.UpdatedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// orgMatch returns a condition matching column against the org ID in
+// placeholder n, where a NULL parameter matches only NULL (untenanted) rows.
+func orgMatch(column string, n int) string {
+	return fmt.Sprintf("%s IS NOT DISTINCT FROM $%d::text", column, n)
+}
+
 // GetProjectByIDForUser retrieves a project by ID with ownership verification.
 // Returns an error if the project doesn't belong to the specified user.
 func (db *DB) GetProjectByIDForUser(ctx context.Context, id, userID uuid.UUID) (*models.Project, error) {
 	query := `
-		SELECT id, user_id, name, description, status, created_at, updated_at
+		SELECT id, user_id, org_id, name, description, status, created_at, updated_at
 		FROM projects WHERE id = $1 AND user_id = $2
 	`
 	var project models.Project
-	err := db.pool.QueryRow(ctx, query, id, userID).Scan(
-		&project.ID, &project.UserID, &project.Name, &project.Description,
-		&project.Status, &project.CreatedAt, &project.UpdatedAt,
-	)
+	err := db.withRetry(ctx, "get_project_for_user", func() error {
+		return db.pool.QueryRow(ctx, query, id, userID).Scan(
+			&project.ID, &project.UserID, &project.OrgID, &project.Name, &project.Description,
+			&project.Status, &project.CreatedAt, &project.UpdatedAt,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return &project, nil
 }
 
-// ListProjects retrieves all projects, optionally filtered by user ID.
-func (db *DB) ListProjects(ctx context.Context, userID *uuid.UUID) ([]models.Project, error) {
-	var query string
-	var args []interface{}
-
+// ListProjects retrieves a page of the projects in orgID (see GetProjectInOrg),
+// optionally filtered by user ID, along with the total number of matching
+// projects. A limit <= 0 returns all rows.
+func (db *DB) ListProjects(ctx context.Context, orgID, userID *uuid.UUID, limit, offset int) ([]models.Project, int, error) {
+	where := "WHERE " + orgMatch("org_id", 1)
+	args := []interface{}{orgID}
 	if userID != nil {
-		query = `
-			SELECT id, user_id, name, description, status, created_at, updated_at
-			FROM projects WHERE user_id = $1
-			ORDER BY created_at DESC
-		`
-		args = []interface{}{*userID}
-	} else {
-		query = `
-			SELECT id, user_id, name, description, status, created_at, updated_at
-			FROM projects
-			ORDER BY created_at DESC
-		`
-	}
-
-	rows, err := db.pool.Query(ctx, query, args...)
+		where += " AND user_id = $2"
+		args = append(args, *userID)
+	}
+
+	var total int
+	err := db.withRetry(ctx, "count_projects", func() error {
+		return db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM projects `+where, args...).Scan(&total)
+	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer rows.Close()
+
+	query := `
+		SELECT id, user_id, org_id, name, description, status, created_at, updated_at
+		FROM projects ` + where + `
+		ORDER BY created_at DESC`
+	query, args = appendLimitOffset(query, args, limit, offset)
 
 	var projects []models.Project
-	for rows.Next() {
-		var p models.Project
-		if err := rows.Scan(
-			&p.ID, &p.UserID, &p.Name, &p.Description,
-			&p.Status, &p.CreatedAt, &p.UpdatedAt,
-		); err != nil {
-			return nil, err
+	err = db.withRetry(ctx, "list_projects", func() error {
+		rows, err := db.pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
 		}
-		projects = append(projects, p)
+		defer rows.Close()
+
+		projects = projects[:0]
+		for rows.Next() {
+			var p models.Project
+			if err := rows.Scan(
+				&p.ID, &p.UserID, &p.OrgID, &p.Name, &p.Description,
+				&p.Status, &p.CreatedAt, &p.UpdatedAt,
+			); err != nil {
+				return err
+			}
+			projects = append(projects, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return projects, rows.Err()
+	return projects, total, nil
+}
+
+// AdminProjectFilter narrows ListProjectsAdmin; zero fields match everything.
+type AdminProjectFilter struct {
+	Status  string
+	OwnerID *uuid.UUID
 }
 
-// UpdateProject updates a project.
-func (db *DB) UpdateProject(ctx context.Context, project *models.Project) error {
+// ListProjectsAdmin retrieves a page of projects across every user and
+// organization, newest first, with each owner's username and the total
+// number of matching projects. A limit <= 0 returns all rows.
+func (db *DB) ListProjectsAdmin(ctx context.Context, filter AdminProjectFilter, limit, offset int) ([]models.AdminProject, int, error) {
+	var conds []string
+	var args []interface{}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("p.status = $%d", len(args)))
+	}
+	if filter.OwnerID != nil {
+		args = append(args, *filter.OwnerID)
+		conds = append(conds, fmt.Sprintf("p.user_id = $%d", len(args)))
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	err := db.withRetry(ctx, "count_projects_admin", func() error {
+		return db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM projects p `+where, args...).Scan(&total)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT p.id, p.user_id, p.org_id, p.name, p.description, p.status, p.created_at, p.updated_at,
+			COALESCE(u.username, '')
+		FROM projects p
+		LEFT JOIN users u ON u.id = p.user_id ` + where + `
+		ORDER BY p.created_at DESC, p.id`
+	query, args = appendLimitOffset(query, args, limit, offset)
+
+	var projects []models.AdminProject
+	err = db.withRetry(ctx, "list_projects_admin", func() error {
+		rows, err := db.pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		projects = projects[:0]
+		for rows.Next() {
+			var p models.AdminProject
+			if err := rows.Scan(
+				&p.ID, &p.UserID, &p.OrgID, &p.Name, &p.Description,
+				&p.Status, &p.CreatedAt, &p.UpdatedAt, &p.OwnerUsername,
+			); err != nil {
+				return err
+			}
+			projects = append(projects, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return projects, total, nil
+}
+
+// UpdateProject updates a project. A non-zero ifUpdatedAt makes the update
+// conditional on the stored updated_at still equalling it; ErrStale is
+// returned when it doesn't.
+func (db *DB) UpdateProject(ctx context.Context, project *models.Project, ifUpdatedAt time.Time) error {
 	query := `
 		UPDATE projects
 		SET name = $2, description = $3, status = $4, updated_at = $5
-		WHERE id = $1
+		WHERE id = $1 AND ($6::timestamptz IS NULL OR updated_at = $6)
 	`
-	_, err := db.pool.Exec(ctx, query,
+	tag, err := db.pool.Exec(ctx, query,
 		project.ID, project.Name, project.Description,
-		project.Status, project.UpdatedAt,
+		project.Status, project.UpdatedAt, nullTime(ifUpdatedAt),
 	)
+	if err == nil && tag.RowsAffected() == 0 && !ifUpdatedAt.IsZero() {
+		return ErrStale
+	}
 	return err
 }
 
+// nullTime maps the zero time to SQL NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
 // DeleteProject deletes a project by ID.
 func (db *DB) DeleteProject(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM projects WHERE id = $1`
@@ -264,7 +689,14 @@ func (db *DB) DeleteProject(ctx context.Context, id uuid.UUID) error {
 // ---- Task Queries ----
 
 // CreateTask inserts a new task into the database and publishes a creation event.
+// The transaction is retried as a whole on transient failures.
 func (db *DB) CreateTask(ctx context.Context, task *models.Task) error {
+	return db.withRetry(ctx, "create_task", func() error {
+		return db.createTaskTx(ctx, task)
+	})
+}
+
+func (db *DB) createTaskTx(ctx context.Context, task *models.Task) error {
 	tx, err := db.pool.Begin(ctx)
 	if err != nil {
 		return err
@@ -272,12 +704,12 @@ func (db *DB) CreateTask(ctx context.Context, task *models.Task) error {
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	query := `
-		INSERT INTO tasks (id, project_id, title, description, priority, status, dependencies, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO tasks (id, project_id, title, description, priority, status, dependencies, due_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 	`
 	_, err = tx.Exec(ctx, query,
 		task.ID, task.ProjectID, task.Title, task.Description,
-		task.Priority, task.Status, task.Dependencies, task.CreatedAt, task.UpdatedAt,
+		task.Priority, task.Status, task.Dependencies, task.DueAt, task.CreatedAt, task.UpdatedAt,
 	)
 	if err != nil {
 		return err
@@ -306,80 +738,406 @@ func (db *DB) CreateTask(ctx context.Context, task *models.Task) error {
 	return tx.Commit(ctx)
 }
 
-// ListTasksByProject retrieves all tasks for a project.
-func (db *DB) ListTasksByProject(ctx context.Context, projectID uuid.UUID) ([]models.Task, error) {
+// Task sort orders accepted by ListTasksByProject.
+const (
+	TaskSortCreated  = "created_at"
+	TaskSortPriority = "priority"
+)
+
+// taskPriorityRank orders P0 first. Keep in sync with the
+// ix_tasks_project_priority expression index.
+const taskPriorityRank = `CASE priority WHEN 'P0' THEN 0 WHEN 'P1' THEN 1 WHEN 'P2' THEN 2 WHEN 'P3' THEN 3 ELSE 4 END`
+
+// taskOrderClauses maps allowed sort orders to their ORDER BY clauses.
+// Only these values are ever interpolated into SQL.
+var taskOrderClauses = map[string]string{
+	TaskSortCreated:  "created_at ASC",
+	TaskSortPriority: taskPriorityRank + " ASC, created_at ASC",
+}
+
+// IsValidTaskSort reports whether sort is an accepted task sort order.
+func IsValidTaskSort(sort string) bool {
+	_, ok := taskOrderClauses[sort]
+	return ok
+}
+
+// taskOverdueCond matches overdue tasks. Keep in sync with models.Task.IsOverdue.
+const taskOverdueCond = `due_at IS NOT NULL AND due_at < NOW() AND status <> 'completed'`
+
+// ListTasksByProject retrieves a page of tasks for a project in the given sort
+// order, along with the total count of matching tasks. With overdueOnly, only
+// overdue tasks are returned; archived tasks are left out unless
+// includeArchived. An empty or unknown sort falls back to creation order; a
+// limit <= 0 returns all rows.
+func (db *DB) ListTasksByProject(ctx context.Context, projectID uuid.UUID, sort string, overdueOnly, includeArchived bool, limit, offset int) ([]models.Task, int, error) {
+	orderBy, ok := taskOrderClauses[sort]
+	if !ok {
+		orderBy = taskOrderClauses[TaskSortCreated]
+	}
+
+	where := "WHERE project_id = $1"
+	if !includeArchived {
+		where += " AND NOT archived"
+	}
+	if overdueOnly {
+		where += " AND " + taskOverdueCond
+	}
+
+	var total int
+	err := db.withRetry(ctx, "count_tasks", func() error {
+		return db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM tasks `+where, projectID).Scan(&total)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
 	query := `
-		SELECT id, project_id, title, description, priority, status, crew_run_id, dependencies, created_at, updated_at
-		FROM tasks WHERE project_id = $1
-		ORDER BY created_at ASC
-	`
-	rows, err := db.pool.Query(ctx, query, projectID)
+		SELECT id, project_id, title, description, priority, status, crew_run_id, dependencies, due_at, archived, created_at, updated_at
+		FROM tasks ` + where + `
+		ORDER BY ` + orderBy
+	query, args := appendLimitOffset(query, []interface{}{projectID}, limit, offset)
+
+	now := time.Now()
+	var tasks []models.Task
+	err = db.withRetry(ctx, "list_tasks", func() error {
+		rows, err := db.pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		tasks = tasks[:0]
+		for rows.Next() {
+			var t models.Task
+			if err := rows.Scan(
+				&t.ID, &t.ProjectID, &t.Title, &t.Description,
+				&t.Priority, &t.Status, &t.CrewRunID, &t.Dependencies, &t.DueAt, &t.Archived, &t.CreatedAt, &t.UpdatedAt,
+			); err != nil {
+				return err
+			}
+			t.Overdue = t.IsOverdue(now)
+			tasks = append(tasks, t)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer rows.Close()
 
+	return tasks, total, nil
+}
+
+// ListTasksForUser retrieves a page of tasks across every project within orgID
+// that userID owns or, as a member of orgID, can see, newest first, optionally
+// filtered by status, along with the total number of matching tasks. Archived
+// tasks are left out. A limit <= 0 returns all rows.
+func (db *DB) ListTasksForUser(ctx context.Context, orgID *uuid.UUID, userID uuid.UUID, status string, limit, offset int) ([]models.Task, int, error) {
+	// Untenanted projects stay private to their owner, as in the handlers'
+	// project access checks
+	where := `WHERE (p.user_id = $1 OR (p.org_id IS NOT NULL AND EXISTS (
+			SELECT 1 FROM users u WHERE u.id = $1 AND u.org_id = p.org_id)))
+		AND ` + orgMatch("p.org_id", 2) + " AND NOT t.archived"
+	args := []interface{}{userID, orgID}
+	if status != "" {
+		where += " AND t.status = $3"
+		args = append(args, status)
+	}
+
+	var total int
+	err := db.withRetry(ctx, "count_user_tasks", func() error {
+		return db.pool.QueryRow(ctx, `
+			SELECT COUNT(*) FROM tasks t
+			JOIN projects p ON p.id = t.project_id `+where, args...).Scan(&total)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT t.id, t.project_id, t.title, t.description, t.priority, t.status, t.crew_run_id, t.dependencies, t.due_at, t.archived, t.created_at, t.updated_at
+		FROM tasks t
+		JOIN projects p ON p.id = t.project_id ` + where + `
+		ORDER BY t.created_at DESC, t.id`
+	query, args = appendLimitOffset(query, args, limit, offset)
+
+	now := time.Now()
 	var tasks []models.Task
-	for rows.Next() {
-		var t models.Task
-		if err := rows.Scan(
-			&t.ID, &t.ProjectID, &t.Title, &t.Description,
-			&t.Priority, &t.Status, &t.CrewRunID, &t.Dependencies, &t.CreatedAt, &t.UpdatedAt,
-		); err != nil {
-			return nil, err
+	err = db.withRetry(ctx, "list_user_tasks", func() error {
+		rows, err := db.pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
 		}
-		tasks = append(tasks, t)
+		defer rows.Close()
+
+		tasks = tasks[:0]
+		for rows.Next() {
+			var t models.Task
+			if err := rows.Scan(
+				&t.ID, &t.ProjectID, &t.Title, &t.Description,
+				&t.Priority, &t.Status, &t.CrewRunID, &t.Dependencies, &t.DueAt, &t.Archived, &t.CreatedAt, &t.UpdatedAt,
+			); err != nil {
+				return err
+			}
+			t.Overdue = t.IsOverdue(now)
+			tasks = append(tasks, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
 	}
 
-	return tasks, rows.Err()
+	return tasks, total, nil
 }
 
 // GetTaskByID retrieves a task by ID.
 func (db *DB) GetTaskByID(ctx context.Context, id uuid.UUID) (*models.Task, error) {
 	query := `
-		SELECT id, project_id, title, description, priority, status, crew_run_id, dependencies, created_at, updated_at
+		SELECT id, project_id, title, description, priority, status, crew_run_id, dependencies, due_at, archived, created_at, updated_at
 		FROM tasks WHERE id = $1
 	`
 	var task models.Task
-	err := db.pool.QueryRow(ctx, query, id).Scan(
-		&task.ID, &task.ProjectID, &task.Title, &task.Description,
-		&task.Priority, &task.Status, &task.CrewRunID, &task.Dependencies, &task.CreatedAt, &task.UpdatedAt,
-	)
+	err := db.withRetry(ctx, "get_task", func() error {
+		return db.pool.QueryRow(ctx, query, id).Scan(
+			&task.ID, &task.ProjectID, &task.Title, &task.Description,
+			&task.Priority, &task.Status, &task.CrewRunID, &task.Dependencies, &task.DueAt, &task.Archived, &task.CreatedAt, &task.UpdatedAt,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
+	task.Overdue = task.IsOverdue(time.Now())
 	return &task, nil
 }
 
-// UpdateTask updates a task.
-func (db *DB) UpdateTask(ctx context.Context, task *models.Task) error {
+// UpdateTask updates a task. Changing the due date re-arms the overdue
+// notification so a task_overdue event fires again for the new deadline.
+// Moving a task out of completed also takes it out of the archive. A non-zero
+// ifUpdatedAt makes the update conditional, as for UpdateProject.
+func (db *DB) UpdateTask(ctx context.Context, task *models.Task, ifUpdatedAt time.Time) error {
 	query := `
 		UPDATE tasks
-		SET title = $2, description = $3, priority = $4, status = $5
-		WHERE id = $1
+		SET title = $2, description = $3, priority = $4, status = $5,
+		    overdue_notified_at = CASE WHEN due_at IS DISTINCT FROM $6 THEN NULL ELSE overdue_notified_at END,
+		    due_at = $6, updated_at = $7, archived = archived AND $5 = '` + models.TaskStatusCompleted + `'
+		WHERE id = $1 AND ($8::timestamptz IS NULL OR updated_at = $8)
 	`
-	_, err := db.pool.Exec(ctx, query,
-		task.ID, task.Title, task.Description, task.Priority, task.Status,
+	tag, err := db.pool.Exec(ctx, query,
+		task.ID, task.Title, task.Description, task.Priority, task.Status, task.DueAt,
+		task.UpdatedAt, nullTime(ifUpdatedAt),
 	)
+	if err == nil && tag.RowsAffected() == 0 && !ifUpdatedAt.IsZero() {
+		return ErrStale
+	}
 	return err
 }
 
-// CountCompletedTasks counts completed tasks for a project.
-func (db *DB) CountCompletedTasks(ctx context.Context, projectID uuid.UUID) (int, error) {
-	query := `SELECT COUNT(*) FROM tasks WHERE project_id = $1 AND status = 'completed'`
-	var count int
-	err := db.pool.QueryRow(ctx, query, projectID).Scan(&count)
-	return count, err
+// ArchiveCompletedTasks archives up to limit tasks that have been completed
+// since before completedBefore, or deletes them with hardDelete, and returns
+// how many it affected. updated_at stands in for the completion time, since a
+// completed task is normally left alone. Deleting also removes tasks archived
+// earlier, so switching to hard-delete clears the backlog.
+func (db *DB) ArchiveCompletedTasks(ctx context.Context, completedBefore time.Time, hardDelete bool, limit int) (int64, error) {
+	match := `status = '` + models.TaskStatusCompleted + `' AND updated_at < $1`
+	query := `UPDATE tasks SET archived = true WHERE id IN (
+		SELECT id FROM tasks WHERE NOT archived AND ` + match + ` LIMIT $2)`
+	if hardDelete {
+		query = `DELETE FROM tasks WHERE id IN (
+		SELECT id FROM tasks WHERE ` + match + ` LIMIT $2)`
+	}
+	tag, err := db.pool.Exec(ctx, query, completedBefore, limit)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
 }
 
-// CountActiveRuns counts currently running crew runs for a project.
-func (db *DB) CountActiveRuns(ctx context.Context, projectID uuid.UUID) (int, error) {
+// MarkNewlyOverdueTasks flags tasks that have become overdue since the last
+// check and returns them, so each deadline is reported exactly once.
+func (db *DB) MarkNewlyOverdueTasks(ctx context.Context) ([]models.Task, error) {
 	query := `
-		SELECT COUNT(*) FROM crew_runs cr
-		JOIN tasks t ON t.crew_run_id = cr.id
-		WHERE t.project_id = $1 AND cr.status = 'running'
+		UPDATE tasks
+		SET overdue_notified_at = NOW()
+		WHERE overdue_notified_at IS NULL AND ` + taskOverdueCond + `
+		RETURNING id, project_id, title, priority, status, due_at
 	`
+	rows, err := db.pool.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.Task
+	for rows.Next() {
+		var t models.Task
+		if err := rows.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Priority, &t.Status, &t.DueAt); err != nil {
+			return nil, err
+		}
+		t.Overdue = true
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// CountOverdueTasks counts overdue tasks across all projects.
+func (db *DB) CountOverdueTasks(ctx context.Context) (int, error) {
 	var count int
-	err := db.pool.QueryRow(ctx, query, projectID).Scan(&count)
+	err := db.withRetry(ctx, "count_overdue_tasks", func() error {
+		return db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM tasks WHERE `+taskOverdueCond).Scan(&count)
+	})
 	return count, err
 }
+
+// CountDashboardStats counts a project's completed tasks and running crew
+// runs in a single round-trip.
+func (db *DB) CountDashboardStats(ctx context.Context, projectID uuid.UUID) (completedTasks, activeRuns int, err error) {
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE t.status = 'completed'),
+			COUNT(*) FILTER (WHERE cr.status = 'running')
+		FROM tasks t
+		LEFT JOIN crew_runs cr ON cr.id = t.crew_run_id
+		WHERE t.project_id = $1
+	`
+	err = db.withRetry(ctx, "count_dashboard_stats", func() error {
+		return db.pool.QueryRow(ctx, query, projectID).Scan(&completedTasks, &activeRuns)
+	})
+	return completedTasks, activeRuns, err
+}
+
+// ---- Audit Queries ----
+
+// InsertAuditEvent persists a security audit record.
+func (db *DB) InsertAuditEvent(ctx context.Context, e *models.AuditEvent) error {
+	query := `
+		INSERT INTO audit_events (event_type, actor, resource, action, outcome, ip_address, user_agent, request_id, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	var details interface{}
+	if len(e.Details) > 0 {
+		details = e.Details
+	}
+	_, err := db.pool.Exec(ctx, query,
+		e.EventType, e.Actor, e.Resource, e.Action, e.Outcome, e.IPAddress, e.UserAgent, e.RequestID, details,
+	)
+	return err
+}
+
+// StreamAuditEvents calls fn for each audit record that occurred in
+// [from, to), oldest first. Rows are read from the cursor one at a time so
+// large ranges are never held in memory; an error from fn stops the scan.
+func (db *DB) StreamAuditEvents(ctx context.Context, from, to time.Time, fn func(*models.AuditEvent) error) error {
+	query := `
+		SELECT id, occurred_at, event_type, actor, resource, action, outcome,
+		       COALESCE(ip_address, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''), details
+		FROM audit_events
+		WHERE occurred_at >= $1 AND occurred_at < $2
+		ORDER BY occurred_at ASC, id ASC
+	`
+	rows, err := db.pool.Query(ctx, query, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var e models.AuditEvent
+	for rows.Next() {
+		e.Details = nil
+		if err := rows.Scan(
+			&e.ID, &e.OccurredAt, &e.EventType, &e.Actor, &e.Resource, &e.Action, &e.Outcome,
+			&e.IPAddress, &e.UserAgent, &e.RequestID, &e.Details,
+		); err != nil {
+			return err
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ---- Event Queries ----
+
+// ListProjectEvents retrieves a page of a project's persisted events, newest
+// first, with the total count. A non-empty eventType restricts both to that type.
+func (db *DB) ListProjectEvents(ctx context.Context, projectID uuid.UUID, eventType string, limit, offset int) ([]models.MemoryEvent, int, error) {
+	where := "WHERE project_id = $1"
+	args := []interface{}{projectID}
+	if eventType != "" {
+		where += " AND event_type = $2"
+		args = append(args, eventType)
+	}
+
+	var total int
+	err := db.withRetry(ctx, "count_project_events", func() error {
+		return db.pool.QueryRow(ctx, `SELECT COUNT(*) FROM memory_events `+where, args...).Scan(&total)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, project_id, event_type, payload, published_at
+		FROM memory_events ` + where + `
+		ORDER BY published_at DESC, id DESC`
+	query, args = appendLimitOffset(query, args, limit, offset)
+
+	var events []models.MemoryEvent
+	err = db.withRetry(ctx, "list_project_events", func() error {
+		rows, err := db.pool.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		events = events[:0]
+		for rows.Next() {
+			var e models.MemoryEvent
+			if err := rows.Scan(&e.ID, &e.ProjectID, &e.EventType, &e.Payload, &e.PublishedAt); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// ListEventsSince retrieves persisted events for a project published in
+// [since, until), oldest first, returning at most limit rows.
+func (db *DB) ListEventsSince(ctx context.Context, projectID uuid.UUID, since, until time.Time, limit int) ([]models.MemoryEvent, error) {
+	query := `
+		SELECT id, project_id, event_type, payload, published_at
+		FROM memory_events
+		WHERE project_id = $1 AND published_at >= $2 AND published_at < $3
+		ORDER BY published_at ASC, id ASC
+		LIMIT $4
+	`
+	var events []models.MemoryEvent
+	err := db.withRetry(ctx, "list_events", func() error {
+		rows, err := db.pool.Query(ctx, query, projectID, since, until, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		events = events[:0]
+		for rows.Next() {
+			var e models.MemoryEvent
+			if err := rows.Scan(&e.ID, &e.ProjectID, &e.EventType, &e.Payload, &e.PublishedAt); err != nil {
+				return err
+			}
+			events = append(events, e)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
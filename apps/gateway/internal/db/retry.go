@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// DefaultMaxRetries is how many times a transient failure is retried by default.
+const DefaultMaxRetries = 3
+
+// retryBaseDelay is the first backoff; each further retry doubles it.
+const retryBaseDelay = 50 * time.Millisecond
+
+// retryableCodes are SQLSTATEs that indicate a transient condition. Anything
+// deterministic (constraint violations, syntax errors, missing rows) is
+// deliberately absent - retrying those only repeats the failure.
+var retryableCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+	"08001": true, // sqlclient_unable_to_establish_sqlconnection
+	"08004": true, // sqlserver_rejected_establishment_of_sqlconnection
+	"57P01": true, // admin_shutdown
+	"53300": true, // too_many_connections
+}
+
+// isRetryable reports whether err is a transient failure that is safe to
+// retry for idempotent reads and whole transactions.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return retryableCodes[pgErr.Code]
+	}
+
+	return pgconn.SafeToRetry(err) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withRetry runs fn, retrying transient failures with exponential backoff up
+// to db.maxRetries times. fn must be idempotent: a read, or a transaction
+// that is rolled back on failure.
+func (db *DB) withRetry(ctx context.Context, op string, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if attempt >= db.maxRetries || !isRetryable(err) {
+			return err
+		}
+
+		observability.Metrics.DBRetries.WithLabelValues(op).Inc()
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
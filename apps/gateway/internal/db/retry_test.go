@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"syntax error", &pgconn.PgError{Code: "42601"}, false},
+		{"no rows", pgx.ErrNoRows, false},
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"context cancelled", context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Fatalf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetry(t *testing.T) {
+	transient := &pgconn.PgError{Code: "40001"}
+
+	tests := []struct {
+		name         string
+		errs         []error
+		maxRetries   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"succeeds first time", []error{nil}, 3, 1, false},
+		{"recovers after transient errors", []error{transient, transient, nil}, 3, 3, false},
+		{"gives up after limit", []error{transient, transient, transient}, 2, 3, true},
+		{"deterministic error not retried", []error{&pgconn.PgError{Code: "23505"}}, 3, 1, true},
+		{"retries disabled", []error{transient}, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &DB{maxRetries: tt.maxRetries}
+			attempts := 0
+			err := db.withRetry(context.Background(), "test", func() error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+			if attempts != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var pgErr *pgconn.PgError
+			if err != nil && !errors.As(err, &pgErr) {
+				t.Fatalf("err = %v, want the last attempt's error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SSLModeDefault is the mode pgx uses when none is configured. It tries TLS
+// but silently falls back to plaintext.
+const SSLModeDefault = "prefer"
+
+// SSLMode returns the effective sslmode for a connection string in either
+// URL or keyword/value form, falling back to PGSSLMODE and then the default.
+func SSLMode(databaseURL string) string {
+	if mode := connParam(databaseURL, "sslmode"); mode != "" {
+		return mode
+	}
+	if mode := os.Getenv("PGSSLMODE"); mode != "" {
+		return mode
+	}
+	return SSLModeDefault
+}
+
+// SSLEnforced reports whether mode guarantees an encrypted connection.
+func SSLEnforced(mode string) bool {
+	switch mode {
+	case "require", "verify-ca", "verify-full":
+		return true
+	}
+	return false
+}
+
+// withRootCert adds sslrootcert to the connection string so the server
+// certificate is verified against the given CA. An explicit sslrootcert in
+// the connection string wins.
+func withRootCert(databaseURL, rootCert string) string {
+	if rootCert == "" || connParam(databaseURL, "sslrootcert") != "" {
+		return databaseURL
+	}
+	if isURL(databaseURL) {
+		u, err := url.Parse(databaseURL)
+		if err != nil {
+			return databaseURL
+		}
+		q := u.Query()
+		q.Set("sslrootcert", rootCert)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(rootCert)
+	return databaseURL + " sslrootcert='" + escaped + "'"
+}
+
+func connParam(databaseURL, key string) string {
+	if isURL(databaseURL) {
+		u, err := url.Parse(databaseURL)
+		if err != nil {
+			return ""
+		}
+		return u.Query().Get(key)
+	}
+	for _, field := range strings.Fields(databaseURL) {
+		if k, v, ok := strings.Cut(field, "="); ok && k == key {
+			return strings.Trim(v, "'")
+		}
+	}
+	return ""
+}
+
+func isURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://")
+}
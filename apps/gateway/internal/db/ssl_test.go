@@ -0,0 +1,61 @@
+package db
+
+import "testing"
+
+func TestSSLMode(t *testing.T) {
+	t.Setenv("PGSSLMODE", "")
+
+	tests := []struct {
+		name     string
+		url      string
+		want     string
+		enforced bool
+	}{
+		{"url disable", "postgres://u:p@localhost:5432/db?sslmode=disable", "disable", false},
+		{"url verify-full", "postgresql://u:p@db.internal/db?sslmode=verify-full", "verify-full", true},
+		{"url unset uses default", "postgres://u:p@localhost/db", SSLModeDefault, false},
+		{"keyword require", "host=db user=u sslmode=require", "require", true},
+		{"keyword quoted", "host=db sslmode='verify-ca'", "verify-ca", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SSLMode(tt.url)
+			if got != tt.want {
+				t.Fatalf("SSLMode = %q, want %q", got, tt.want)
+			}
+			if SSLEnforced(got) != tt.enforced {
+				t.Fatalf("SSLEnforced(%q) = %v, want %v", got, !tt.enforced, tt.enforced)
+			}
+		})
+	}
+
+	t.Run("PGSSLMODE fallback", func(t *testing.T) {
+		t.Setenv("PGSSLMODE", "require")
+		if got := SSLMode("postgres://u:p@localhost/db"); got != "require" {
+			t.Fatalf("SSLMode = %q, want require", got)
+		}
+	})
+}
+
+func TestWithRootCert(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		cert string
+		want string
+	}{
+		{"no cert", "postgres://h/db?sslmode=verify-full", "", "postgres://h/db?sslmode=verify-full"},
+		{"url form", "postgres://h/db?sslmode=verify-full", "/etc/ca.pem", "postgres://h/db?sslmode=verify-full&sslrootcert=%2Fetc%2Fca.pem"},
+		{"keyword form", "host=h sslmode=verify-full", "/etc/ca.pem", "host=h sslmode=verify-full sslrootcert='/etc/ca.pem'"},
+		{"explicit wins", "postgres://h/db?sslrootcert=/a.pem", "/etc/ca.pem", "postgres://h/db?sslrootcert=/a.pem"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withRootCert(tt.url, tt.cert); got != tt.want {
+				t.Fatalf("withRootCert = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
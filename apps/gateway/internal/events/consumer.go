@@ -0,0 +1,224 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/redis/go-redis/v9"
+)
+
+// Handler processes a single event received from the events channel.
+type Handler func(ctx context.Context, event Event) error
+
+// permanentError marks a failure that will not succeed on retry.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the consumer dead-letters the event without retrying.
+// Use it for bad payloads and other deterministic failures.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+// DeadLetter is an event that could not be processed, as stored in the DLQ.
+type DeadLetter struct {
+	EventType EventType `json:"event_type,omitempty"`
+	Message   string    `json:"message"` // Original message, republished verbatim on replay
+	Error     string    `json:"error"`
+	Attempts  int       `json:"attempts"`
+	FailedAt  string    `json:"failed_at"`
+}
+
+// Consumer subscribes to the events channel and dispatches events to handlers.
+// Failed events are retried and then pushed to the dead-letter queue. Replayed
+// events are skipped: they are for rebuilding state downstream, and applying
+// one again here would move a task back to an old status.
+type Consumer struct {
+	redis       *redis.Client
+	handlers    map[EventType]Handler
+	maxAttempts int
+	backoff     time.Duration
+	log         *slog.Logger
+}
+
+// NewConsumer creates a new consumer that tries each event up to maxAttempts times.
+func NewConsumer(redisClient *redis.Client, maxAttempts int, log *slog.Logger) *Consumer {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Consumer{
+		redis:       redisClient,
+		handlers:    make(map[EventType]Handler),
+		maxAttempts: maxAttempts,
+		backoff:     500 * time.Millisecond,
+		log:         log,
+	}
+}
+
+// Handle registers the handler for an event type. Events without a handler are ignored.
+func (c *Consumer) Handle(eventType EventType, handler Handler) {
+	c.handlers[eventType] = handler
+}
+
+// Run consumes events until ctx is cancelled.
+func (c *Consumer) Run(ctx context.Context) {
+	pubsub := c.redis.Subscribe(ctx, EventsChannel)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			c.process(ctx, msg.Payload)
+		}
+	}
+}
+
+// process handles one raw message, retrying transient failures.
+func (c *Consumer) process(ctx context.Context, message string) {
+	var event Event
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		c.deadLetter(ctx, "", message, fmt.Errorf("invalid event: %w", err), 1)
+		return
+	}
+
+	handler, ok := c.handlers[event.EventType]
+	if !ok || event.Replayed {
+		return
+	}
+
+	var err error
+	attempt := 1
+	for ; attempt <= c.maxAttempts; attempt++ {
+		if err = handler(ctx, event); err == nil {
+			return
+		}
+		if IsPermanent(err) || attempt == c.maxAttempts {
+			break
+		}
+		c.log.Warn("event processing failed, retrying",
+			"event_id", event.ID,
+			"event_type", event.EventType,
+			"attempt", attempt,
+			"error", err,
+		)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(attempt) * c.backoff):
+		}
+	}
+
+	c.deadLetter(ctx, event.EventType, message, err, attempt)
+}
+
+// deadLetter pushes a failed message onto the DLQ.
+func (c *Consumer) deadLetter(ctx context.Context, eventType EventType, message string, cause error, attempts int) {
+	c.log.Error("event dead-lettered",
+		"event_type", eventType,
+		"attempts", attempts,
+		"error", cause,
+	)
+
+	data, err := json.Marshal(DeadLetter{
+		EventType: eventType,
+		Message:   message,
+		Error:     cause.Error(),
+		Attempts:  attempts,
+		FailedAt:  time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		c.log.Error("failed to marshal dead letter", "error", err)
+		return
+	}
+
+	depth, err := c.redis.LPush(ctx, DeadLetterQueue, data).Result()
+	if err != nil {
+		c.log.Error("failed to push dead letter", "error", err)
+		return
+	}
+	observability.Metrics.EventsDLQDepth.Set(float64(depth))
+}
+
+// ListDeadLetters returns up to limit entries from the DLQ, newest first,
+// along with the total queue depth.
+func (s *Service) ListDeadLetters(ctx context.Context, limit int) ([]DeadLetter, int64, error) {
+	if !s.Enabled() {
+		return nil, 0, ErrNoRedis
+	}
+	depth, err := s.redis.LLen(ctx, DeadLetterQueue).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read DLQ depth: %w", err)
+	}
+	observability.Metrics.EventsDLQDepth.Set(float64(depth))
+
+	raw, err := s.redis.LRange(ctx, DeadLetterQueue, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read DLQ: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(raw))
+	for _, item := range raw {
+		var dl DeadLetter
+		if err := json.Unmarshal([]byte(item), &dl); err != nil {
+			continue // Skip corrupt entries rather than failing the listing
+		}
+		letters = append(letters, dl)
+	}
+	return letters, depth, nil
+}
+
+// ReplayDeadLetters republishes up to count of the oldest DLQ entries to the
+// events channel, removing them from the queue. Returns the number replayed.
+func (s *Service) ReplayDeadLetters(ctx context.Context, count int) (int, error) {
+	if !s.Enabled() {
+		return 0, ErrNoRedis
+	}
+	replayed := 0
+	for replayed < count {
+		item, err := s.redis.RPop(ctx, DeadLetterQueue).Result()
+		if err == redis.Nil {
+			break
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("failed to pop DLQ entry: %w", err)
+		}
+
+		var dl DeadLetter
+		if err := json.Unmarshal([]byte(item), &dl); err != nil {
+			continue // Unreadable entry; nothing to replay
+		}
+
+		if err := s.redis.Publish(ctx, EventsChannel, dl.Message).Err(); err != nil {
+			// Put it back so it isn't lost
+			_ = s.redis.RPush(ctx, DeadLetterQueue, item).Err()
+			return replayed, fmt.Errorf("failed to republish event: %w", err)
+		}
+		replayed++
+	}
+
+	if depth, err := s.redis.LLen(ctx, DeadLetterQueue).Result(); err == nil {
+		observability.Metrics.EventsDLQDepth.Set(float64(depth))
+	}
+	return replayed, nil
+}
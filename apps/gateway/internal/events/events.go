@@ -3,7 +3,10 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -13,8 +16,21 @@ import (
 type EventType string
 
 const (
-	EventTypeTaskCreated EventType = "task_created"
-	EventTypeTaskUpdated EventType = "task_updated"
+	EventTypeTaskCreated       EventType = "task_created"
+	EventTypeTaskUpdated       EventType = "task_updated"
+	EventTypeTaskOverdue       EventType = "task_overdue"
+	EventTypeTaskStatusChanged EventType = "task_status_changed"
+	EventTypeSessionEvicted    EventType = "session_evicted"
+)
+
+// SourceGateway marks events the gateway publishes itself, so its consumer
+// can tell them from the workers' events of the same type.
+const SourceGateway = "gateway"
+
+// Redis keys shared with the Python workers.
+const (
+	EventsChannel   = "kyros:events"
+	DeadLetterQueue = "kyros:events:dlq"
 )
 
 // Event represents the structure of an event message
@@ -22,11 +38,22 @@ type Event struct {
 	ID          string      `json:"id"`
 	ProjectID   string      `json:"project_id"`
 	EventType   EventType   `json:"event_type"`
+	Version     int         `json:"version,omitempty"`
 	Payload     interface{} `json:"payload"`
 	PublishedAt string      `json:"published_at"`
+	Replayed    bool        `json:"replayed,omitempty"`
+	Source      string      `json:"source,omitempty"` // SourceGateway, or empty from the workers
 }
 
-// Service handles event publishing
+// ErrNoRedis is returned by operations that can't work without Redis.
+var ErrNoRedis = errors.New("events: redis not configured")
+
+// warnDropped logs the first event dropped for lack of Redis; later drops
+// are logged at debug level only.
+var warnDropped sync.Once
+
+// Service handles event publishing. A nil Service, or one without a Redis
+// client, is valid: publishing becomes a logged no-op.
 type Service struct {
 	redis *redis.Client
 }
@@ -38,14 +65,38 @@ func New(redisClient *redis.Client) *Service {
 	}
 }
 
-// Publish publishes an event to the shared Redis channel
+// Enabled reports whether events can actually be delivered.
+func (s *Service) Enabled() bool {
+	return s != nil && s.redis != nil
+}
+
+// logDropped records an event that was discarded because Redis isn't configured.
+func logDropped(eventType EventType) {
+	level := slog.LevelDebug
+	warnDropped.Do(func() { level = slog.LevelWarn })
+	slog.Log(context.Background(), level, "redis not configured; dropping event", "event_type", eventType)
+}
+
+// Publish publishes an event to the shared Redis channel.
+// The payload is validated against the event type's schema first.
 func (s *Service) Publish(ctx context.Context, projectID string, eventType EventType, payload interface{}) error {
+	version, err := ValidatePayload(eventType, payload)
+	if err != nil {
+		return err
+	}
+	if !s.Enabled() {
+		logDropped(eventType)
+		return nil
+	}
+
 	event := Event{
 		ID:          fmt.Sprintf("%s-%d", eventType, time.Now().UnixNano()), // Simple unique ID
 		ProjectID:   projectID,
 		EventType:   eventType,
+		Version:     version,
 		Payload:     payload,
 		PublishedAt: time.Now().UTC().Format(time.RFC3339),
+		Source:      SourceGateway,
 	}
 
 	data, err := json.Marshal(event)
@@ -54,10 +105,30 @@ func (s *Service) Publish(ctx context.Context, projectID string, eventType Event
 	}
 
 	// Publish to the "kyros:events" channel, matching the Python service's subscription
-	err = s.redis.Publish(ctx, "kyros:events", data).Err()
+	err = s.redis.Publish(ctx, EventsChannel, data).Err()
 	if err != nil {
 		return fmt.Errorf("failed to publish event to redis: %w", err)
 	}
 
 	return nil
 }
+
+// Republish publishes a previously persisted event as-is, flagged as replayed
+// so consumers can tell it apart from live traffic.
+func (s *Service) Republish(ctx context.Context, event Event) error {
+	if !s.Enabled() {
+		return ErrNoRedis
+	}
+	event.Replayed = true
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if err := s.redis.Publish(ctx, EventsChannel, data).Err(); err != nil {
+		return fmt.Errorf("failed to publish event to redis: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestServiceWithoutRedis(t *testing.T) {
+	ctx := context.Background()
+	payload := TaskStatusChangedPayload{
+		TaskID:    uuid.NewString(),
+		ProjectID: uuid.NewString(),
+		OldStatus: "queued",
+		NewStatus: "running",
+	}
+
+	for name, s := range map[string]*Service{"nil service": nil, "nil client": New(nil)} {
+		t.Run(name, func(t *testing.T) {
+			if s.Enabled() {
+				t.Fatal("Enabled() = true without redis")
+			}
+			if err := s.Publish(ctx, payload.ProjectID, EventTypeTaskStatusChanged, payload); err != nil {
+				t.Errorf("Publish = %v, want nil", err)
+			}
+			if err := s.Publish(ctx, payload.ProjectID, EventTypeTaskStatusChanged, struct{}{}); !errors.Is(err, ErrInvalidPayload) {
+				t.Errorf("Publish invalid payload = %v, want ErrInvalidPayload", err)
+			}
+			if err := s.Republish(ctx, Event{}); !errors.Is(err, ErrNoRedis) {
+				t.Errorf("Republish = %v, want ErrNoRedis", err)
+			}
+			if _, _, err := s.ListDeadLetters(ctx, 10); !errors.Is(err, ErrNoRedis) {
+				t.Errorf("ListDeadLetters = %v, want ErrNoRedis", err)
+			}
+			if _, err := s.ReplayDeadLetters(ctx, 10); !errors.Is(err, ErrNoRedis) {
+				t.Errorf("ReplayDeadLetters = %v, want ErrNoRedis", err)
+			}
+		})
+	}
+}
+
+func TestConsumerSkipsReplayedEvents(t *testing.T) {
+	c := NewConsumer(nil, 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var handled []string
+	c.Handle(EventTypeTaskUpdated, func(_ context.Context, event Event) error {
+		handled = append(handled, event.ID)
+		return nil
+	})
+
+	for _, event := range []Event{
+		{ID: "live", EventType: EventTypeTaskUpdated},
+		{ID: "replayed", EventType: EventTypeTaskUpdated, Replayed: true},
+	} {
+		raw, err := json.Marshal(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.process(context.Background(), string(raw))
+	}
+	if len(handled) != 1 || handled[0] != "live" {
+		t.Errorf("handled %v, want only the live event", handled)
+	}
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// ErrInvalidPayload is returned when an event payload doesn't match its schema.
+var ErrInvalidPayload = errors.New("invalid event payload")
+
+// TaskCreatedPayload is the schema for task_created events.
+type TaskCreatedPayload struct {
+	ID        string `json:"id" validate:"required,uuid"`
+	ProjectID string `json:"project_id" validate:"required,uuid"`
+	Title     string `json:"title" validate:"required"`
+	Priority  string `json:"priority" validate:"required,task_priority"`
+	Status    string `json:"status" validate:"required"`
+}
+
+// TaskUpdatedPayload is the schema for task_updated events.
+type TaskUpdatedPayload struct {
+	TaskID string `json:"task_id" validate:"required,uuid"`
+	Status string `json:"status" validate:"required"`
+}
+
+// TaskOverduePayload is the schema for task_overdue events.
+type TaskOverduePayload struct {
+	TaskID    string `json:"task_id" validate:"required,uuid"`
+	ProjectID string `json:"project_id" validate:"required,uuid"`
+	Title     string `json:"title"`
+	DueAt     string `json:"due_at" validate:"required"`
+}
+
+// TaskStatusChangedPayload is the schema for task_status_changed events.
+type TaskStatusChangedPayload struct {
+	TaskID    string `json:"task_id" validate:"required,uuid"`
+	ProjectID string `json:"project_id" validate:"required,uuid"`
+	OldStatus string `json:"old_status" validate:"required"`
+	NewStatus string `json:"new_status" validate:"required"`
+}
+
+// SessionEvictedPayload is the schema for session_evicted events, published
+// with no project ID when the session cap signs a user out of their oldest
+// session. A notifier subscribed to the events channel tells the user.
+type SessionEvictedPayload struct {
+	UserID     string `json:"user_id" validate:"required,uuid"`
+	SessionID  string `json:"session_id" validate:"required"`
+	DeviceInfo string `json:"device_info"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	CreatedAt  string `json:"created_at" validate:"required"`
+}
+
+// schema describes the expected payload of an event type. Bump version when
+// the payload changes incompatibly so consumers can branch on it.
+type schema struct {
+	version    int
+	newPayload func() interface{}
+}
+
+var schemas = map[EventType]schema{
+	EventTypeTaskCreated:       {version: 1, newPayload: func() interface{} { return &TaskCreatedPayload{} }},
+	EventTypeTaskUpdated:       {version: 1, newPayload: func() interface{} { return &TaskUpdatedPayload{} }},
+	EventTypeTaskOverdue:       {version: 1, newPayload: func() interface{} { return &TaskOverduePayload{} }},
+	EventTypeTaskStatusChanged: {version: 1, newPayload: func() interface{} { return &TaskStatusChangedPayload{} }},
+	EventTypeSessionEvicted:    {version: 1, newPayload: func() interface{} { return &SessionEvictedPayload{} }},
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	models.RegisterValidators(v)
+	return v
+}
+
+// ValidatePayload checks payload against the schema for eventType and returns
+// the schema version. The payload may be any value that marshals to the
+// expected JSON shape; extra fields are allowed.
+func ValidatePayload(eventType EventType, payload interface{}) (int, error) {
+	sc, ok := schemas[eventType]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown event type %q", ErrInvalidPayload, eventType)
+	}
+	if err := DecodePayload(eventType, payload, sc.newPayload()); err != nil {
+		return 0, err
+	}
+	return sc.version, nil
+}
+
+// DecodePayload decodes payload into dst, a pointer to the schema struct for
+// eventType, and validates it.
+func DecodePayload(eventType EventType, payload interface{}, dst interface{}) error {
+	return decodePayload(eventType, payload, dst, false)
+}
+
+// DecodePayloadStrict is DecodePayload for events received from the workers:
+// fields the schema doesn't know are rejected rather than ignored, so a
+// worker bug surfaces as a dead letter instead of a half-applied update.
+func DecodePayloadStrict(eventType EventType, payload interface{}, dst interface{}) error {
+	return decodePayload(eventType, payload, dst, true)
+}
+
+func decodePayload(eventType EventType, payload interface{}, dst interface{}, strict bool) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidPayload, eventType, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidPayload, eventType, err)
+	}
+	if err := validate.Struct(dst); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidPayload, eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidatePayload(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name      string
+		eventType EventType
+		payload   interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "valid task_created struct",
+			eventType: EventTypeTaskCreated,
+			payload: struct {
+				ID        uuid.UUID `json:"id"`
+				ProjectID uuid.UUID `json:"project_id"`
+				Title     string    `json:"title"`
+				Priority  string    `json:"priority"`
+				Status    string    `json:"status"`
+				Extra     string    `json:"extra"`
+			}{id, id, "Build API", "P1", "queued", "ignored"},
+		},
+		{
+			name:      "task_created missing title",
+			eventType: EventTypeTaskCreated,
+			payload:   map[string]string{"id": id.String(), "project_id": id.String(), "priority": "P1", "status": "queued"},
+			wantErr:   true,
+		},
+		{
+			name:      "task_created bad priority",
+			eventType: EventTypeTaskCreated,
+			payload:   map[string]string{"id": id.String(), "project_id": id.String(), "title": "x", "priority": "P9", "status": "queued"},
+			wantErr:   true,
+		},
+		{
+			name:      "valid task_updated",
+			eventType: EventTypeTaskUpdated,
+			payload:   map[string]string{"task_id": id.String(), "status": "completed"},
+		},
+		{
+			name:      "task_updated wrong shape",
+			eventType: EventTypeTaskUpdated,
+			payload:   []string{"nope"},
+			wantErr:   true,
+		},
+		{
+			name:      "valid session_evicted",
+			eventType: EventTypeSessionEvicted,
+			payload:   map[string]string{"user_id": id.String(), "session_id": id.String(), "device_info": "Old Laptop", "created_at": "2026-01-01T00:00:00Z"},
+		},
+		{
+			name:      "session_evicted missing user",
+			eventType: EventTypeSessionEvicted,
+			payload:   map[string]string{"session_id": id.String(), "created_at": "2026-01-01T00:00:00Z"},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown event type",
+			eventType: EventType("mystery"),
+			payload:   map[string]string{},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := ValidatePayload(tt.eventType, tt.payload)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidPayload) {
+					t.Fatalf("err = %v, want ErrInvalidPayload", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if version < 1 {
+				t.Fatalf("version = %d, want >= 1", version)
+			}
+		})
+	}
+}
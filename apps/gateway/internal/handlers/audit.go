@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// Audit event types, matching the API service's audit trail.
+const (
+	auditMFADisabled     = "auth.mfa_disabled"
+	auditPasswordChanged = "auth.password_changed"
+	auditExported        = "admin.audit_exported"
+	auditOrgCreated      = "admin.org_created"
+	auditOrgMemberSet    = "org.member_role_set"
+)
+
+// auditInsertTimeout bounds the best-effort database write of an audit record.
+const auditInsertTimeout = 2 * time.Second
+
+// audit writes a security audit record using the same fields as the API
+// service's audit log, so both streams can be analysed together. The record is
+// also stored in audit_events for export; a failed insert is logged but never
+// fails the request.
+func (h *Handler) audit(r *http.Request, eventType, actor, resource, action, outcome string, details ...any) {
+	h.log.Info("audit",
+		slog.String("event_type", eventType),
+		slog.String("actor", actor),
+		slog.String("resource", resource),
+		slog.String("action", action),
+		slog.String("outcome", outcome),
+		slog.String("ip_address", r.RemoteAddr),
+		slog.String("user_agent", r.UserAgent()),
+		slog.String("request_id", chimw.GetReqID(r.Context())),
+		slog.Group("details", details...),
+	)
+
+	if h.db == nil {
+		return
+	}
+	event := &models.AuditEvent{
+		EventType: eventType,
+		Actor:     actor,
+		Resource:  resource,
+		Action:    action,
+		Outcome:   outcome,
+		IPAddress: r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+		RequestID: chimw.GetReqID(r.Context()),
+		Details:   auditDetailsJSON(details),
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), auditInsertTimeout)
+	defer cancel()
+	if err := h.db.InsertAuditEvent(ctx, event); err != nil {
+		h.log.Error("failed to store audit event", "error", err, "event_type", eventType)
+	}
+}
+
+// auditDetailsJSON encodes slog-style key/value details as a JSON object,
+// or nil when there are none.
+func auditDetailsJSON(details []any) json.RawMessage {
+	attrs := slog.Group("details", details...).Value.Group()
+	if len(attrs) == 0 {
+		return nil
+	}
+	fields := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		fields[a.Key] = a.Value.Resolve().Any()
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	return data
+}
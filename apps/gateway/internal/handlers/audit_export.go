@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// auditExportFlushEvery is how many rows are written between flushes, so
+// clients see progress on large exports without a flush per row.
+const auditExportFlushEvery = 500
+
+// errAuditRangeTooLarge reports a from/to window wider than the configured limit.
+var errAuditRangeTooLarge = errors.New("range too large")
+
+// auditCSVHeader lists the CSV columns, in the order written by auditCSVRecord.
+var auditCSVHeader = []string{
+	"id", "occurred_at", "event_type", "actor", "resource", "action",
+	"outcome", "ip_address", "user_agent", "request_id", "details",
+}
+
+// auditExportQuery is a validated ?format=&from=&to= selection.
+type auditExportQuery struct {
+	Format string
+	From   time.Time
+	To     time.Time
+}
+
+// parseAuditExportQuery validates the export parameters. format defaults to
+// json, from is required and to defaults to now; the window may not exceed
+// maxRange.
+func parseAuditExportQuery(q url.Values, now time.Time, maxRange time.Duration) (auditExportQuery, error) {
+	query := auditExportQuery{Format: q.Get("format"), To: now}
+	if query.Format == "" {
+		query.Format = "json"
+	}
+	if query.Format != "json" && query.Format != "csv" {
+		return query, errors.New("format must be csv or json")
+	}
+
+	raw := q.Get("from")
+	if raw == "" {
+		return query, errors.New("from is required")
+	}
+	from, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return query, errors.New("from must be an RFC 3339 timestamp")
+	}
+	query.From = from.UTC()
+
+	if raw := q.Get("to"); raw != "" {
+		to, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, errors.New("to must be an RFC 3339 timestamp")
+		}
+		query.To = to.UTC()
+	}
+	if !query.To.After(query.From) {
+		return query, errors.New("to must be after from")
+	}
+	if maxRange > 0 && query.To.Sub(query.From) > maxRange {
+		return query, fmt.Errorf("%w: at most %s per export", errAuditRangeTooLarge, maxRange)
+	}
+	return query, nil
+}
+
+// ExportAudit handles GET /admin/audit/export - streams audit records in a
+// time range as a CSV or JSON download. Rows are written as they are read,
+// so an error after the first row can only be logged, not reported.
+func (h *Handler) ExportAudit(w http.ResponseWriter, r *http.Request) {
+	admin := auth.GetUserFromContext(r.Context())
+	if admin == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	maxRange := time.Duration(h.cfg.AuditExportMaxDays) * 24 * time.Hour
+	query, err := parseAuditExportQuery(r.URL.Query(), time.Now().UTC(), maxRange)
+	if errors.Is(err, errAuditRangeTooLarge) {
+		h.writeError(w, http.StatusBadRequest, "range_too_large", err.Error())
+		return
+	}
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_query", err.Error())
+		return
+	}
+
+	h.audit(r, auditExported, admin.ID.String(), "audit_events", "export", "success",
+		"format", query.Format, "from", models.FormatTime(query.From), "to", models.FormatTime(query.To))
+
+	filename := fmt.Sprintf("audit-%s-%s.%s",
+		query.From.Format("20060102T150405Z"), query.To.Format("20060102T150405Z"), query.Format)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	var stream auditStream
+	if query.Format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		stream = newAuditCSVStream(w)
+	} else {
+		w.Header().Set("Content-Type", "application/json")
+		stream = newAuditJSONStream(w)
+	}
+
+	rc := http.NewResponseController(w)
+	rows := 0
+	err = stream.begin()
+	if err == nil {
+		err = h.db.StreamAuditEvents(r.Context(), query.From, query.To, func(e *models.AuditEvent) error {
+			if err := stream.write(e); err != nil {
+				return err
+			}
+			rows++
+			if rows%auditExportFlushEvery == 0 {
+				if err := stream.flush(); err != nil {
+					return err
+				}
+				_ = rc.Flush()
+			}
+			return nil
+		})
+	}
+	if err == nil {
+		err = stream.end()
+	}
+	if err != nil {
+		h.logger(r).Error("audit export failed", "error", err, "rows", rows)
+		return
+	}
+	h.logger(r).Info("audit export completed", "format", query.Format, "rows", rows)
+}
+
+// auditStream writes audit records incrementally in one export format.
+type auditStream interface {
+	begin() error
+	write(e *models.AuditEvent) error
+	flush() error
+	end() error
+}
+
+// auditCSVStream writes a header row followed by one row per record.
+type auditCSVStream struct {
+	w *csv.Writer
+}
+
+func newAuditCSVStream(w io.Writer) *auditCSVStream {
+	return &auditCSVStream{w: csv.NewWriter(w)}
+}
+
+func (s *auditCSVStream) begin() error {
+	return s.w.Write(auditCSVHeader)
+}
+
+func (s *auditCSVStream) write(e *models.AuditEvent) error {
+	return s.w.Write(auditCSVRecord(e))
+}
+
+func (s *auditCSVStream) flush() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *auditCSVStream) end() error {
+	return s.flush()
+}
+
+// auditCSVRecord flattens a record into auditCSVHeader's columns, keeping
+// details as a JSON string.
+func auditCSVRecord(e *models.AuditEvent) []string {
+	return []string{
+		strconv.FormatInt(e.ID, 10),
+		models.FormatTime(e.OccurredAt),
+		e.EventType,
+		e.Actor,
+		e.Resource,
+		e.Action,
+		e.Outcome,
+		e.IPAddress,
+		e.UserAgent,
+		e.RequestID,
+		string(e.Details),
+	}
+}
+
+// auditJSONStream writes a single JSON array, one element per record.
+type auditJSONStream struct {
+	w     io.Writer
+	count int
+}
+
+func newAuditJSONStream(w io.Writer) *auditJSONStream {
+	return &auditJSONStream{w: w}
+}
+
+func (s *auditJSONStream) begin() error {
+	_, err := io.WriteString(s.w, "[")
+	return err
+}
+
+func (s *auditJSONStream) write(e *models.AuditEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if s.count > 0 {
+		if _, err := io.WriteString(s.w, ","); err != nil {
+			return err
+		}
+	}
+	s.count++
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+func (s *auditJSONStream) flush() error {
+	return nil
+}
+
+func (s *auditJSONStream) end() error {
+	_, err := io.WriteString(s.w, "]\n")
+	return err
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestParseAuditExportQuery(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		query    string
+		wantErr  bool
+		tooLarge bool
+		format   string
+	}{
+		{"defaults to json and now", "from=2026-10-15T00:00:00Z", false, false, "json"},
+		{"csv", "format=csv&from=2026-10-15T00:00:00Z&to=2026-10-16T00:00:00Z", false, false, "csv"},
+		{"unknown format", "format=xml&from=2026-10-15T00:00:00Z", true, false, ""},
+		{"missing from", "format=csv", true, false, ""},
+		{"bad timestamp", "from=yesterday", true, false, ""},
+		{"inverted range", "from=2026-10-16T00:00:00Z&to=2026-10-15T00:00:00Z", true, false, ""},
+		{"range too large", "from=2026-01-01T00:00:00Z", true, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := parseAuditExportQuery(q, now, week)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, errAuditRangeTooLarge) != tt.tooLarge {
+				t.Errorf("err = %v, tooLarge %v", err, tt.tooLarge)
+			}
+			if err == nil && got.Format != tt.format {
+				t.Errorf("format = %q, want %q", got.Format, tt.format)
+			}
+		})
+	}
+}
+
+func TestAuditStreams(t *testing.T) {
+	events := []*models.AuditEvent{
+		{ID: 1, EventType: auditExported, Actor: "a", Details: json.RawMessage(`{"format":"csv"}`)},
+		{ID: 2, EventType: auditPasswordChanged, Actor: "b, c"},
+	}
+
+	var csvBuf bytes.Buffer
+	var jsonBuf bytes.Buffer
+	for _, s := range []auditStream{newAuditCSVStream(&csvBuf), newAuditJSONStream(&jsonBuf)} {
+		if err := s.begin(); err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range events {
+			if err := s.write(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.end(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(csvBuf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "id,occurred_at,") || !strings.Contains(lines[2], `"b, c"`) {
+		t.Errorf("unexpected CSV:\n%s", csvBuf.String())
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &decoded); err != nil {
+		t.Fatalf("JSON export is not an array: %v\n%s", err, jsonBuf.String())
+	}
+	if len(decoded) != 2 || decoded[0]["details"].(map[string]interface{})["format"] != "csv" {
+		t.Errorf("unexpected JSON: %s", jsonBuf.String())
+	}
+}
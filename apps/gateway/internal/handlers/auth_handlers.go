@@ -2,13 +2,19 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/events"
 	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
 )
 
 // ---- OAuth Handlers ----
@@ -23,13 +29,21 @@ func (h *Handler) OAuthStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate and store state
-	state, err := auth.GenerateState()
+	// Check return_to now so a bad link fails before the provider round trip;
+	// the callback checks it again before redirecting
+	returnTo := r.URL.Query().Get("return_to")
+	if returnTo != "" && !h.redirects.Allows(returnTo) {
+		h.logger(r).Warn("rejected OAuth redirect", "provider", provider, "return_to", returnTo, "ip", h.auth.ClientIP(r).String())
+		h.writeError(w, http.StatusBadRequest, "invalid_redirect", "return_to is not an allowed redirect")
+		return
+	}
+
+	// Generate state (stored or signed, depending on OAUTH_STATE_MODE)
+	state, err := h.oauthStates.Issue(provider, returnTo)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to generate state")
 		return
 	}
-	h.oauthStates.Store(state)
 
 	// Redirect to OAuth provider
 	authURL := oauthProvider.GetAuthURL(state)
@@ -40,13 +54,32 @@ func (h *Handler) OAuthStart(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 	provider := chi.URLParam(r, "provider")
 
+	// Every return below is counted; unconfigured providers share one label
+	metricProvider := "unknown"
+	if _, err := h.oauth.GetProvider(provider); err == nil {
+		metricProvider = provider
+	}
+	var success, newUser bool
+	defer func() { observability.RecordOAuthLogin(metricProvider, success, newUser) }()
+
 	// Validate state
 	state := r.URL.Query().Get("state")
-	if !h.oauthStates.Validate(state) {
+	returnTo, ok := h.oauthStates.Verify(state, provider)
+	if !ok {
 		h.writeError(w, http.StatusBadRequest, "invalid_state", "Invalid or expired OAuth state")
 		return
 	}
 
+	// Where to send the browser, checked before anything is issued
+	if returnTo == "" {
+		returnTo = h.cfg.FrontendURL() + "/dashboard"
+	}
+	if h.cfg.AuthCookies() && !h.redirects.Allows(returnTo) {
+		h.logger(r).Warn("rejected OAuth redirect", "provider", provider, "return_to", returnTo, "ip", h.auth.ClientIP(r).String())
+		h.writeError(w, http.StatusBadRequest, "invalid_redirect", "return_to is not an allowed redirect")
+		return
+	}
+
 	// Get code
 	code := r.URL.Query().Get("code")
 	if code == "" {
@@ -63,78 +96,126 @@ func (h *Handler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
 
 	oauthUser, err := oauthProvider.ExchangeCode(r.Context(), code)
 	if err != nil {
-		h.log.Error("oauth exchange failed", "provider", provider, "error", err)
+		h.logger(r).Error("oauth exchange failed", "provider", provider, "error", err)
 		h.writeError(w, http.StatusBadRequest, "oauth_failed", "Failed to authenticate with provider")
 		return
 	}
 
-	// Find or create user
-	user, err := h.db.GetUserByEmail(r.Context(), oauthUser.Email)
-	if err != nil {
-		// Create new user from OAuth
+	// Find the user by linked provider account first, so a returning user
+	// is recognized even if their email at the provider changed. Otherwise
+	// fall back to email, which links a second provider to an existing
+	// account, or create the user.
+	user, err := h.db.GetUserByOAuthAccount(r.Context(), oauthUser.Provider, oauthUser.ProviderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Only a verified email may claim an existing account
+		if !oauthUser.EmailVerified {
+			h.writeError(w, http.StatusForbidden, "email_unverified", "Verify your email with the provider before signing in")
+			return
+		}
+		user, err = h.db.GetUserByEmail(r.Context(), oauthUser.Email)
+		if err == nil && user.EmailVerifiedAt == nil {
+			err = h.claimUnverifiedUser(r, user)
+		}
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		h.logger(r).Error("failed to look up oauth user", "provider", provider, "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to look up user")
+		return
+	}
+	if user == nil {
+		// Create new user from OAuth; the provider has verified the email
+		now := time.Now().UTC()
 		user = &models.User{
-			ID:        uuid.New(),
-			Username:  oauthUser.Name,
-			Email:     oauthUser.Email,
-			Role:      "user",
-			Active:    true,
-			CreatedAt: time.Now().UTC(),
+			ID:              uuid.New(),
+			Username:        oauthUser.Name,
+			Email:           oauthUser.Email,
+			Role:            "user",
+			Active:          true,
+			EmailVerifiedAt: &now,
+			CreatedAt:       now,
 		}
 		if err := h.db.CreateUser(r.Context(), user); err != nil {
-			h.log.Error("failed to create oauth user", "error", err)
+			h.logger(r).Error("failed to create oauth user", "error", err)
 			h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create user")
 			return
 		}
+		newUser = true
+	}
+
+	if !user.Active {
+		h.writeError(w, http.StatusForbidden, "account_inactive", "Account is inactive. Contact an administrator.")
+		return
+	}
+	if err := h.db.RecordLogin(r.Context(), user.ID); err != nil {
+		h.logger(r).Warn("failed to record login", "error", err)
 	}
 
 	// Link OAuth account to user
 	if err := h.db.LinkOAuthAccount(r.Context(), user.ID, oauthUser.Provider, oauthUser.ProviderID, oauthUser.Email, oauthUser.AccessToken, oauthUser.RefreshToken); err != nil {
-		h.log.Warn("failed to link oauth account", "error", err)
+		h.logger(r).Warn("failed to link oauth account", "error", err)
 		// Non-fatal: user can still login, just won't have linked account
 	}
 
-	// Create tokens
-	accessToken, err := h.auth.CreateAccessToken(user)
+	// Create tokens, tied to the session like those from Login
+	sessionID := h.startSession(w, r, user)
+	accessToken, err := h.auth.CreateAccessToken(user, sessionID)
 	if err != nil {
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create token")
 		return
 	}
 
-	refreshToken, _ := h.auth.CreateRefreshToken(user)
+	refreshToken, _ := h.auth.CreateRefreshToken(user, sessionID)
+
+	// Token-only clients have no cookie jar to hand the tokens to, so they
+	// get them in the body, as from Login
+	if !h.cfg.AuthCookies() {
+		success = true
+		h.writeJSON(w, r, http.StatusOK, models.TokenResponse{
+			AccessToken:  accessToken,
+			TokenType:    "bearer",
+			RefreshToken: refreshToken,
+			ExpiresIn:    h.cfg.JWTExpireMinutes * 60,
+			SessionID:    sessionID,
+		})
+		return
+	}
 
 	// Set cookie and redirect to frontend
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.IsProduction(),
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   h.cfg.JWTExpireMinutes * 60,
-	})
+	http.SetCookie(w, h.auth.AccessTokenCookie(accessToken))
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "refresh_token",
-		Value:    refreshToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.IsProduction(),
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   h.cfg.JWTRefreshExpireDays * 24 * 60 * 60,
-	})
+	http.SetCookie(w, h.auth.RefreshTokenCookie(refreshToken))
 
 	// Redirect to frontend
-	http.Redirect(w, r, h.cfg.CORSAllowOrigins[0]+"/dashboard", http.StatusTemporaryRedirect)
+	success = true
+	http.Redirect(w, r, returnTo, http.StatusTemporaryRedirect)
 }
 
 // ListOAuthProviders handles GET /auth/oauth/providers - lists available OAuth providers.
 func (h *Handler) ListOAuthProviders(w http.ResponseWriter, r *http.Request) {
 	providers := h.oauth.ListProviders()
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
 		"providers": providers,
 	})
 }
 
+// claimUnverifiedUser hands an account whose email was never verified to the
+// OAuth user who just proved they own that email. Anyone could have
+// registered it first with a password, so the password, MFA and every
+// session are dropped before the provider is linked.
+func (h *Handler) claimUnverifiedUser(r *http.Request, user *models.User) error {
+	claimed, err := h.db.ClaimUnverifiedUser(r.Context(), user.ID)
+	if err != nil || !claimed {
+		return err
+	}
+	h.logger(r).Warn("oauth sign-in claimed an unverified account; password and sessions cleared", "user_id", user.ID)
+	if manager := h.sessionManager(); manager != nil {
+		if err := manager.RevokeAllUserSessions(r.Context(), user.ID.String()); err != nil {
+			h.logger(r).Error("failed to revoke sessions of claimed account", "error", err, "user_id", user.ID)
+		}
+	}
+	return nil
+}
+
 // ---- MFA Handlers ----
 
 // MFASetup handles POST /auth/mfa/setup - generates TOTP secret.
@@ -150,15 +231,91 @@ func (h *Handler) MFASetup(w http.ResponseWriter, r *http.Request) {
 		BackupCodes: 10,
 	})
 	if err != nil {
-		h.log.Error("failed to generate TOTP", "error", err)
+		h.logger(r).Error("failed to generate TOTP", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to setup MFA")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
-		"secret":       setup.Secret,
-		"url":          setup.URL,
-		"backup_codes": setup.BackupCodes,
+	// Backup codes are stored hashed, so the file download has to be offered
+	// now, while the plaintext exists. The link seals the codes for this user.
+	download, err := h.codeFiles.Issue(user.ID, setup.BackupCodes)
+	if err != nil {
+		h.logger(r).Error("failed to issue backup code download", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to setup MFA")
+		return
+	}
+	downloadURL := strings.TrimSuffix(r.URL.Path, "/setup") + "/backup-codes/download?token=" + download
+
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"secret":                    setup.Secret,
+		"url":                       setup.URL,
+		"backup_codes":              setup.BackupCodes,
+		"backup_codes_download_url": downloadURL,
+	})
+}
+
+// backupCodeDownloadTTL is how long the link returned by MFA setup works.
+const backupCodeDownloadTTL = 10 * time.Minute
+
+// MFADownloadBackupCodes handles GET /auth/mfa/backup-codes/download - returns
+// the backup codes from a recent MFA setup as a text file. Stored codes are
+// hashed and can't be recovered, so this only redeems the short-lived link
+// from the setup response, and only for the user it was issued to.
+func (h *Handler) MFADownloadBackupCodes(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	codes, err := h.codeFiles.Open(r.URL.Query().Get("token"), user.ID)
+	if errors.Is(err, auth.ErrDownloadExpired) {
+		h.writeError(w, http.StatusGone, "download_expired", "Download link expired; set up MFA again for new codes")
+		return
+	}
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_token", "Invalid download link")
+		return
+	}
+
+	var body strings.Builder
+	body.WriteString(h.cfg.MFAIssuer + " MFA backup codes\n")
+	body.WriteString("Each code works once. Keep this file somewhere safe and offline.\n\n")
+	for _, code := range codes {
+		body.WriteString(code + "\n")
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="mfa-backup-codes.txt"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, body.String())
+}
+
+// MFABackupCodeCount handles GET /auth/mfa/backup-codes/count - reports how
+// many backup codes the user has left, so they know when to regenerate. Used
+// codes are deleted when MFAVerify consumes them, so every stored hash is
+// unused; no code is ever checked here.
+func (h *Handler) MFABackupCodeCount(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	enabled, _, backupCodes, err := h.db.GetUserMFA(r.Context(), user.ID)
+	if err != nil {
+		h.logger(r).Error("failed to get MFA settings", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to load backup codes")
+		return
+	}
+	if !enabled {
+		h.writeError(w, http.StatusBadRequest, "mfa_not_enabled", "MFA is not enabled for this user")
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"backup_codes_left": len(backupCodes),
 	})
 }
 
@@ -180,8 +337,9 @@ func (h *Handler) MFAEnable(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate the code
-	if !auth.ValidateTOTP(req.Secret, req.Code) {
+	// Setup is a one-off check that the authenticator is configured, so allow
+	// more clock drift than MFAVerify does at login
+	if !auth.ValidateTOTPWithWindow(req.Secret, req.Code, uint(h.cfg.MFAEnableSkew)) {
 		h.writeError(w, http.StatusBadRequest, "invalid_code", "Invalid verification code")
 		return
 	}
@@ -194,39 +352,62 @@ func (h *Handler) MFAEnable(w http.ResponseWriter, r *http.Request) {
 
 	// Store MFA settings in database
 	if err := h.db.UpdateUserMFA(r.Context(), user.ID, true, &req.Secret, hashedCodes); err != nil {
-		h.log.Error("failed to enable MFA", "error", err)
+		h.logger(r).Error("failed to enable MFA", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to enable MFA")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
 		"enabled": true,
 		"message": "MFA enabled successfully",
 	})
 }
 
-// MFAVerify handles POST /auth/mfa/verify - verifies TOTP during login.
+// MFAVerify handles POST /auth/mfa/verify - the second step of a login for a
+// user with MFA. It takes the challenge token from Login with a TOTP or
+// backup code and answers with the user's tokens, as Login does without MFA.
 func (h *Handler) MFAVerify(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		UserID string `json:"user_id"`
-		Code   string `json:"code"`
-	}
+	var req models.MFAVerifyRequest
 	if err := h.decodeAndValidate(r, &req); err != nil {
 		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
 		return
 	}
 
-	// Parse user ID
-	userID, err := uuid.Parse(req.UserID)
+	claims, err := h.auth.ValidateMFAChallengeToken(req.MFAToken)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_user_id", "Invalid user ID format")
+		h.writeError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired MFA token; log in again")
+		return
+	}
+
+	// Each challenge takes a few codes wherever they come from, so guesses
+	// spread across addresses still run out
+	ttl := time.Until(claims.ExpiresAt.Time) + h.cfg.JWTLeeway()
+	err = h.sessionManager().CountMFAAttempt(r.Context(), claims.ID, auth.MFAMaxAttempts, ttl)
+	if errors.Is(err, auth.ErrMFAChallengeExhausted) {
+		h.logger(r).Warn("MFA challenge exhausted", "user_id", claims.UserID)
+		h.writeError(w, http.StatusUnauthorized, "mfa_attempts_exceeded", "Too many invalid codes; log in again")
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to record MFA attempt", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to verify MFA")
+		return
+	}
+	user, err := h.db.GetUserByID(r.Context(), claims.UserID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !user.Active) {
+		h.writeError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired MFA token; log in again")
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to load user for MFA", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to verify MFA")
 		return
 	}
 
 	// Get user's MFA secret from database
-	enabled, secret, backupCodes, err := h.db.GetUserMFA(r.Context(), userID)
+	enabled, secret, backupCodes, err := h.db.GetUserMFA(r.Context(), user.ID)
 	if err != nil {
-		h.log.Error("failed to get MFA settings", "error", err)
+		h.logger(r).Error("failed to get MFA settings", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to verify MFA")
 		return
 	}
@@ -238,24 +419,20 @@ func (h *Handler) MFAVerify(w http.ResponseWriter, r *http.Request) {
 
 	// Try TOTP first
 	if auth.ValidateTOTPWithWindow(*secret, req.Code, 1) {
-		h.writeJSON(w, http.StatusOK, map[string]interface{}{
-			"verified": true,
-		})
+		h.completeLogin(w, r, user)
 		return
 	}
 
-	// Try backup codes
+	// Try backup codes; a code that can't be removed isn't accepted, so it
+	// can't be used twice
 	if idx := auth.ValidateBackupCode(req.Code, backupCodes); idx >= 0 {
-		// Remove used backup code
 		newCodes := append(backupCodes[:idx], backupCodes[idx+1:]...)
-		if err := h.db.UpdateUserMFA(r.Context(), userID, true, secret, newCodes); err != nil {
-			h.log.Error("failed to update backup codes", "error", err)
+		if err := h.db.UpdateUserMFA(r.Context(), user.ID, true, secret, newCodes); err != nil {
+			h.logger(r).Error("failed to update backup codes", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to verify MFA")
+			return
 		}
-		h.writeJSON(w, http.StatusOK, map[string]interface{}{
-			"verified":          true,
-			"backup_code_used":  true,
-			"backup_codes_left": len(newCodes),
-		})
+		h.completeLogin(w, r, user)
 		return
 	}
 
@@ -281,7 +458,7 @@ func (h *Handler) MFADisable(w http.ResponseWriter, r *http.Request) {
 	// Get current MFA settings
 	enabled, secret, backupCodes, err := h.db.GetUserMFA(r.Context(), user.ID)
 	if err != nil {
-		h.log.Error("failed to get MFA settings", "error", err)
+		h.logger(r).Error("failed to get MFA settings", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to disable MFA")
 		return
 	}
@@ -302,19 +479,369 @@ func (h *Handler) MFADisable(w http.ResponseWriter, r *http.Request) {
 
 	// Disable MFA
 	if err := h.db.UpdateUserMFA(r.Context(), user.ID, false, nil, nil); err != nil {
-		h.log.Error("failed to disable MFA", "error", err)
+		h.logger(r).Error("failed to disable MFA", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to disable MFA")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
 		"disabled": true,
 		"message":  "MFA disabled successfully",
 	})
 }
 
+// AdminResetMFA handles POST /admin/users/{id}/mfa/reset - disables another
+// user's MFA so a locked-out user can enroll again. The acting admin must have
+// MFA enabled, unless they are the configured break-glass account.
+func (h *Handler) AdminResetMFA(w http.ResponseWriter, r *http.Request) {
+	admin := auth.GetUserFromContext(r.Context())
+	if admin == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	targetID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	if targetID == admin.ID {
+		h.writeError(w, http.StatusBadRequest, "self_reset", "Use /auth/mfa/disable to disable your own MFA")
+		return
+	}
+
+	breakGlass := h.cfg.MFABreakGlassEmail != "" && strings.EqualFold(admin.Email, h.cfg.MFABreakGlassEmail)
+	if !breakGlass {
+		adminMFA, _, _, err := h.db.GetUserMFA(r.Context(), admin.ID)
+		if err != nil {
+			h.logger(r).Error("failed to get MFA settings", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to reset MFA")
+			return
+		}
+		if !adminMFA {
+			h.audit(r, auditMFADisabled, admin.ID.String(), "user:"+targetID.String(), "mfa_reset", "failure",
+				"reason", "actor_mfa_not_enabled")
+			h.writeError(w, http.StatusForbidden, "mfa_required", "Enable MFA on your own account before resetting others")
+			return
+		}
+	}
+
+	target, err := h.db.GetUserByID(r.Context(), targetID)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	enabled, _, _, err := h.db.GetUserMFA(r.Context(), target.ID)
+	if err != nil {
+		h.logger(r).Error("failed to get MFA settings", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to reset MFA")
+		return
+	}
+	if !enabled {
+		h.writeError(w, http.StatusBadRequest, "mfa_not_enabled", "MFA is not enabled for this user")
+		return
+	}
+
+	// Record the reset before touching the account so there is a trail even
+	// if the update fails part-way.
+	h.audit(r, auditMFADisabled, admin.ID.String(), "user:"+target.ID.String(), "mfa_reset", "pending",
+		"break_glass", breakGlass, "target_email", target.Email)
+
+	if err := h.db.UpdateUserMFA(r.Context(), target.ID, false, nil, nil); err != nil {
+		h.logger(r).Error("failed to reset MFA", "error", err, "user_id", target.ID)
+		h.audit(r, auditMFADisabled, admin.ID.String(), "user:"+target.ID.String(), "mfa_reset", "error")
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to reset MFA")
+		return
+	}
+
+	h.audit(r, auditMFADisabled, admin.ID.String(), "user:"+target.ID.String(), "mfa_reset", "success",
+		"break_glass", breakGlass)
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"user_id":  target.ID,
+		"disabled": true,
+		"message":  "MFA reset; the user must enroll again",
+	})
+}
+
+// ---- Password Change ----
+
+// ChangePassword handles POST /auth/password - changes the caller's password
+// and revokes sessions according to PASSWORD_CHANGE_SESSIONS: "revoke_others"
+// keeps the session named by X-Session-ID, "revoke_all" ends every session.
+func (h *Handler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	if !auth.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+		h.audit(r, auditPasswordChanged, user.ID.String(), "user:"+user.ID.String(), "password_change", "failure",
+			"reason", "invalid_current_password")
+		h.writeError(w, http.StatusUnauthorized, "invalid_credentials", "Current password is incorrect")
+		return
+	}
+
+	if err := validatePassword(req.NewPassword); err != nil {
+		h.writeError(w, http.StatusBadRequest, "weak_password", err.Error())
+		return
+	}
+
+	hash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		h.logger(r).Error("failed to hash password", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to change password")
+		return
+	}
+
+	if err := h.db.UpdateUserPassword(r.Context(), user.ID, hash); err != nil {
+		h.logger(r).Error("failed to update password", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to change password")
+		return
+	}
+
+	if h.cfg.PasswordChangeSessions == "revoke_all" {
+		err = h.sessionManager().RevokeAllUserSessions(r.Context(), user.ID.String())
+	} else {
+		err = h.sessionManager().RevokeAllSessions(r.Context(), user.ID.String(), h.currentSessionID(r))
+	}
+	sessionsRevoked := err == nil
+	if err != nil {
+		// The password has already changed; report the partial failure rather than fail the request
+		h.logger(r).Error("failed to revoke sessions after password change", "error", err, "user_id", user.ID)
+	}
+
+	h.audit(r, auditPasswordChanged, user.ID.String(), "user:"+user.ID.String(), "password_change", "success",
+		"session_policy", h.cfg.PasswordChangeSessions, "sessions_revoked", sessionsRevoked)
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"changed":          true,
+		"sessions_revoked": sessionsRevoked,
+		"session_policy":   h.cfg.PasswordChangeSessions,
+	})
+}
+
+// ---- Token Introspection ----
+
+// Introspect handles POST /auth/introspect - reports whether a token is
+// still valid (RFC 7662). A token is active only if its signature and expiry
+// check out and its user still exists and is active. The token is never
+// echoed back.
+func (h *Handler) Introspect(w http.ResponseWriter, r *http.Request) {
+	var req models.IntrospectRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	// Clients may cache the answer themselves, but shared caches must not
+	w.Header().Set("Cache-Control", "no-store")
+
+	claims, err := h.auth.ValidateAccessToken(req.Token)
+	if err != nil {
+		h.writeJSON(w, r, http.StatusOK, models.IntrospectResponse{Active: false})
+		return
+	}
+
+	if h.auth.SessionEnded(r.Context(), claims) {
+		h.writeJSON(w, r, http.StatusOK, models.IntrospectResponse{Active: false})
+		return
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), claims.UserID)
+	if err != nil || !user.Active {
+		h.writeJSON(w, r, http.StatusOK, models.IntrospectResponse{Active: false})
+		return
+	}
+
+	// Tokens issued before scope claims existed report the role's scopes
+	scopes := claims.Scopes
+	if scopes == nil {
+		scopes = auth.ScopesForRole(user.Role)
+	}
+	resp := models.IntrospectResponse{
+		Active: true,
+		Sub:    claims.Subject,
+		Scope:  strings.Join(scopes, " "),
+	}
+	if claims.ExpiresAt != nil {
+		resp.Exp = claims.ExpiresAt.Unix()
+	}
+	h.writeJSON(w, r, http.StatusOK, resp)
+}
+
+// RefreshToken handles POST /auth/refresh - exchanges a refresh token for a
+// new access token and a new refresh token. Each refresh token works once:
+// its ID is recorded in Redis when exchanged, and presenting it again gets
+// 401 token_reused. When cookies are enabled an empty body falls back to the
+// refresh token cookie, and both token cookies are replaced.
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var success bool
+	defer func() { observability.RecordAuthAttempt("refresh", success) }()
+
+	// Without Redis a used token can't be remembered, so refreshing would
+	// allow replay
+	manager := h.sessionManager()
+	if !h.requireFeature(w, manager != nil, "Token refresh requires Redis") {
+		return
+	}
+
+	var req models.RefreshRequest
+	if cookie, err := r.Cookie(h.cfg.RefreshTokenCookie); err == nil && r.ContentLength == 0 && h.cfg.AuthCookies() {
+		req.RefreshToken = cookie.Value
+	} else if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	// Tokens issued before refresh tokens carried an ID can't be tracked
+	claims, err := h.auth.ValidateRefreshToken(req.RefreshToken)
+	if err != nil || claims.ID == "" || claims.ExpiresAt == nil {
+		h.writeError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired refresh token")
+		return
+	}
+	if h.auth.SessionEnded(r.Context(), claims) {
+		h.writeError(w, http.StatusUnauthorized, "session_ended", "Session has been signed out")
+		return
+	}
+
+	user, err := h.db.GetUserByID(r.Context(), claims.UserID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !user.Active) {
+		h.writeError(w, http.StatusUnauthorized, "invalid_token", "Invalid or expired refresh token")
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to load user for token refresh", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to refresh token")
+		return
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time) + h.cfg.JWTLeeway()
+	err = manager.ConsumeRefreshToken(r.Context(), claims.ID, ttl)
+	if errors.Is(err, auth.ErrRefreshTokenReused) {
+		h.logger(r).Warn("refresh token reused", "user_id", user.ID)
+		h.writeError(w, http.StatusUnauthorized, "token_reused", "Refresh token has already been used")
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to consume refresh token", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to refresh token")
+		return
+	}
+
+	accessToken, err := h.auth.CreateAccessToken(user, claims.SessionID)
+	if err != nil {
+		h.logger(r).Error("failed to create access token", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create token")
+		return
+	}
+	refreshToken, err := h.auth.CreateRefreshToken(user, claims.SessionID)
+	if err != nil {
+		h.logger(r).Error("failed to create refresh token", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create token")
+		return
+	}
+
+	if h.cfg.AuthCookies() {
+		http.SetCookie(w, h.auth.AccessTokenCookie(accessToken))
+		http.SetCookie(w, h.auth.RefreshTokenCookie(refreshToken))
+	}
+
+	success = true
+	h.writeJSON(w, r, http.StatusOK, models.TokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "bearer",
+		RefreshToken: refreshToken,
+		ExpiresIn:    h.cfg.JWTExpireMinutes * 60,
+	})
+}
+
 // ---- Session Handlers ----
 
+// startSession records a new session for user and sets the session ID cookie.
+// The cookie is readable by scripts so a browser client can send the ID back
+// as X-Session-ID; it grants nothing on its own. With AUTH_MODE=token the
+// cookie is skipped and the ID is only returned. Without Redis, or if the
+// session can't be stored, it returns "" and the login goes ahead anyway.
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user *models.User) string {
+	manager := h.sessionManager()
+	if manager == nil {
+		return ""
+	}
+
+	ip := ""
+	if addr := h.auth.ClientIP(r); addr.IsValid() {
+		ip = addr.String()
+	}
+	session, err := manager.CreateSession(r.Context(), user.ID.String(), "", ip, r.UserAgent())
+	if err != nil {
+		h.logger(r).Warn("failed to create session", "error", err, "user_id", user.ID)
+		return ""
+	}
+	h.evictExcessSessions(r, manager, user, session.ID)
+
+	if h.cfg.AuthCookies() {
+		http.SetCookie(w, &http.Cookie{
+			Name:     h.cfg.SessionIDCookie,
+			Value:    session.ID,
+			Path:     "/",
+			Secure:   h.cfg.IsProduction(),
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   h.cfg.SessionTTLHours * 60 * 60,
+		})
+	}
+	return session.ID
+}
+
+// evictExcessSessions enforces MAX_SESSIONS_PER_USER after a login by
+// signing the user out of their oldest sessions, and with
+// SESSION_EVICTION_NOTIFY publishes a session_evicted event for each so a
+// notifier can tell them. Failures are logged; the login goes ahead.
+func (h *Handler) evictExcessSessions(r *http.Request, manager *auth.SessionManager, user *models.User, newSessionID string) {
+	evicted, err := manager.EvictExcessSessions(r.Context(), user.ID.String(), h.cfg.MaxSessionsPerUser, newSessionID)
+	if err != nil {
+		h.logger(r).Warn("failed to evict excess sessions", "error", err, "user_id", user.ID)
+		return
+	}
+	for _, s := range evicted {
+		h.logger(r).Info("session evicted by session limit", "user_id", user.ID, "session_id", s.ID)
+		if !h.cfg.SessionEvictionNotify || h.events == nil {
+			continue
+		}
+		payload := events.SessionEvictedPayload{
+			UserID:     user.ID.String(),
+			SessionID:  s.ID,
+			DeviceInfo: s.DeviceInfo,
+			IPAddress:  s.IPAddress,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  models.FormatTime(s.CreatedAt),
+		}
+		if err := h.events.Publish(r.Context(), "", events.EventTypeSessionEvicted, payload); err != nil {
+			h.logger(r).Error("failed to publish session_evicted event", "error", err)
+		}
+	}
+}
+
+// currentSessionID returns the caller's session ID from X-Session-ID, falling
+// back to the session ID cookie set at login.
+func (h *Handler) currentSessionID(r *http.Request) string {
+	if id := r.Header.Get("X-Session-ID"); id != "" {
+		return id
+	}
+	if !h.cfg.AuthCookies() {
+		return ""
+	}
+	if cookie, err := r.Cookie(h.cfg.SessionIDCookie); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
 // ListSessions handles GET /auth/sessions - lists user's active sessions.
 func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
@@ -323,22 +850,19 @@ func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.sessions == nil {
-		h.writeJSON(w, http.StatusOK, map[string]interface{}{
-			"sessions": []interface{}{},
-			"message":  "Session management requires Redis",
-		})
+	manager := h.sessionManager()
+	if !h.requireFeature(w, manager != nil, "Session management requires Redis") {
 		return
 	}
 
-	sessions, err := h.sessions.ListUserSessions(r.Context(), user.ID.String())
+	sessions, err := manager.ListUserSessions(r.Context(), user.ID.String())
 	if err != nil {
-		h.log.Error("failed to list sessions", "error", err)
+		h.logger(r).Error("failed to list sessions", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list sessions")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
 		"sessions": sessions,
 	})
 }
@@ -357,23 +881,24 @@ func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.sessions == nil {
-		h.writeError(w, http.StatusServiceUnavailable, "unavailable", "Session management requires Redis")
+	manager := h.sessionManager()
+	if !h.requireFeature(w, manager != nil, "Session management requires Redis") {
 		return
 	}
 
-	if err := h.sessions.RevokeSession(r.Context(), sessionID, user.ID.String()); err != nil {
-		h.log.Error("failed to revoke session", "error", err)
+	if err := manager.RevokeSession(r.Context(), sessionID, user.ID.String()); err != nil {
+		h.logger(r).Error("failed to revoke session", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to revoke session")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
 		"revoked": true,
 	})
 }
 
-// RevokeAllSessions handles DELETE /auth/sessions - revokes all other sessions.
+// RevokeAllSessions handles DELETE /auth/sessions - revokes all other
+// sessions, or with ?ip= and/or ?device= only the other sessions that match.
 func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
 	user := auth.GetUserFromContext(r.Context())
 	if user == nil {
@@ -381,21 +906,46 @@ func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.sessions == nil {
-		h.writeError(w, http.StatusServiceUnavailable, "unavailable", "Session management requires Redis")
+	// A filter that is given but empty must not fall through to revoke-all
+	query := r.URL.Query()
+	filtered := query.Has("ip") || query.Has("device")
+	filter := auth.SessionFilter{
+		IPAddress:  strings.TrimSpace(query.Get("ip")),
+		DeviceInfo: strings.TrimSpace(query.Get("device")),
+	}
+	if filtered && filter == (auth.SessionFilter{}) {
+		h.writeError(w, http.StatusBadRequest, "missing_filter", "ip or device must not be empty")
 		return
 	}
 
-	// Get current session ID from cookie/header to exclude
-	currentSessionID := r.Header.Get("X-Session-ID")
+	manager := h.sessionManager()
+	if !h.requireFeature(w, manager != nil, "Session management requires Redis") {
+		return
+	}
+
+	// Keep the caller's own session
+	currentSessionID := h.currentSessionID(r)
+
+	if filtered {
+		revoked, err := manager.RevokeMatchingSessions(r.Context(), user.ID.String(), filter, currentSessionID)
+		if err != nil {
+			h.logger(r).Error("failed to revoke matching sessions", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to revoke sessions")
+			return
+		}
+		h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+			"revoked": revoked,
+		})
+		return
+	}
 
-	if err := h.sessions.RevokeAllSessions(r.Context(), user.ID.String(), currentSessionID); err != nil {
-		h.log.Error("failed to revoke sessions", "error", err)
+	if err := manager.RevokeAllSessions(r.Context(), user.ID.String(), currentSessionID); err != nil {
+		h.logger(r).Error("failed to revoke sessions", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to revoke sessions")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
 		"revoked_all": true,
 	})
 }
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// maxBatchResponseBytes caps each captured sub-response, so a batch can't
+// buffer something like an audit export in memory.
+const maxBatchResponseBytes = 1 << 20
+
+// SetBatchRouter sets the router POST /batch sends sub-requests through. It
+// must be the top-level router, so each sub-request passes the same
+// authentication, rate limiting and caching as if it had been sent alone.
+func (h *Handler) SetBatchRouter(router http.Handler) {
+	h.batchRouter = router
+}
+
+// Batch handles POST /batch: it runs several read-only requests with the
+// caller's credentials and returns each one's status and body, in order.
+func (h *Handler) Batch(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFeature(w, h.batchRouter != nil, "Batch requests are not configured") {
+		return
+	}
+
+	var req models.BatchRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if len(req.Requests) > h.cfg.BatchMaxRequests {
+		h.writeError(w, http.StatusBadRequest, "batch_too_large",
+			fmt.Sprintf("A batch may contain at most %d requests", h.cfg.BatchMaxRequests))
+		return
+	}
+
+	subs := make([]*http.Request, len(req.Requests))
+	for i, sub := range req.Requests {
+		method := strings.ToUpper(sub.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		if method != http.MethodGet && method != http.MethodHead {
+			h.writeError(w, http.StatusBadRequest, "method_not_allowed",
+				fmt.Sprintf("requests[%d]: only GET and HEAD can be batched", i))
+			return
+		}
+		target, ok := batchTarget(sub.Path)
+		if !ok {
+			h.writeError(w, http.StatusBadRequest, "invalid_path",
+				fmt.Sprintf("requests[%d]: path must be a gateway path other than /batch", i))
+			return
+		}
+		subs[i] = newBatchSubRequest(r, method, target, i)
+	}
+
+	responses := make([]models.BatchSubResponse, len(subs))
+	var wg sync.WaitGroup
+	for i, sub := range subs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := newBatchRecorder()
+			h.batchRouter.ServeHTTP(rec, sub)
+			responses[i] = rec.result()
+		}()
+	}
+	wg.Wait()
+
+	h.writeJSON(w, r, http.StatusOK, models.BatchResponse{Responses: responses})
+}
+
+// batchTarget parses a sub-request path. It must be a path on this gateway,
+// not an absolute URL, and may not be another batch.
+func batchTarget(path string) (*url.URL, bool) {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") {
+		return nil, false
+	}
+	target, err := url.ParseRequestURI(path)
+	if err != nil || target.Scheme != "" || target.Host != "" {
+		return nil, false
+	}
+	switch strings.TrimSuffix(target.Path, "/") {
+	case "/batch", "/" + APIVersion + "/batch":
+		return nil, false
+	}
+	return target, true
+}
+
+// newBatchSubRequest builds sub-request i of parent. It carries the parent's
+// headers, so the same credentials apply, and its client address, so limits
+// count against the same client.
+func newBatchSubRequest(parent *http.Request, method string, target *url.URL, i int) *http.Request {
+	// Drop the parent's chi routing state so the router matches afresh
+	ctx := context.WithValue(parent.Context(), chi.RouteCtxKey, nil)
+	sub, _ := http.NewRequestWithContext(ctx, method, target.String(), http.NoBody)
+	sub.Header = parent.Header.Clone()
+	for _, name := range []string{"Content-Type", "Content-Length", "If-Match", "If-None-Match"} {
+		sub.Header.Del(name)
+	}
+	sub.Header.Set(chimw.RequestIDHeader, chimw.GetReqID(parent.Context())+"-"+strconv.Itoa(i))
+	sub.RemoteAddr = parent.RemoteAddr
+	sub.Host = parent.Host
+	sub.TLS = parent.TLS
+	return sub
+}
+
+// batchRecorder captures one sub-response, up to maxBatchResponseBytes.
+type batchRecorder struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header)}
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *batchRecorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	if rec.body.Len()+len(p) > maxBatchResponseBytes {
+		rec.overflow = true
+		return 0, http.ErrContentLength
+	}
+	return rec.body.Write(p)
+}
+
+// result converts the captured response. JSON bodies are embedded as they
+// are; anything else becomes a JSON string.
+func (rec *batchRecorder) result() models.BatchSubResponse {
+	if rec.overflow {
+		body, _ := json.Marshal(models.ErrorResponse{
+			Error:   "response_too_large",
+			Message: "Response exceeds the batch size limit; request it on its own",
+		})
+		return models.BatchSubResponse{Status: http.StatusInsufficientStorage, Body: body}
+	}
+
+	res := models.BatchSubResponse{Status: rec.status}
+	if res.Status == 0 {
+		res.Status = http.StatusOK
+	}
+	raw := bytes.TrimSpace(rec.body.Bytes())
+	switch {
+	case len(raw) == 0:
+	case json.Valid(raw):
+		res.Body = json.RawMessage(raw)
+	default:
+		res.Body, _ = json.Marshal(string(raw))
+	}
+	return res
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// newBatchTestRouter serves POST /batch alongside a few stub routes that
+// echo what the sub-request carried.
+func newBatchTestRouter(maxRequests int) *chi.Mux {
+	h := newTestHandler(&config.Config{BatchMaxRequests: maxRequests})
+	h.validate = validator.New()
+
+	r := chi.NewRouter()
+	r.Post("/batch", h.Batch)
+	r.Get("/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			h.writeError(w, http.StatusUnauthorized, "unauthorized", "Missing token")
+			return
+		}
+		h.writeJSON(w, r, http.StatusOK, map[string]string{"id": chi.URLParam(r, "id"), "q": r.URL.Query().Get("q")})
+	})
+	r.Get("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain"))
+	})
+	r.Get("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, maxBatchResponseBytes+1))
+	})
+	h.SetBatchRouter(r)
+	return r
+}
+
+func postBatch(router http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestBatchRunsSubRequestsInOrder(t *testing.T) {
+	router := newBatchTestRouter(10)
+
+	rec := postBatch(router, `{"requests":[
+		{"path":"/projects/p1?q=x"},
+		{"method":"get","path":"/missing"},
+		{"path":"/text"},
+		{"path":"/big"}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+
+	var resp models.BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Responses) != 4 {
+		t.Fatalf("got %d responses, want 4", len(resp.Responses))
+	}
+
+	want := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `{"id":"p1","q":"x"}`},
+		{http.StatusNotFound, `"404 page not found"`},
+		{http.StatusOK, `"plain"`},
+		{http.StatusInsufficientStorage, `{"error":"response_too_large","message":"Response exceeds the batch size limit; request it on its own"}`},
+	}
+	for i, w := range want {
+		got := resp.Responses[i]
+		if got.Status != w.status || string(got.Body) != w.body {
+			t.Errorf("responses[%d] = %d %s, want %d %s", i, got.Status, got.Body, w.status, w.body)
+		}
+	}
+}
+
+func TestBatchUsesCallerCredentials(t *testing.T) {
+	router := newBatchTestRouter(10)
+
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{"requests":[{"path":"/projects/p1"}]}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var resp models.BatchResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got := resp.Responses[0].Status; got != http.StatusUnauthorized {
+		t.Errorf("sub-request status = %d, want 401 without the caller's token", got)
+	}
+}
+
+func TestBatchRejectsInvalidBatches(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code string
+	}{
+		{"empty", `{"requests":[]}`, "validation_error"},
+		{"too many", `{"requests":[{"path":"/text"},{"path":"/text"},{"path":"/text"}]}`, "batch_too_large"},
+		{"mutating method", `{"requests":[{"method":"DELETE","path":"/projects/p1"}]}`, "method_not_allowed"},
+		{"absolute url", `{"requests":[{"path":"//evil.example/x"}]}`, "invalid_path"},
+		{"nested batch", `{"requests":[{"path":"/v1/batch"}]}`, "invalid_path"},
+	}
+
+	router := newBatchTestRouter(2)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postBatch(router, tt.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", rec.Code)
+			}
+			var resp models.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Error != tt.code {
+				t.Errorf("error = %q, want %q", resp.Error, tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// sharedRead runs read once for identical concurrent requests to the named
+// handler and hands every caller the same result, so a burst of clients
+// polling one page costs a single set of queries. Requests are identical
+// when they come from the same user and organization for the same path and
+// query, so callers never see data fetched for someone else.
+//
+// Nothing outlives the call: once read returns, the next request runs it
+// again, and an error reaches only the callers that were already waiting.
+// read gets a context detached from any one caller's cancellation; each
+// caller still stops waiting when its own request ends. The result is
+// shared, so callers must not modify it.
+func sharedRead[T any](h *Handler, r *http.Request, handler string, read func(ctx context.Context) (T, error)) (T, error) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		return read(r.Context())
+	}
+
+	var key strings.Builder
+	key.WriteString(handler)
+	key.WriteString("\x00")
+	key.WriteString(user.ID.String())
+	key.WriteString("\x00")
+	if orgID := auth.GetOrgIDFromContext(r.Context()); orgID != nil {
+		key.WriteString(orgID.String())
+	}
+	key.WriteString("\x00")
+	key.WriteString(r.URL.Path)
+	key.WriteString("?")
+	key.WriteString(r.URL.Query().Encode()) // Sorted, so parameter order doesn't matter
+
+	ran := false
+	ch := h.reads.DoChan(key.String(), func() (interface{}, error) {
+		ran = true
+		return read(context.WithoutCancel(r.Context()))
+	})
+
+	var zero T
+	select {
+	case res := <-ch:
+		if !ran {
+			observability.Metrics.ReadsDeduped.WithLabelValues(handler).Inc()
+		}
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	case <-r.Context().Done():
+		return zero, r.Context().Err()
+	}
+}
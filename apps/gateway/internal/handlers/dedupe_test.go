@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func requestAs(user *models.User, target string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	return r.WithContext(context.WithValue(r.Context(), auth.UserContextKey, user))
+}
+
+func TestSharedReadDeduplicatesConcurrentReads(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	alice, bob := &models.User{ID: uuid.New()}, &models.User{ID: uuid.New()}
+	deduped := observability.Metrics.ReadsDeduped.WithLabelValues("test_read")
+	before := testutil.ToFloat64(deduped)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	read := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "result", nil
+	}
+
+	// Same user and query (in another order) share one read; another user
+	// gets a read of their own
+	requests := []*http.Request{
+		requestAs(alice, "/projects/1/dashboard?a=1&b=2"),
+		requestAs(alice, "/projects/1/dashboard?b=2&a=1"),
+		requestAs(alice, "/projects/1/dashboard?a=1&b=2"),
+		requestAs(bob, "/projects/1/dashboard?a=1&b=2"),
+	}
+	var wg sync.WaitGroup
+	results := make([]string, len(requests))
+	for i, r := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = sharedRead(h, r, "test_read", read)
+		}()
+	}
+
+	// Let every request join before the reads finish
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 2 {
+		t.Errorf("reads = %d, want 2 (one per user)", got)
+	}
+	for i, got := range results {
+		if got != "result" {
+			t.Errorf("results[%d] = %q, want the shared result", i, got)
+		}
+	}
+	if got := testutil.ToFloat64(deduped) - before; got != 2 {
+		t.Errorf("deduplicated reads counted = %v, want 2", got)
+	}
+}
+
+func TestSharedReadDoesNotKeepErrors(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	user := &models.User{ID: uuid.New()}
+	r := requestAs(user, "/tasks")
+
+	failure := errors.New("db down")
+	if _, err := sharedRead(h, r, "test_read", func(ctx context.Context) (int, error) { return 0, failure }); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want %v", err, failure)
+	}
+	got, err := sharedRead(h, r, "test_read", func(ctx context.Context) (int, error) { return 42, nil })
+	if err != nil || got != 42 {
+		t.Errorf("after an error got %d, %v, want a fresh read", got, err)
+	}
+}
+
+func TestSharedReadStopsWaitingWhenCallerLeaves(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	user := &models.User{ID: uuid.New()}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := requestAs(user, "/tasks").WithContext(context.WithValue(ctx, auth.UserContextKey, user))
+
+	release := make(chan struct{})
+	defer close(release)
+	cancel()
+	_, err := sharedRead(h, r, "test_read", func(ctx context.Context) (int, error) {
+		<-release
+		return 0, ctx.Err() // The read itself is detached from the caller
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resourceETag derives a strong ETag from a resource's updated_at. Postgres
+// stores microseconds, so the tag uses that precision to match what a later
+// read returns.
+func resourceETag(updatedAt time.Time) string {
+	return `"` + strconv.FormatInt(updatedAt.UnixMicro(), 36) + `"`
+}
+
+// ifMatch reports whether r's If-Match header allows changing a resource
+// whose current ETag is etag. No header always matches and "*" matches any
+// existing resource. Weak tags never match, as If-Match compares strongly.
+func ifMatch(r *http.Request, etag string) bool {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conditionalUpdatedAt returns the updated_at a conditional write must still
+// find, or the zero time when r has no If-Match and the write is unconditional.
+func conditionalUpdatedAt(r *http.Request, updatedAt time.Time) time.Time {
+	if r.Header.Get("If-Match") == "" {
+		return time.Time{}
+	}
+	return updatedAt
+}
+
+// checkIfMatch writes 412 Precondition Failed and returns false when r's
+// If-Match header doesn't match updatedAt.
+func (h *Handler) checkIfMatch(w http.ResponseWriter, r *http.Request, updatedAt time.Time) bool {
+	if ifMatch(r, resourceETag(updatedAt)) {
+		return true
+	}
+	w.Header().Set("ETag", resourceETag(updatedAt))
+	h.writeError(w, http.StatusPreconditionFailed, "precondition_failed", "Resource has changed; fetch it again and retry")
+	return false
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/config"
+)
+
+func TestResourceETagMatchesStoredPrecision(t *testing.T) {
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	if resourceETag(updated) != resourceETag(updated.Truncate(time.Microsecond)) {
+		t.Error("ETag should ignore sub-microsecond precision Postgres doesn't store")
+	}
+	if resourceETag(updated) == resourceETag(updated.Add(time.Microsecond)) {
+		t.Error("ETag should change when updated_at changes")
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	etag := resourceETag(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name   string
+		header []string
+		want   bool
+	}{
+		{"absent", nil, true},
+		{"match", []string{etag}, true},
+		{"wildcard", []string{"*"}, true},
+		{"one of a list", []string{`"other", ` + etag}, true},
+		{"repeated header", []string{`"other"`, etag}, true},
+		{"stale", []string{`"other"`}, false},
+		{"weak never matches", []string{"W/" + etag}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/", nil)
+			for _, v := range tt.header {
+				r.Header.Add("If-Match", v)
+			}
+			if got := ifMatch(r, etag); got != tt.want {
+				t.Errorf("ifMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckIfMatchWritesPreconditionFailed(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r.Header.Set("If-Match", `"stale"`)
+	rec := httptest.NewRecorder()
+	if h.checkIfMatch(rec, r, updated) {
+		t.Fatal("checkIfMatch() = true for a stale ETag")
+	}
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("status = %d, want 412", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != resourceETag(updated) {
+		t.Errorf("ETag = %q, want the current one", got)
+	}
+}
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/events"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/pagination"
+)
+
+// maxReplayEvents caps how many events a single replay may republish.
+const maxReplayEvents = 1000
+
+// ---- Event Consumers ----
+
+// HandleTaskUpdatedEvent applies task status updates reported by the workers.
+// Anything that can't be applied as sent - an unexpected field, an unknown
+// status or task, or a task from another project - is dead-lettered untouched.
+// The gateway's own task_updated events, from UpdateTask, are ignored.
+func (h *Handler) HandleTaskUpdatedEvent(ctx context.Context, event events.Event) error {
+	if event.Source == events.SourceGateway {
+		return nil
+	}
+	var payload events.TaskUpdatedPayload
+	if err := events.DecodePayloadStrict(event.EventType, event.Payload, &payload); err != nil {
+		return events.Permanent(err)
+	}
+	if !h.transitions.IsValid(payload.Status) {
+		return events.Permanent(fmt.Errorf("%w: unknown task status %q", events.ErrInvalidPayload, payload.Status))
+	}
+
+	taskID, err := uuid.Parse(payload.TaskID)
+	if err != nil {
+		return events.Permanent(err)
+	}
+
+	task, err := h.db.GetTaskByID(ctx, taskID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return events.Permanent(fmt.Errorf("task %s not found", taskID))
+	}
+	if err != nil {
+		return err
+	}
+	if event.ProjectID != "" && event.ProjectID != task.ProjectID.String() {
+		return events.Permanent(fmt.Errorf("task %s does not belong to project %s", taskID, event.ProjectID))
+	}
+	if task.Status == payload.Status {
+		return nil
+	}
+	if !h.transitions.Allows(task.Status, payload.Status) {
+		return events.Permanent(fmt.Errorf("task %s: invalid status transition %s -> %s", taskID, task.Status, payload.Status))
+	}
+
+	oldStatus := task.Status
+	task.Status = payload.Status
+	task.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if err := h.db.UpdateTask(ctx, task, time.Time{}); err != nil {
+		return err
+	}
+	if err := h.publishStatusChange(ctx, task, oldStatus); err != nil {
+		h.log.Error("failed to publish task_status_changed event", "error", err, "task_id", task.ID)
+	}
+	return nil
+}
+
+// WorkerCallback handles POST /worker/events - an HTTP alternative to the
+// events channel for workers to report back. The body is an events.Event
+// signed in X-Worker-Signature with WORKER_CALLBACK_SECRET; unsigned or
+// mis-signed callbacks get 401 before anything is parsed. An event that can
+// never apply answers 422, and a transient failure 503 so the worker retries.
+func (h *Handler) WorkerCallback(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFeature(w, h.cfg.WorkerCallbackSecret != "", "Worker callbacks require WORKER_CALLBACK_SECRET") {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if !auth.VerifyWorkerSignature(h.cfg.WorkerCallbackSecret, body, r.Header.Get(auth.WorkerSignatureHeader)) {
+		h.logger(r).Warn("rejected worker callback with invalid signature")
+		h.writeError(w, http.StatusUnauthorized, "invalid_signature", "Missing or invalid "+auth.WorkerSignatureHeader)
+		return
+	}
+
+	var event events.Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	var handle events.Handler
+	switch event.EventType {
+	case events.EventTypeTaskUpdated:
+		handle = h.HandleTaskUpdatedEvent
+	default:
+		h.writeError(w, http.StatusBadRequest, "unsupported_event", fmt.Sprintf("event type %q is not accepted from workers", event.EventType))
+		return
+	}
+
+	err = handle(r.Context(), event)
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case events.IsPermanent(err):
+		h.writeError(w, http.StatusUnprocessableEntity, "invalid_event", err.Error())
+	default:
+		h.logger(r).Error("worker callback failed", "error", err, "event_id", event.ID)
+		h.writeError(w, http.StatusServiceUnavailable, "callback_failed", "Failed to apply event; retry later")
+	}
+}
+
+// publishStatusChange announces a task's status change to the workers. It is
+// a no-op without Redis.
+func (h *Handler) publishStatusChange(ctx context.Context, task *models.Task, oldStatus string) error {
+	if h.events == nil {
+		return nil
+	}
+	return h.events.Publish(ctx, task.ProjectID.String(), events.EventTypeTaskStatusChanged, events.TaskStatusChangedPayload{
+		TaskID:    task.ID.String(),
+		ProjectID: task.ProjectID.String(),
+		OldStatus: oldStatus,
+		NewStatus: task.Status,
+	})
+}
+
+// ListProjectEvents handles GET /projects/{id}/events/history - the events
+// persisted for a project, newest first. Only the project's owner, its
+// organization's admins and platform admins may read it. Supports
+// ?event_type= filtering and limit/offset/cursor pagination.
+func (h *Handler) ListProjectEvents(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	page, err := pagination.Parse(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
+		return
+	}
+
+	if _, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleAdmin); err != nil {
+		h.writeProjectAccessError(w, r, err)
+		return
+	}
+
+	history, total, err := h.db.ListProjectEvents(r.Context(), projectID, r.URL.Query().Get("event_type"), page.Limit, page.Offset)
+	if err != nil {
+		h.logger(r).Error("failed to list project events", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list events")
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, paginate(history, total, page))
+}
+
+// ---- Dead-Letter Queue Handlers ----
+
+// ListDeadLetters handles GET /admin/events/dlq - inspects failed events.
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFeature(w, h.events != nil, "Events require Redis") {
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			h.writeError(w, http.StatusBadRequest, "invalid_limit", "limit must be between 1 and 500")
+			return
+		}
+		limit = n
+	}
+
+	letters, depth, err := h.events.ListDeadLetters(r.Context(), limit)
+	if err != nil {
+		h.logger(r).Error("failed to list dead letters", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list dead letters")
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"depth":   depth,
+		"entries": letters,
+	})
+}
+
+// ReplayDeadLetters handles POST /admin/events/dlq/replay - republishes failed events.
+func (h *Handler) ReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFeature(w, h.events != nil, "Events require Redis") {
+		return
+	}
+
+	var req struct {
+		Count int `json:"count" validate:"required,min=1,max=1000"`
+	}
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	replayed, err := h.events.ReplayDeadLetters(r.Context(), req.Count)
+	if err != nil {
+		h.logger(r).Error("failed to replay dead letters", "error", err, "replayed", replayed)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to replay dead letters")
+		return
+	}
+
+	h.logger(r).Info("dead letters replayed", "count", replayed)
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"replayed": replayed,
+	})
+}
+
+// ---- Replay Handlers ----
+
+// ReplayEvents handles POST /admin/events/replay - republishes persisted events
+// for a project within a time range. With dry_run, only reports what would be sent.
+// The gateway's own consumer ignores the replayed events.
+func (h *Handler) ReplayEvents(w http.ResponseWriter, r *http.Request) {
+	var req models.ReplayEventsRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	projectID, err := uuid.Parse(req.ProjectID)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_id", "Invalid project ID")
+		return
+	}
+
+	until := time.Now().UTC()
+	if req.Until != nil {
+		until = *req.Until
+	}
+	if !until.After(req.Since) {
+		h.writeError(w, http.StatusBadRequest, "invalid_range", "until must be after since")
+		return
+	}
+
+	maxCount := req.MaxCount
+	if maxCount == 0 || maxCount > maxReplayEvents {
+		maxCount = maxReplayEvents
+	}
+
+	if !h.requireFeature(w, req.DryRun || h.events != nil, "Events require Redis") {
+		return
+	}
+
+	// Fetch one extra row to report whether the cap truncated the range
+	stored, err := h.db.ListEventsSince(r.Context(), projectID, req.Since, until, maxCount+1)
+	if err != nil {
+		h.logger(r).Error("failed to list events", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list events")
+		return
+	}
+	truncated := len(stored) > maxCount
+	if truncated {
+		stored = stored[:maxCount]
+	}
+
+	if req.DryRun {
+		summary := make([]map[string]interface{}, len(stored))
+		for i, e := range stored {
+			summary[i] = map[string]interface{}{
+				"id":           e.ID,
+				"event_type":   e.EventType,
+				"published_at": models.FormatTime(e.PublishedAt),
+			}
+		}
+		h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+			"dry_run":   true,
+			"count":     len(stored),
+			"truncated": truncated,
+			"events":    summary,
+		})
+		return
+	}
+
+	replayed := 0
+	for _, e := range stored {
+		err := h.events.Republish(r.Context(), events.Event{
+			ID:          strconv.FormatInt(e.ID, 10),
+			ProjectID:   e.ProjectID.String(),
+			EventType:   events.EventType(e.EventType),
+			Payload:     e.Payload,
+			PublishedAt: e.PublishedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			h.logger(r).Error("event replay interrupted", "error", err, "replayed", replayed)
+			h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to replay events")
+			return
+		}
+		replayed++
+	}
+
+	h.logger(r).Info("events replayed", "project_id", projectID, "count", replayed, "truncated", truncated)
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"dry_run":   false,
+		"replayed":  replayed,
+		"truncated": truncated,
+	})
+}
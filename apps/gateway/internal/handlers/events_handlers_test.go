@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/events"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestHandleTaskUpdatedEventRejectsMalformedPayloads(t *testing.T) {
+	// No database: malformed events must be rejected before any lookup
+	h := &Handler{transitions: models.DefaultTaskTransitions()}
+	taskID := uuid.NewString()
+
+	tests := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"missing status", map[string]string{"task_id": taskID}},
+		{"bad task id", map[string]string{"task_id": "42", "status": "running"}},
+		{"unknown field", map[string]string{"task_id": taskID, "status": "running", "progress": "50"}},
+		{"unknown status", map[string]string{"task_id": taskID, "status": "exploded"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := h.HandleTaskUpdatedEvent(context.Background(), events.Event{
+				EventType: events.EventTypeTaskUpdated,
+				Payload:   tt.payload,
+			})
+			if !errors.Is(err, events.ErrInvalidPayload) {
+				t.Fatalf("err = %v, want ErrInvalidPayload", err)
+			}
+		})
+	}
+}
+
+func TestHandleTaskUpdatedEventIgnoresGatewayEvents(t *testing.T) {
+	// No database: the gateway's own event must return before any lookup
+	h := &Handler{transitions: models.DefaultTaskTransitions()}
+	err := h.HandleTaskUpdatedEvent(context.Background(), events.Event{
+		EventType: events.EventTypeTaskUpdated,
+		Payload:   map[string]string{"task_id": uuid.NewString(), "status": "running"},
+		Source:    events.SourceGateway,
+	})
+	if err != nil {
+		t.Fatalf("err = %v, want nil", err)
+	}
+}
+
+func TestWorkerCallbackVerifiesSignature(t *testing.T) {
+	// No database: every case is answered before a task lookup
+	const secret = "callback-secret"
+	h := newTestHandler(&config.Config{WorkerCallbackSecret: secret})
+	h.transitions = models.DefaultTaskTransitions()
+
+	malformed := `{"event_type":"task_updated","payload":{"task_id":"42","status":"running"}}`
+	unsupported := `{"event_type":"task_created","payload":{}}`
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		want      int
+	}{
+		{"unsigned", malformed, "", http.StatusUnauthorized},
+		{"wrong secret", malformed, auth.SignWorkerBody("guess", []byte(malformed)), http.StatusUnauthorized},
+		{"signature for another body", malformed, auth.SignWorkerBody(secret, []byte(unsupported)), http.StatusUnauthorized},
+		{"unsupported event", unsupported, auth.SignWorkerBody(secret, []byte(unsupported)), http.StatusBadRequest},
+		{"permanently invalid event", malformed, auth.SignWorkerBody(secret, []byte(malformed)), http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/worker/events", strings.NewReader(tt.body))
+			if tt.signature != "" {
+				req.Header.Set(auth.WorkerSignatureHeader, tt.signature)
+			}
+			rec := httptest.NewRecorder()
+			h.WorkerCallback(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWorkerCallbackUnavailableWithoutSecret(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	body := `{"event_type":"task_updated","payload":{}}`
+	req := httptest.NewRequest(http.MethodPost, "/worker/events", strings.NewReader(body))
+	req.Header.Set(auth.WorkerSignatureHeader, auth.SignWorkerBody("", []byte(body)))
+
+	rec := httptest.NewRecorder()
+	h.WorkerCallback(rec, req)
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("status = %d, want 501", rec.Code)
+	}
+}
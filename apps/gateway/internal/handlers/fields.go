@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// parseFields reads the comma-separated ?fields= param and validates each
+// name against the JSON fields of model. Returns nil when no selection was
+// requested, meaning the full object should be returned.
+func parseFields(r *http.Request, model interface{}) ([]string, error) {
+	raw := r.URL.Query().Get("fields")
+	if raw == "" {
+		return nil, nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(model))
+	seen := make(map[string]bool)
+	var fields, unknown []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		if !known[f] {
+			unknown = append(unknown, f)
+			continue
+		}
+		fields = append(fields, f)
+	}
+
+	if len(unknown) > 0 {
+		allowed := make([]string, 0, len(known))
+		for name := range known {
+			allowed = append(allowed, name)
+		}
+		sort.Strings(allowed)
+		return nil, errors.New("unknown fields: " + strings.Join(unknown, ", ") +
+			" (allowed: " + strings.Join(allowed, ", ") + ")")
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("fields must name at least one field")
+	}
+	return fields, nil
+}
+
+// jsonFieldNames returns the JSON names of the exported fields of struct type t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = sf.Name
+		}
+		names[name] = true
+	}
+	return names
+}
+
+// selectFields reduces each item to the given JSON fields. Fields that an
+// item omits (omitempty) are left out rather than sent as null.
+func selectFields[T any](items []T, fields []string) ([]map[string]json.RawMessage, error) {
+	out := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &all); err != nil {
+			return nil, err
+		}
+		picked := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				picked[f] = v
+			}
+		}
+		out[i] = picked
+	}
+	return out, nil
+}
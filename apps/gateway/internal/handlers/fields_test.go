@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"absent", "", nil, false},
+		{"known fields", "?fields=id,title,status", []string{"id", "title", "status"}, false},
+		{"whitespace and duplicates", "?fields=id,%20title,id", []string{"id", "title"}, false},
+		{"unknown field", "?fields=id,secret", nil, true},
+		{"go field name is not a json name", "?fields=ProjectID", nil, true},
+		{"only separators", "?fields=,,", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/projects/x/tasks"+tt.query, nil)
+			got, err := parseFields(req, models.Task{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("fields = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("fields = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectFields(t *testing.T) {
+	tasks := []models.Task{{ID: uuid.New(), Title: "Build API", Status: "queued"}}
+
+	selected, err := selectFields(tasks, []string{"id", "title", "crew_run_id"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := selected[0]
+	if len(got) != 2 {
+		t.Fatalf("selected %d fields, want 2 (omitted fields stay omitted): %v", len(got), got)
+	}
+	var title string
+	if err := json.Unmarshal(got["title"], &title); err != nil || title != "Build API" {
+		t.Fatalf("title = %q (err %v)", title, err)
+	}
+}
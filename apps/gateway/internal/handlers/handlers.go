@@ -2,11 +2,17 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
+	"sort"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -16,8 +22,13 @@ import (
 	"github.com/kyros-praxis/gateway/internal/config"
 	"github.com/kyros-praxis/gateway/internal/db"
 	"github.com/kyros-praxis/gateway/internal/events"
+	"github.com/kyros-praxis/gateway/internal/middleware"
 	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/kyros-praxis/gateway/internal/pagination"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 )
 
 // Handler holds dependencies for HTTP handlers.
@@ -26,32 +37,58 @@ type Handler struct {
 	db          *db.DB
 	auth        *auth.Auth
 	oauth       *auth.OAuthManager
-	oauthStates *auth.OAuthStateStore
-	sessions    *auth.SessionManager
+	oauthStates auth.OAuthStateManager
+	redirects   *auth.RedirectAllowlist // Where the OAuth callback may send the browser
+	codeFiles   *auth.BackupCodeDownloads
+	sessions    atomic.Pointer[auth.SessionManager] // Set later if Redis comes up after startup
+	rateLimiter *middleware.RateLimiter
 	validate    *validator.Validate
 	log         *slog.Logger
 	workerProxy *httputil.ReverseProxy
+	batchRouter http.Handler       // Where POST /batch sends sub-requests; set via SetBatchRouter
+	reads       singleflight.Group // Identical in-flight reads; see sharedRead
 	events      *events.Service
+	mfaReady    bool
+	readiness   []readinessCheck
+	transitions models.TaskTransitions
+	llmModels   models.ProviderModels
 }
 
 // New creates a new Handler.
 func New(cfg *config.Config, database *db.DB, authService *auth.Auth, eventService *events.Service, log *slog.Logger) *Handler {
-	// Initialize worker proxy
-	target, err := url.Parse(cfg.WorkerBaseURL)
+	// Initialize worker proxy. Main refuses to start with a bad URL; other
+	// callers get a nil proxy, which ProxyWorker answers with 503
+	target, err := cfg.WorkerURL()
 	var proxy *httputil.ReverseProxy
 	if err != nil {
-		log.Error("failed to parse worker base URL", "error", err)
+		log.Error("invalid worker base URL", "error", err)
+	} else if rewrite, err := parsePathRewrite(cfg.WorkerPathPrefix, cfg.WorkerPathRewrites); err != nil {
+		log.Error("invalid worker path rewrite", "error", err)
 	} else {
-		proxy = httputil.NewSingleHostReverseProxy(target)
-		// Modify Director to handle path correctly if needed, generally default is fine for direct mapping
-		originalDirector := proxy.Director
-		proxy.Director = func(req *http.Request) {
-			originalDirector(req)
-			// Don't overwrite Host if you want to respect the target's virtual host,
-			// but for internal docker networking, preserving original Host or setting to target is usually fine.
-			// Let's set it to target host to be safe for some servers.
-			req.Host = target.Host
-		}
+		transport := newWorkerTransport(time.Duration(cfg.WorkerTimeoutSecs)*time.Second, cfg.WorkerMaxIdleConns)
+		proxy = newWorkerProxy(target, rewrite, transport, cfg.WorkerAuthToken, log)
+	}
+
+	// OAuth state tokens: shared store by default, or stateless signed tokens
+	var oauthStates auth.OAuthStateManager = auth.NewOAuthStateStore()
+	if cfg.OAuthStateMode == "signed" {
+		oauthStates = auth.NewSignedOAuthState(cfg.JWTSecretKey, 10*time.Minute)
+	}
+
+	// Main refuses to start with a bad allowlist; failing closed here keeps
+	// other callers from redirecting anywhere
+	redirects, err := auth.NewRedirectAllowlist(cfg.OAuthRedirectPrefixes())
+	if err != nil {
+		log.Error("invalid OAuth redirect allowlist", "error", err)
+		redirects = &auth.RedirectAllowlist{}
+	}
+
+	validate := validator.New()
+	models.RegisterValidators(validate)
+
+	// Keep h.events nil without Redis so every call site has a single check
+	if !eventService.Enabled() {
+		eventService = nil
 	}
 
 	return &Handler{
@@ -59,12 +96,15 @@ func New(cfg *config.Config, database *db.DB, authService *auth.Auth, eventServi
 		db:          database,
 		auth:        authService,
 		oauth:       nil, // Set via SetOAuth
-		oauthStates: auth.NewOAuthStateStore(),
-		sessions:    nil, // Set via SetSessions
-		validate:    validator.New(),
+		oauthStates: oauthStates,
+		redirects:   redirects,
+		codeFiles:   auth.NewBackupCodeDownloads(cfg.JWTSecretKey, backupCodeDownloadTTL),
+		validate:    validate,
 		log:         log,
 		workerProxy: proxy,
 		events:      eventService,
+		transitions: models.DefaultTaskTransitions(),
+		llmModels:   models.DefaultProviderModels(),
 	}
 }
 
@@ -73,38 +113,121 @@ func (h *Handler) SetOAuth(oauth *auth.OAuthManager) {
 	h.oauth = oauth
 }
 
-// SetSessions sets the session manager.
+// SetSessions sets the session manager. It is safe to call while serving
+// requests, so session features can be enabled once Redis becomes reachable.
 func (h *Handler) SetSessions(sessions *auth.SessionManager) {
-	h.sessions = sessions
+	h.sessions.Store(sessions)
+}
+
+// sessionManager returns the current session manager, or nil without Redis.
+func (h *Handler) sessionManager() *auth.SessionManager {
+	return h.sessions.Load()
+}
+
+// SetRateLimiter sets the rate limiter exposed by the admin endpoints.
+func (h *Handler) SetRateLimiter(rl *middleware.RateLimiter) {
+	h.rateLimiter = rl
+}
+
+// SetTaskTransitions replaces the default task status transition rules.
+func (h *Handler) SetTaskTransitions(transitions models.TaskTransitions) {
+	h.transitions = transitions
+}
+
+// SetProviderModels replaces the default LLM provider model lists.
+func (h *Handler) SetProviderModels(providerModels models.ProviderModels) {
+	h.llmModels = providerModels
+}
+
+// SetMFAAvailable records whether the database schema supports MFA.
+func (h *Handler) SetMFAAvailable(ready bool) {
+	h.mfaReady = ready
 }
 
 // SetOAuthStateRedis sets the Redis client for OAuth state persistence.
+// It has no effect in signed state mode.
 func (h *Handler) SetOAuthStateRedis(client *redis.Client) {
-	if client != nil {
-		h.oauthStates.SetRedis(client)
+	if store, ok := h.oauthStates.(*auth.OAuthStateStore); ok && client != nil {
+		store.SetRedis(client)
 	}
 }
 
 // ---- Helper Functions ----
 
+// APIVersion is the latest API version served under its own URL prefix.
+const APIVersion = "v1"
+
 // Maximum request body size (1MB)
 const maxRequestBodySize = 1 << 20
 
-func (h *Handler) writeJSON(w http.ResponseWriter, status int, data interface{}) {
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if h.wantsEnvelope(r) {
+		data = h.envelope(r, data)
+	}
+	h.encodeJSON(w, status, data)
+}
+
+// encodeFailedBody replaces a response that could not be encoded.
+const encodeFailedBody = `{"error":"internal_error","message":"Failed to encode response"}` + "\n"
+
+// encodeJSON writes data as-is, bypassing the envelope. Errors always use this
+// so clients can rely on a single error shape; the request ID is still
+// available to them via the X-Request-Id response header.
+//
+// The body is encoded into a buffer before anything is written, so a value
+// that fails to marshal becomes a clean 500 instead of a half-written
+// response. Unbounded responses such as the audit export stream separately.
+func (h *Handler) encodeJSON(w http.ResponseWriter, status int, data interface{}) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(data); err != nil {
+		h.log.Error("failed to encode response", "error", err, "status", status)
+		status = http.StatusInternalServerError
+		buf.Reset()
+		buf.WriteString(encodeFailedBody)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.log.Error("failed to encode response", "error", err)
-	}
+	_, _ = w.Write(buf.Bytes())
 }
 
 func (h *Handler) writeError(w http.ResponseWriter, status int, err string, message string) {
-	h.writeJSON(w, status, models.ErrorResponse{
+	h.encodeJSON(w, status, models.ErrorResponse{
 		Error:   err,
 		Message: message,
 	})
 }
 
+// logger returns the request-scoped logger (request ID, user ID) with the
+// matched route added, falling back to the handler's logger.
+func (h *Handler) logger(r *http.Request) *slog.Logger {
+	log := observability.LoggerFrom(r.Context(), h.log)
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		log = log.With("route", rctx.RoutePattern())
+	}
+	return log
+}
+
+// requireFeature writes the standard 501 feature_unavailable response when an
+// optional dependency, such as Redis for sessions and events, isn't
+// configured. It reports whether the handler may go on.
+func (h *Handler) requireFeature(w http.ResponseWriter, available bool, message string) bool {
+	if !available {
+		h.writeError(w, http.StatusNotImplemented, "feature_unavailable", message)
+	}
+	return available
+}
+
+// parseUUIDParam reads the URL parameter name as a UUID. On a malformed or nil
+// UUID it writes the standard 400 invalid_id response and returns false.
+func (h *Handler) parseUUIDParam(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, name))
+	if err != nil || id == uuid.Nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_id", name+" must be a valid, non-nil UUID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
 func (h *Handler) decodeAndValidate(r *http.Request, v interface{}) error {
 	// Limit request body size to prevent DOS attacks
 	r.Body = http.MaxBytesReader(nil, r.Body, maxRequestBodySize)
@@ -161,16 +284,28 @@ func validatePassword(password string) error {
 
 // ---- Health ----
 
-// Health handles GET /health.
+// Health handles GET /health. Features reflect the running configuration.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
-	h.writeJSON(w, http.StatusOK, models.HealthResponse{
-		Status: "ok",
-		Env:    h.cfg.Environment,
+	oauthProviders := []string{}
+	if h.oauth != nil {
+		oauthProviders = h.oauth.ListProviders()
+		sort.Strings(oauthProviders)
+	}
+
+	h.writeJSON(w, r, http.StatusOK, models.HealthResponse{
+		Status:     "ok",
+		Env:        h.cfg.Environment,
+		APIVersion: APIVersion,
 		Features: map[string]interface{}{
-			"rate_limiting":   true,
-			"metrics":         true,
-			"caching":         h.cfg.RedisURL != "",
-			"background_jobs": true,
+			"rate_limiting":   h.cfg.RateLimitRPM > 0,
+			"metrics":         h.cfg.MetricsEnabled,
+			"caching":         h.cfg.ResponseCacheTTL > 0 && h.cfg.RedisURL != "",
+			"sessions":        h.sessionManager() != nil,
+			"background_jobs": h.events != nil, // Event consumer runs only with Redis
+			"oauth":           len(oauthProviders) > 0,
+			"oauth_providers": oauthProviders,
+			"mfa":             h.mfaReady,
+			"tls":             h.cfg.TLSEnabled,
 		},
 	})
 }
@@ -204,7 +339,7 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	// Hash password
 	hash, err := auth.HashPassword(req.Password)
 	if err != nil {
-		h.log.Error("failed to hash password", "error", err)
+		h.logger(r).Error("failed to hash password", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create user")
 		return
 	}
@@ -221,22 +356,23 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.db.CreateUser(r.Context(), user); err != nil {
-		h.log.Error("failed to create user", "error", err)
+		h.logger(r).Error("failed to create user", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create user")
 		return
 	}
 
-	h.writeJSON(w, http.StatusCreated, models.UserResponse{
+	h.writeJSON(w, r, http.StatusCreated, models.UserResponse{
 		ID:        user.ID,
 		Username:  user.Username,
 		Email:     user.Email,
 		Role:      user.Role,
 		Active:    user.Active,
-		CreatedAt: user.CreatedAt.Format(time.RFC3339),
+		CreatedAt: models.FormatTime(user.CreatedAt),
 	})
 }
 
-// Login handles POST /auth/login.
+// Login handles POST /auth/login. A user with MFA enabled gets an
+// MFAChallengeResponse instead of tokens.
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req models.LoginRequest
 	if err := h.decodeAndValidate(r, &req); err != nil {
@@ -257,61 +393,178 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		passwordHash = "$2a$10$XXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX"
 	}
 
-	if err != nil || !auth.CheckPassword(req.Password, passwordHash) {
+	// Compare before looking at err, so an unknown email costs a bcrypt
+	// check too
+	passwordOK := auth.CheckPassword(req.Password, passwordHash)
+
+	// The lockout is per email whether or not it has an account, and is
+	// checked after the password so a locked login takes as long as any
+	// other: neither reveals which emails exist
+	if locked := h.loginLockedFor(r, req.Email); locked > 0 {
+		h.writeAccountLocked(w, locked)
+		return
+	}
+
+	if err != nil || !passwordOK {
+		if h.recordLoginFailure(r, req.Email) {
+			h.writeAccountLocked(w, time.Duration(h.cfg.LoginLockoutMinutes)*time.Minute)
+			return
+		}
 		h.writeError(w, http.StatusUnauthorized, "invalid_credentials", "Incorrect email or password")
 		return
 	}
+	if manager := h.sessionManager(); manager != nil && h.cfg.LoginMaxAttempts > 0 {
+		if err := manager.ResetLoginFailures(r.Context(), req.Email); err != nil {
+			h.logger(r).Warn("failed to reset login failures", "error", err)
+		}
+	}
+
+	if !user.Active {
+		h.writeError(w, http.StatusForbidden, "account_inactive", "Account is inactive. Contact an administrator.")
+		return
+	}
+
+	// With MFA the password only earns a challenge; MFAVerify finishes the
+	// login
+	if h.mfaReady {
+		mfaEnabled, _, _, err := h.db.GetUserMFA(r.Context(), user.ID)
+		if err != nil {
+			h.logger(r).Error("failed to get MFA settings", "error", err)
+			h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to log in")
+			return
+		}
+		if mfaEnabled {
+			challenge, err := h.auth.CreateMFAChallengeToken(user)
+			if err != nil {
+				h.logger(r).Error("failed to create MFA challenge token", "error", err)
+				h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create token")
+				return
+			}
+			h.writeJSON(w, r, http.StatusOK, models.MFAChallengeResponse{
+				MFARequired: true,
+				MFAToken:    challenge,
+				ExpiresIn:   int(auth.MFAChallengeTTL.Seconds()),
+			})
+			return
+		}
+	}
+
+	h.completeLogin(w, r, user)
+}
+
+// loginLockedFor returns how much longer logins for email are locked by
+// LOGIN_MAX_ATTEMPTS, or 0. Without Redis there is no lockout, and a Redis
+// error is logged and treated as unlocked.
+func (h *Handler) loginLockedFor(r *http.Request, email string) time.Duration {
+	manager := h.sessionManager()
+	if manager == nil || h.cfg.LoginMaxAttempts <= 0 {
+		return 0
+	}
+	locked, err := manager.LoginLockedFor(r.Context(), email)
+	if err != nil {
+		h.logger(r).Warn("failed to check login lockout", "error", err)
+		return 0
+	}
+	return locked
+}
+
+// recordLoginFailure counts a failed login for email and reports whether it
+// locked the email.
+func (h *Handler) recordLoginFailure(r *http.Request, email string) bool {
+	manager := h.sessionManager()
+	if manager == nil || h.cfg.LoginMaxAttempts <= 0 {
+		return false
+	}
+	lockout := time.Duration(h.cfg.LoginLockoutMinutes) * time.Minute
+	locked, err := manager.RecordLoginFailure(r.Context(), email, h.cfg.LoginMaxAttempts, lockout)
+	if err != nil {
+		h.logger(r).Warn("failed to record login failure", "error", err)
+		return false
+	}
+	if locked {
+		h.logger(r).Warn("login locked after repeated failures", "lockout", lockout)
+	}
+	return locked
+}
+
+// writeAccountLocked answers 423 with the seconds left on the lockout in
+// Retry-After and retry_after.
+func (h *Handler) writeAccountLocked(w http.ResponseWriter, remaining time.Duration) {
+	retryAfter := int(math.Ceil(remaining.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	h.encodeJSON(w, http.StatusLocked, models.ErrorResponse{
+		Error:      "account_locked",
+		Message:    "Too many failed logins. Try again later.",
+		RetryAfter: retryAfter,
+	})
+}
+
+// completeLogin records a successful login and answers with the user's
+// tokens, starting a session and setting the access token cookie.
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	if err := h.db.RecordLogin(r.Context(), user.ID); err != nil {
+		h.logger(r).Warn("failed to record login", "error", err)
+	}
 
-	// Create tokens
-	accessToken, err := h.auth.CreateAccessToken(user)
+	// The tokens carry the session ID, so revoking the session ends them too
+	sessionID := h.startSession(w, r, user)
+
+	accessToken, err := h.auth.CreateAccessToken(user, sessionID)
 	if err != nil {
-		h.log.Error("failed to create access token", "error", err)
+		h.logger(r).Error("failed to create access token", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create token")
 		return
 	}
 
-	refreshToken, err := h.auth.CreateRefreshToken(user)
+	refreshToken, err := h.auth.CreateRefreshToken(user, sessionID)
 	if err != nil {
-		h.log.Error("failed to create refresh token", "error", err)
+		h.logger(r).Error("failed to create refresh token", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create token")
 		return
 	}
 
-	// Set cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    accessToken,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.IsProduction(),
-		SameSite: http.SameSiteLaxMode,
-		MaxAge:   h.cfg.JWTExpireMinutes * 60,
-	})
+	if h.cfg.AuthCookies() {
+		http.SetCookie(w, h.auth.AccessTokenCookie(accessToken))
+	}
 
-	h.writeJSON(w, http.StatusOK, models.TokenResponse{
+	h.writeJSON(w, r, http.StatusOK, models.TokenResponse{
 		AccessToken:  accessToken,
 		TokenType:    "bearer",
 		RefreshToken: refreshToken,
 		ExpiresIn:    h.cfg.JWTExpireMinutes * 60,
+		SessionID:    sessionID,
 	})
 }
 
-// GetMe handles GET /auth/me.
+// GetMe handles GET /auth/me. With JWT_TRUST_CLAIMS it answers from the
+// token alone: username is empty, created_at is omitted and a deactivated user
+// is still seen until the token expires.
 func (h *Handler) GetMe(w http.ResponseWriter, r *http.Request) {
-	user := auth.GetUserFromContext(r.Context())
+	user := auth.GetIdentityFromContext(r.Context())
 	if user == nil {
 		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, models.UserResponse{
-		ID:        user.ID,
-		Username:  user.Username,
-		Email:     user.Email,
-		Role:      user.Role,
-		Active:    user.Active,
-		CreatedAt: user.CreatedAt.Format(time.RFC3339),
-	})
+	resp := models.UserResponse{
+		ID:       user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Role:     user.Role,
+		Active:   user.Active,
+	}
+	if !user.CreatedAt.IsZero() {
+		resp.CreatedAt = models.FormatTime(user.CreatedAt)
+	}
+	if h.db != nil {
+		providers, err := h.db.ListOAuthProviders(r.Context(), user.ID)
+		if err != nil {
+			// The rest of the profile is still useful without them
+			h.logger(r).Warn("failed to list linked oauth providers", "error", err)
+		}
+		resp.LinkedProviders = providers
+	}
+	h.writeJSON(w, r, http.StatusOK, resp)
 }
 
 // ---- Project Handlers ----
@@ -337,70 +590,140 @@ func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
 
 	if user != nil {
 		project.UserID = &user.ID
+		project.OrgID = user.OrgID
 	}
 
 	if err := h.db.CreateProject(r.Context(), project); err != nil {
-		h.log.Error("failed to create project", "error", err)
+		h.logger(r).Error("failed to create project", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create project")
 		return
 	}
 
-	h.writeJSON(w, http.StatusCreated, project)
+	h.writeJSON(w, r, http.StatusCreated, project)
+}
+
+// UpdateProject handles PATCH /projects/{id}. With If-Match, the update
+// only applies while the project still has that ETag; otherwise it gets 412.
+func (h *Handler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProjectRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	project, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleMember)
+	if err != nil {
+		h.writeProjectAccessError(w, r, err)
+		return
+	}
+	if !h.checkIfMatch(w, r, project.UpdatedAt) {
+		return
+	}
+	ifUpdatedAt := conditionalUpdatedAt(r, project.UpdatedAt)
+
+	if req.Name != nil {
+		project.Name = *req.Name
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.Status != nil {
+		project.Status = *req.Status
+	}
+	project.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	err = h.db.UpdateProject(r.Context(), project, ifUpdatedAt)
+	if errors.Is(err, db.ErrStale) {
+		h.writeError(w, http.StatusPreconditionFailed, "precondition_failed", "Resource has changed; fetch it again and retry")
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to update project", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to update project")
+		return
+	}
+
+	w.Header().Set("ETag", resourceETag(project.UpdatedAt))
+	h.writeJSON(w, r, http.StatusOK, project)
 }
 
-// ListProjects handles GET /projects.
+// ListProjects handles GET /projects. Paginated via limit/offset/cursor; the
+// response is a bare array unless the client opts in to the envelope.
 func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	// db.ListProjects treats a nil user as "every owner", so never reach it
+	// without one even if the route loses RequireAuth
 	user := auth.GetUserFromContext(r.Context())
-
-	var userID *uuid.UUID
-	if user != nil {
-		userID = &user.ID
+	if user == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
 	}
 
-	projects, err := h.db.ListProjects(r.Context(), userID)
+	page, err := pagination.Parse(r)
 	if err != nil {
-		h.log.Error("failed to list projects", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list projects")
+		h.writeError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
 		return
 	}
 
-	if projects == nil {
-		projects = []models.Project{}
+	orgID := auth.GetOrgIDFromContext(r.Context())
+	projects, total, err := h.db.ListProjects(r.Context(), orgID, &user.ID, page.Limit, page.Offset)
+	if err != nil {
+		h.logger(r).Error("failed to list projects", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list projects")
+		return
 	}
 
-	h.writeJSON(w, http.StatusOK, projects)
+	writeList(h, w, r, projects, total, page)
 }
 
-// GetProject handles GET /projects/{id}.
+// GetProject handles GET /projects/{id}. Supports ?fields= to return a subset of fields.
 func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
-	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	fields, err := parseFields(r, models.Project{})
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_id", "Invalid project ID")
+		h.writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
 		return
 	}
 
-	project, err := h.db.GetProjectByID(r.Context(), projectID)
+	project, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleMember)
 	if err != nil {
-		h.writeError(w, http.StatusNotFound, "not_found", "Project not found")
+		h.writeProjectAccessError(w, r, err)
 		return
 	}
+	w.Header().Set("ETag", resourceETag(project.UpdatedAt))
 
-	h.writeJSON(w, http.StatusOK, project)
+	if fields == nil {
+		h.writeJSON(w, r, http.StatusOK, project)
+		return
+	}
+	selected, err := selectFields([]*models.Project{project}, fields)
+	if err != nil {
+		h.logger(r).Error("failed to select project fields", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to get project")
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, selected[0])
 }
 
 // ---- Task Handlers ----
 
 // CreateTask handles POST /projects/{id}/tasks.
 func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
-	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
-	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_id", "Invalid project ID")
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
 		return
 	}
 
-	// Verify project exists
-	if _, err := h.db.GetProjectByID(r.Context(), projectID); err != nil {
-		h.writeError(w, http.StatusNotFound, "not_found", "Project not found")
+	if _, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleMember); err != nil {
+		h.writeProjectAccessError(w, r, err)
 		return
 	}
 
@@ -412,24 +735,30 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	priority := req.Priority
 	if priority == "" {
-		priority = "P2"
+		priority = h.cfg.DefaultTaskPriority
 	}
 
 	now := time.Now().UTC()
+	if req.DueAt != nil && !req.DueAt.After(now) {
+		h.writeError(w, http.StatusBadRequest, "invalid_due_at", "due_at must be in the future")
+		return
+	}
+
 	task := &models.Task{
 		ID:           uuid.New(),
 		ProjectID:    projectID,
 		Title:        req.Title,
 		Description:  req.Description,
 		Priority:     priority,
-		Status:       "queued",
+		Status:       models.TaskStatusQueued,
 		Dependencies: req.Dependencies,
+		DueAt:        req.DueAt,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
 
 	if err := h.db.CreateTask(r.Context(), task); err != nil {
-		h.log.Error("failed to create task", "error", err)
+		h.logger(r).Error("failed to create task", "error", err)
 		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create task")
 		return
 	}
@@ -438,105 +767,355 @@ func (h *Handler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	if h.events != nil {
 		if err := h.events.Publish(r.Context(), projectID.String(), events.EventTypeTaskCreated, task); err != nil {
 			// Don't fail the request if publishing fails, but log it
-			h.log.Error("failed to publish task_created event", "error", err)
+			h.logger(r).Error("failed to publish task_created event", "error", err)
+		}
+	}
+
+	h.writeJSON(w, r, http.StatusCreated, task)
+}
+
+// UpdateTask handles PATCH /projects/{id}/tasks/{taskID}. Status changes must
+// follow the task transition rules; invalid ones get 400. Each update
+// publishes task_updated, tagged so the gateway's own consumer ignores it.
+func (h *Handler) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	taskID, ok := h.parseUUIDParam(w, r, "taskID")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTaskRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if req.Status != nil && !h.transitions.IsValid(*req.Status) {
+		h.writeError(w, http.StatusBadRequest, "invalid_status", "Unknown task status "+*req.Status)
+		return
+	}
+
+	if _, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleMember); err != nil {
+		h.writeProjectAccessError(w, r, err)
+		return
+	}
+	task, err := h.db.GetTaskByID(r.Context(), taskID)
+	if err != nil || task.ProjectID != projectID {
+		h.writeError(w, http.StatusNotFound, "not_found", "Task not found")
+		return
+	}
+	if !h.checkIfMatch(w, r, task.UpdatedAt) {
+		return
+	}
+	ifUpdatedAt := conditionalUpdatedAt(r, task.UpdatedAt)
+
+	oldStatus := task.Status
+	if req.Status != nil {
+		if !h.transitions.Allows(oldStatus, *req.Status) {
+			h.writeError(w, http.StatusBadRequest, "invalid_transition",
+				"Task status can't change from "+oldStatus+" to "+*req.Status)
+			return
+		}
+		task.Status = *req.Status
+	}
+	if req.DueAt != nil {
+		if !req.DueAt.After(time.Now()) {
+			h.writeError(w, http.StatusBadRequest, "invalid_due_at", "due_at must be in the future")
+			return
+		}
+		task.DueAt = req.DueAt
+	}
+	if req.Title != nil {
+		task.Title = *req.Title
+	}
+	if req.Description != nil {
+		task.Description = *req.Description
+	}
+	if req.Priority != nil {
+		task.Priority = *req.Priority
+	}
+	task.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	err = h.db.UpdateTask(r.Context(), task, ifUpdatedAt)
+	if errors.Is(err, db.ErrStale) {
+		h.writeError(w, http.StatusPreconditionFailed, "precondition_failed", "Resource has changed; fetch it again and retry")
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to update task", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to update task")
+		return
+	}
+	if task.Status != oldStatus {
+		if err := h.publishStatusChange(r.Context(), task, oldStatus); err != nil {
+			h.logger(r).Error("failed to publish task_status_changed event", "error", err)
+		}
+	}
+	if h.events != nil {
+		if err := h.events.Publish(r.Context(), projectID.String(), events.EventTypeTaskUpdated, events.TaskUpdatedPayload{
+			TaskID: task.ID.String(),
+			Status: task.Status,
+		}); err != nil {
+			h.logger(r).Error("failed to publish task_updated event", "error", err)
 		}
 	}
 
-	h.writeJSON(w, http.StatusCreated, task)
+	task.Overdue = task.IsOverdue(time.Now())
+	w.Header().Set("ETag", resourceETag(task.UpdatedAt))
+	h.writeJSON(w, r, http.StatusOK, task)
+}
+
+// GetTask handles GET /projects/{id}/tasks/{taskID}. The ETag header can be
+// sent back as If-Match on UpdateTask.
+func (h *Handler) GetTask(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	taskID, ok := h.parseUUIDParam(w, r, "taskID")
+	if !ok {
+		return
+	}
+
+	if _, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleMember); err != nil {
+		h.writeProjectAccessError(w, r, err)
+		return
+	}
+	task, err := h.db.GetTaskByID(r.Context(), taskID)
+	if err != nil || task.ProjectID != projectID {
+		h.writeError(w, http.StatusNotFound, "not_found", "Task not found")
+		return
+	}
+
+	task.Overdue = task.IsOverdue(time.Now())
+	w.Header().Set("ETag", resourceETag(task.UpdatedAt))
+	h.writeJSON(w, r, http.StatusOK, task)
+}
+
+// taskPage is one page of a project's tasks, as shared between identical
+// ListTasks requests.
+type taskPage struct {
+	tasks []models.Task
+	total int
 }
 
 // ListTasks handles GET /projects/{id}/tasks.
+// Supports ?sort=created_at (default) or ?sort=priority (P0 first),
+// ?overdue=true, ?include_archived=true, limit/offset/cursor pagination, and
+// ?fields= to select task fields.
 func (h *Handler) ListTasks(w http.ResponseWriter, r *http.Request) {
-	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = db.TaskSortCreated
+	}
+	if !db.IsValidTaskSort(sort) {
+		h.writeError(w, http.StatusBadRequest, "invalid_sort", "sort must be one of: created_at, priority")
+		return
+	}
+
+	page, err := pagination.Parse(r)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_id", "Invalid project ID")
+		h.writeError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
 		return
 	}
 
-	tasks, err := h.db.ListTasksByProject(r.Context(), projectID)
+	fields, err := parseFields(r, models.Task{})
 	if err != nil {
-		h.log.Error("failed to list tasks", "error", err)
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list tasks")
+		h.writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
 		return
 	}
 
-	if tasks == nil {
-		tasks = []models.Task{}
+	var overdueOnly bool
+	if v := r.URL.Query().Get("overdue"); v != "" {
+		overdueOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_overdue", "overdue must be true or false")
+			return
+		}
+	}
+
+	var includeArchived bool
+	if v := r.URL.Query().Get("include_archived"); v != "" {
+		includeArchived, err = strconv.ParseBool(v)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_include_archived", "include_archived must be true or false")
+			return
+		}
+	}
+
+	result, err := sharedRead(h, r, "list_tasks", func(ctx context.Context) (taskPage, error) {
+		if _, err := h.authorizeProjectAccess(ctx, projectID, models.OrgRoleMember); err != nil {
+			return taskPage{}, err
+		}
+		tasks, total, err := h.db.ListTasksByProject(ctx, projectID, sort, overdueOnly, includeArchived, page.Limit, page.Offset)
+		return taskPage{tasks: tasks, total: total}, err
+	})
+	if errors.Is(err, errProjectNotFound) || errors.Is(err, errProjectForbidden) {
+		h.writeProjectAccessError(w, r, err)
+		return
+	}
+	if err != nil {
+		h.logger(r).Error("failed to list tasks", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list tasks")
+		return
 	}
+	tasks, total := result.tasks, result.total
 
-	h.writeJSON(w, http.StatusOK, tasks)
+	if fields == nil {
+		writeList(h, w, r, tasks, total, page)
+		return
+	}
+	selected, err := selectFields(tasks, fields)
+	if err != nil {
+		h.logger(r).Error("failed to select task fields", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list tasks")
+		return
+	}
+	writeList(h, w, r, selected, total, page)
 }
 
-// GetDashboard handles GET /projects/{id}/dashboard.
-func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
-	projectID, err := uuid.Parse(chi.URLParam(r, "id"))
+// ListMyTasks handles GET /tasks - the caller's tasks across all the projects
+// they own, plus their organization's projects. Supports ?status= filtering, ?fields= selection, and
+// limit/offset/cursor pagination.
+func (h *Handler) ListMyTasks(w http.ResponseWriter, r *http.Request) {
+	user := auth.GetUserFromContext(r.Context())
+	if user == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	page, err := pagination.Parse(r)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_id", "Invalid project ID")
+		h.writeError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
 		return
 	}
 
-	project, err := h.db.GetProjectByID(r.Context(), projectID)
+	fields, err := parseFields(r, models.Task{})
 	if err != nil {
-		h.writeError(w, http.StatusNotFound, "not_found", "Project not found")
+		h.writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
 		return
 	}
 
-	tasks, err := h.db.ListTasksByProject(r.Context(), projectID)
+	status := r.URL.Query().Get("status")
+	tasks, total, err := h.db.ListTasksForUser(r.Context(), auth.GetOrgIDFromContext(r.Context()), user.ID, status, page.Limit, page.Offset)
 	if err != nil {
-		tasks = []models.Task{}
+		h.logger(r).Error("failed to list user tasks", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list tasks")
+		return
+	}
+
+	if fields == nil {
+		h.writeJSON(w, r, http.StatusOK, paginate(tasks, total, page))
+		return
+	}
+	selected, err := selectFields(tasks, fields)
+	if err != nil {
+		h.logger(r).Error("failed to select task fields", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list tasks")
+		return
 	}
+	h.writeJSON(w, r, http.StatusOK, paginate(selected, total, page))
+}
 
-	completedCount, _ := h.db.CountCompletedTasks(r.Context(), projectID)
-	activeRuns, _ := h.db.CountActiveRuns(r.Context(), projectID)
+// GetDashboard handles GET /projects/{id}/dashboard. The project, its tasks
+// and the counts are fetched concurrently; only a missing or inaccessible
+// project fails the request, while failed tasks or counts render as empty.
+func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
 
-	h.writeJSON(w, http.StatusOK, models.DashboardResponse{
-		Project:        *project,
-		Tasks:          tasks,
-		TotalTasks:     len(tasks),
-		CompletedTasks: completedCount,
-		ActiveRuns:     activeRuns,
-		Artifacts:      []map[string]interface{}{},
+	dashboard, err := sharedRead(h, r, "get_dashboard", func(ctx context.Context) (models.DashboardResponse, error) {
+		var (
+			project                    *models.Project
+			tasks                      []models.Task
+			completedCount, activeRuns int
+		)
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			var err error
+			project, err = h.authorizeProjectAccess(ctx, projectID, models.OrgRoleMember)
+			return err
+		})
+		g.Go(func() error {
+			tasks, _, _ = h.db.ListTasksByProject(ctx, projectID, db.TaskSortPriority, false, false, 0, 0)
+			return nil
+		})
+		g.Go(func() error {
+			completedCount, activeRuns, _ = h.db.CountDashboardStats(ctx, projectID)
+			return nil
+		})
+		if err := g.Wait(); err != nil {
+			return models.DashboardResponse{}, err
+		}
+		if tasks == nil {
+			tasks = []models.Task{}
+		}
+		return models.DashboardResponse{
+			Project:        *project,
+			Tasks:          tasks,
+			TotalTasks:     len(tasks),
+			CompletedTasks: completedCount,
+			ActiveRuns:     activeRuns,
+			Artifacts:      []map[string]interface{}{},
+		}, nil
 	})
+	if err != nil {
+		h.writeProjectAccessError(w, r, err)
+		return
+	}
+
+	h.writeJSON(w, r, http.StatusOK, dashboard)
 }
 
 // ---- Admin Handlers ----
 
-// GetProviders handles GET /admin/providers.
-func (h *Handler) GetProviders(w http.ResponseWriter, r *http.Request) {
-	// Provider configuration status
-	providers := map[string]models.ProviderStatus{
-		"openrouter": {
-			Configured:    true, // OpenRouter is default
-			MissingConfig: []string{},
-			DefaultModel:  "openrouter/openai/gpt-4o-mini",
-		},
-		"openai": {
-			Configured:    false,
-			MissingConfig: []string{"OPENAI_API_KEY"},
-			DefaultModel:  "gpt-4o-mini",
-		},
-		"vertex": {
-			Configured:    false,
-			MissingConfig: []string{"GOOGLE_PROJECT_ID"},
-			DefaultModel:  "gemini-1.5-pro",
-		},
-		"bedrock": {
-			Configured:    true, // AWS can use IAM
-			MissingConfig: []string{},
-			DefaultModel:  "anthropic.claude-3-sonnet-20240229-v1:0",
-		},
-		"azure": {
-			Configured:    false,
-			MissingConfig: []string{"AZURE_OPENAI_API_KEY", "AZURE_OPENAI_ENDPOINT"},
-			DefaultModel:  "gpt-4o",
-		},
+// AdminListProjects handles GET /admin/projects - every project across users
+// and organizations with its owner's username. Supports ?status= and
+// ?owner_id= filtering and limit/offset/cursor pagination.
+func (h *Handler) AdminListProjects(w http.ResponseWriter, r *http.Request) {
+	page, err := pagination.Parse(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_pagination", err.Error())
+		return
+	}
+
+	filter := db.AdminProjectFilter{Status: r.URL.Query().Get("status")}
+	if v := r.URL.Query().Get("owner_id"); v != "" {
+		ownerID, err := uuid.Parse(v)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid_owner_id", "owner_id must be a valid UUID")
+			return
+		}
+		filter.OwnerID = &ownerID
+	}
+
+	projects, total, err := h.db.ListProjectsAdmin(r.Context(), filter, page.Limit, page.Offset)
+	if err != nil {
+		h.logger(r).Error("failed to list projects", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to list projects")
+		return
 	}
 
-	h.writeJSON(w, http.StatusOK, models.ProvidersResponse{
+	h.writeJSON(w, r, http.StatusOK, paginate(projects, total, page))
+}
+
+// GetProviders handles GET /admin/providers.
+func (h *Handler) GetProviders(w http.ResponseWriter, r *http.Request) {
+	currentValid, currentMissing := h.CurrentModelStatus()
+	h.writeJSON(w, r, http.StatusOK, models.ProvidersResponse{
 		CurrentProvider: h.cfg.ModelProvider,
 		CurrentModel:    h.cfg.ModelName,
-		CurrentValid:    true,
-		CurrentMissing:  []string{},
-		Providers:       providers,
+		CurrentValid:    currentValid,
+		CurrentMissing:  currentMissing,
+		Providers:       h.providerStatuses(),
 	})
 }
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/db"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestParseUUIDParam(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		ok    bool
+	}{
+		{"valid", "6f1c2a3e-8a4b-4d3c-9e2f-1a2b3c4d5e6f", true},
+		{"malformed", "not-a-uuid", false},
+		{"nil uuid", "00000000-0000-0000-0000-000000000000", false},
+		{"empty", "", false},
+	}
+
+	h := newTestHandler(&config.Config{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.value)
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+			rec := httptest.NewRecorder()
+			_, ok := h.parseUUIDParam(rec, r, "id")
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok && rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
+
+func TestUnconfiguredFeaturesAnswerUniformly(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	user := &models.User{ID: uuid.New(), Role: "admin"}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+	}{
+		{"list sessions", h.ListSessions, http.MethodGet},
+		{"revoke session", h.RevokeSession, http.MethodDelete},
+		{"revoke all sessions", h.RevokeAllSessions, http.MethodDelete},
+		{"refresh token", h.RefreshToken, http.MethodPost},
+		{"list dead letters", h.ListDeadLetters, http.MethodGet},
+		{"replay dead letters", h.ReplayDeadLetters, http.MethodPost},
+		{"rate limit usage", h.GetRateLimit, http.MethodGet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "session-1")
+			rctx.URLParams.Add("ip", "203.0.113.7")
+			ctx := context.WithValue(context.Background(), chi.RouteCtxKey, rctx)
+			ctx = context.WithValue(ctx, auth.UserContextKey, user)
+
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(tt.method, "/", nil).WithContext(ctx))
+
+			var body models.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusNotImplemented || body.Error != "feature_unavailable" {
+				t.Errorf("got %d %q, want 501 feature_unavailable", rec.Code, body.Error)
+			}
+		})
+	}
+}
+
+func TestWriteAccountLocked(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	rec := httptest.NewRecorder()
+	h.writeAccountLocked(rec, 90*time.Second+time.Millisecond)
+
+	var body models.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusLocked || body.Error != "account_locked" {
+		t.Errorf("got %d %q, want 423 account_locked", rec.Code, body.Error)
+	}
+	if body.RetryAfter != 91 || rec.Header().Get("Retry-After") != "91" {
+		t.Errorf("retry_after = %d, Retry-After = %q, want 91 (rounded up)", body.RetryAfter, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestListProjectsRejectsAnonymous(t *testing.T) {
+	// No database: an anonymous request must be refused before any query
+	h := newTestHandler(&config.Config{})
+
+	rec := httptest.NewRecorder()
+	h.ListProjects(rec, httptest.NewRequest(http.MethodGet, "/projects", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
+
+// TestListProjectsHidesOtherUsersProjects needs a migrated database:
+//
+//	TEST_DATABASE_URL=postgres://... go test -run=ListProjects ./internal/handlers
+func TestListProjectsHidesOtherUsersProjects(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := db.New(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	owner := &models.User{ID: uuid.New(), Username: "owner-" + uuid.NewString()[:8], Email: "owner-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	other := &models.User{ID: uuid.New(), Username: "other-" + uuid.NewString()[:8], Email: "other-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	for _, u := range []*models.User{owner, other} {
+		if err := database.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	private := &models.Project{ID: uuid.New(), UserID: &owner.ID, Name: "private", Status: "active", CreatedAt: now, UpdatedAt: now}
+	if err := database.CreateProject(ctx, private); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHandler(&config.Config{})
+	h.db = database
+
+	for name, user := range map[string]*models.User{"anonymous": nil, "other user": other} {
+		req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+		if user != nil {
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, user))
+		}
+		rec := httptest.NewRecorder()
+		h.ListProjects(rec, req)
+		if strings.Contains(rec.Body.String(), private.ID.String()) {
+			t.Errorf("%s sees another user's project: %s", name, rec.Body.String())
+		}
+	}
+}
+
+func TestUpdateTaskRejectsBadInputBeforeLookup(t *testing.T) {
+	// No database: every case is answered before the project or task lookup
+	h := newTestHandler(&config.Config{})
+	h.validate = validator.New()
+	models.RegisterValidators(h.validate)
+	h.transitions = models.DefaultTaskTransitions()
+
+	tests := []struct {
+		name   string
+		taskID string
+		body   string
+		code   string
+	}{
+		{"malformed task id", "42", `{"title":"Write spec"}`, "invalid_id"},
+		{"empty title", uuid.NewString(), `{"title":""}`, "validation_error"},
+		{"unknown status", uuid.NewString(), `{"status":"exploded"}`, "invalid_status"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", uuid.NewString())
+			rctx.URLParams.Add("taskID", tt.taskID)
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(tt.body))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+			h.UpdateTask(rec, req)
+
+			var body models.ErrorResponse
+			_ = json.Unmarshal(rec.Body.Bytes(), &body)
+			if rec.Code != http.StatusBadRequest || body.Error != tt.code {
+				t.Errorf("got %d %q, want 400 %q", rec.Code, body.Error, tt.code)
+			}
+		})
+	}
+}
+
+// TestUpdateTaskAppliesOnlySentFields needs a migrated database, like
+// TestListProjectsHidesOtherUsersProjects.
+func TestUpdateTaskAppliesOnlySentFields(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := db.New(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	owner := &models.User{ID: uuid.New(), Username: "owner-" + uuid.NewString()[:8], Email: "owner-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	if err := database.CreateUser(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+	project := &models.Project{ID: uuid.New(), UserID: &owner.ID, Name: "tasks", Status: "active", CreatedAt: now, UpdatedAt: now}
+	if err := database.CreateProject(ctx, project); err != nil {
+		t.Fatal(err)
+	}
+	task := &models.Task{ID: uuid.New(), ProjectID: project.ID, Title: "Draft spec", Description: "keep me", Priority: "P2", Status: models.TaskStatusQueued, CreatedAt: now, UpdatedAt: now}
+	if err := database.CreateTask(ctx, task); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHandler(&config.Config{})
+	h.db = database
+	h.validate = validator.New()
+	models.RegisterValidators(h.validate)
+	h.transitions = models.DefaultTaskTransitions()
+	patch := func(body string) (*httptest.ResponseRecorder, models.Task) {
+		t.Helper()
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", project.ID.String())
+		rctx.URLParams.Add("taskID", task.ID.String())
+		reqCtx := context.WithValue(context.WithValue(ctx, chi.RouteCtxKey, rctx), auth.UserContextKey, owner)
+		rec := httptest.NewRecorder()
+		h.UpdateTask(rec, httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body)).WithContext(reqCtx))
+		var got models.Task
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+		return rec, got
+	}
+
+	rec, got := patch(`{"title":"Write spec"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	if got.Title != "Write spec" || got.Description != "keep me" || got.Priority != "P2" || got.Status != models.TaskStatusQueued {
+		t.Errorf("after a title-only update got %+v, want only the title changed", got)
+	}
+
+	rec, got = patch(`{"status":"running"}`)
+	if rec.Code != http.StatusOK || got.Status != "running" || got.Title != "Write spec" {
+		t.Errorf("status update: %d %+v, want running with the new title kept", rec.Code, got)
+	}
+
+	if rec, _ := patch(`{"status":"queued"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("illegal transition: status = %d, want 400", rec.Code)
+	}
+}
+
+// TestListMyTasksIncludesOrgProjects needs a migrated database, like
+// TestListProjectsHidesOtherUsersProjects.
+func TestListMyTasksIncludesOrgProjects(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := db.New(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	owner := &models.User{ID: uuid.New(), Username: "owner-" + uuid.NewString()[:8], Email: "owner-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	outsider := &models.User{ID: uuid.New(), Username: "outsider-" + uuid.NewString()[:8], Email: "outsider-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	for _, u := range []*models.User{owner, outsider} {
+		if err := database.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	org := &models.Organization{ID: uuid.New(), Name: "Tasks org", Slug: "tasks-" + uuid.NewString()[:8], CreatedAt: now}
+	if err := database.CreateOrganization(ctx, org, owner.ID); err != nil {
+		t.Fatal(err)
+	}
+	member := &models.User{ID: uuid.New(), Username: "member-" + uuid.NewString()[:8], Email: "member-" + uuid.NewString() + "@example.com", Role: "user", Active: true, OrgID: &org.ID, OrgRole: models.OrgRoleMember, CreatedAt: now}
+	if err := database.CreateUser(ctx, member); err != nil {
+		t.Fatal(err)
+	}
+
+	shared := &models.Project{ID: uuid.New(), UserID: &owner.ID, OrgID: &org.ID, Name: "shared", Status: "active", CreatedAt: now, UpdatedAt: now}
+	private := &models.Project{ID: uuid.New(), UserID: &owner.ID, Name: "private", Status: "active", CreatedAt: now, UpdatedAt: now}
+	tasks := make(map[uuid.UUID]*models.Task)
+	for _, p := range []*models.Project{shared, private} {
+		if err := database.CreateProject(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+		task := &models.Task{ID: uuid.New(), ProjectID: p.ID, Title: p.Name + " task", Priority: "P2", Status: models.TaskStatusQueued, CreatedAt: now, UpdatedAt: now}
+		if err := database.CreateTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+		tasks[p.ID] = task
+	}
+
+	h := newTestHandler(&config.Config{})
+	h.db = database
+	list := func(user *models.User) map[uuid.UUID]bool {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+		rec := httptest.NewRecorder()
+		h.ListMyTasks(rec, req.WithContext(context.WithValue(ctx, auth.UserContextKey, user)))
+		var page models.PaginatedResponse[models.Task]
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s, want a page of tasks", rec.Code, rec.Body.String())
+		}
+		seen := make(map[uuid.UUID]bool)
+		for _, task := range page.Items {
+			seen[task.ID] = true
+		}
+		return seen
+	}
+
+	// A member sees the organization's projects but not the owner's
+	// untenanted ones
+	seen := list(member)
+	if !seen[tasks[shared.ID].ID] {
+		t.Error("member doesn't see a task in an organization project")
+	}
+	if seen[tasks[private.ID].ID] {
+		t.Error("member sees a task in the owner's untenanted project")
+	}
+	if seen := list(outsider); len(seen) != 0 {
+		t.Errorf("outsider sees %d tasks, want 0", len(seen))
+	}
+}
+
+// TestListMyTasksScopesFiltersAndPages needs a migrated database, like
+// TestListProjectsHidesOtherUsersProjects.
+func TestListMyTasksScopesFiltersAndPages(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	database, err := db.New(url, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	owner := &models.User{ID: uuid.New(), Username: "owner-" + uuid.NewString()[:8], Email: "owner-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	other := &models.User{ID: uuid.New(), Username: "other-" + uuid.NewString()[:8], Email: "other-" + uuid.NewString() + "@example.com", Role: "user", Active: true, CreatedAt: now}
+	for _, u := range []*models.User{owner, other} {
+		if err := database.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := &models.Project{ID: uuid.New(), UserID: &owner.ID, Name: "first", Status: "active", CreatedAt: now, UpdatedAt: now}
+	second := &models.Project{ID: uuid.New(), UserID: &owner.ID, Name: "second", Status: "active", CreatedAt: now, UpdatedAt: now}
+	foreign := &models.Project{ID: uuid.New(), UserID: &other.ID, Name: "foreign", Status: "active", CreatedAt: now, UpdatedAt: now}
+	for _, p := range []*models.Project{first, second, foreign} {
+		if err := database.CreateProject(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Three queued tasks across both projects, newest last, plus a running
+	// one, another user's task, and a completed task that gets archived
+	longAgo := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	newTask := func(project *models.Project, status string, created time.Time) *models.Task {
+		t.Helper()
+		task := &models.Task{ID: uuid.New(), ProjectID: project.ID, Title: project.Name + " task", Priority: "P2", Status: status, CreatedAt: created, UpdatedAt: created}
+		if err := database.CreateTask(ctx, task); err != nil {
+			t.Fatal(err)
+		}
+		return task
+	}
+	queued := []*models.Task{
+		newTask(first, models.TaskStatusQueued, now.Add(-3*time.Minute)),
+		newTask(second, models.TaskStatusQueued, now.Add(-2*time.Minute)),
+		newTask(first, models.TaskStatusQueued, now.Add(-time.Minute)),
+	}
+	running := newTask(second, models.TaskStatusRunning, now)
+	foreignTask := newTask(foreign, models.TaskStatusQueued, now)
+	archived := newTask(first, models.TaskStatusCompleted, longAgo)
+	if _, err := database.ArchiveCompletedTasks(ctx, longAgo.Add(time.Hour), false, 100); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHandler(&config.Config{})
+	h.db = database
+	list := func(query string) models.PaginatedResponse[models.Task] {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/tasks"+query, nil)
+		rec := httptest.NewRecorder()
+		h.ListMyTasks(rec, req.WithContext(context.WithValue(ctx, auth.UserContextKey, owner)))
+		var page models.PaginatedResponse[models.Task]
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("GET /tasks%s: status = %d, body = %s, want a page of tasks", query, rec.Code, rec.Body.String())
+		}
+		return page
+	}
+
+	// Every project the caller owns, without other users' or archived tasks
+	page := list("")
+	if page.Total != 4 || len(page.Items) != 4 {
+		t.Fatalf("all tasks: total = %d, items = %d, want 4 and 4", page.Total, len(page.Items))
+	}
+	for _, task := range page.Items {
+		if task.ID == foreignTask.ID {
+			t.Error("listed another user's task")
+		}
+		if task.ID == archived.ID {
+			t.Error("listed an archived task")
+		}
+	}
+	if page.Items[0].ID != running.ID {
+		t.Errorf("first task = %s, want the newest (%s)", page.Items[0].ID, running.ID)
+	}
+
+	// The status filter applies to both the page and the total
+	page = list("?status=queued&limit=2")
+	if page.Total != 3 || len(page.Items) != 2 || !page.HasMore {
+		t.Fatalf("first queued page: total = %d, items = %d, has_more = %v, want 3, 2, true", page.Total, len(page.Items), page.HasMore)
+	}
+	if page.Items[0].ID != queued[2].ID || page.Items[1].ID != queued[1].ID {
+		t.Errorf("first queued page = %s, %s, want the two newest queued tasks", page.Items[0].ID, page.Items[1].ID)
+	}
+	page = list("?status=queued&limit=2&offset=2")
+	if page.Total != 3 || len(page.Items) != 1 || page.HasMore || page.Items[0].ID != queued[0].ID {
+		t.Errorf("second queued page: total = %d, items = %d, has_more = %v, want 3, 1, false with the oldest queued task", page.Total, len(page.Items), page.HasMore)
+	}
+	if page := list("?status=completed"); page.Total != 0 || len(page.Items) != 0 {
+		t.Errorf("completed tasks: total = %d, items = %d, want the archived task left out", page.Total, len(page.Items))
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// LLMProviderHeader and LLMModelHeader carry a workflow request's provider
+// and model to the worker. Client-supplied values are always dropped by the
+// proxy; only a selection validated by GenerateWorkflow is forwarded.
+const (
+	LLMProviderHeader = "X-LLM-Provider"
+	LLMModelHeader    = "X-LLM-Model"
+)
+
+// llmSelection is the provider and model a workflow request will use.
+type llmSelection struct {
+	provider string
+	model    string
+}
+
+type llmSelectionKey struct{}
+
+func withLLMSelection(ctx context.Context, sel llmSelection) context.Context {
+	return context.WithValue(ctx, llmSelectionKey{}, sel)
+}
+
+func llmSelectionFromContext(ctx context.Context) (llmSelection, bool) {
+	sel, ok := ctx.Value(llmSelectionKey{}).(llmSelection)
+	return sel, ok
+}
+
+// errUnsupportedModel marks a model outside the provider's model list.
+var errUnsupportedModel = errors.New("unsupported model")
+
+// providerStatuses reports the known LLM providers and their models. A
+// provider is configured if it needs no keys, is the default MODEL_PROVIDER,
+// or is listed in MODEL_PROVIDERS_ENABLED because the worker holds its
+// credentials. Providers added only through PROVIDER_MODELS need the latter.
+func (h *Handler) providerStatuses() map[string]models.ProviderStatus {
+	providers := map[string]models.ProviderStatus{
+		"openrouter": {
+			Configured:    true, // OpenRouter is default
+			MissingConfig: []string{},
+			DefaultModel:  "openrouter/openai/gpt-4o-mini",
+		},
+		"openai": {
+			Configured:    false,
+			MissingConfig: []string{"OPENAI_API_KEY"},
+			DefaultModel:  "gpt-4o-mini",
+		},
+		"vertex": {
+			Configured:    false,
+			MissingConfig: []string{"GOOGLE_PROJECT_ID"},
+			DefaultModel:  "gemini-1.5-pro",
+		},
+		"bedrock": {
+			Configured:    true, // AWS can use IAM
+			MissingConfig: []string{},
+			DefaultModel:  "anthropic.claude-3-sonnet-20240229-v1:0",
+		},
+		"azure": {
+			Configured:    false,
+			MissingConfig: []string{"AZURE_OPENAI_API_KEY", "AZURE_OPENAI_ENDPOINT"},
+			DefaultModel:  "gpt-4o",
+		},
+	}
+
+	for name, list := range h.llmModels {
+		status, ok := providers[name]
+		if !ok && len(list) > 0 {
+			status = models.ProviderStatus{MissingConfig: []string{"MODEL_PROVIDERS_ENABLED"}, DefaultModel: list[0]}
+		}
+		status.Models = list
+		providers[name] = status
+	}
+
+	enabled := []string{h.cfg.ModelProvider}
+	for _, name := range h.cfg.ModelProvidersEnabled {
+		enabled = append(enabled, strings.ToLower(strings.TrimSpace(name)))
+	}
+	for name, status := range providers {
+		if status.Models == nil {
+			status.Models = []string{}
+		}
+		if slices.Contains(enabled, name) {
+			status.Configured = true
+			status.MissingConfig = []string{}
+		}
+		providers[name] = status
+	}
+	return providers
+}
+
+// CurrentModelStatus checks MODEL_PROVIDER and MODEL_NAME against the
+// provider model lists. missing names the settings that need fixing.
+func (h *Handler) CurrentModelStatus() (valid bool, missing []string) {
+	missing = []string{}
+	if _, ok := h.providerStatuses()[h.cfg.ModelProvider]; !ok {
+		return false, append(missing, "MODEL_PROVIDER")
+	}
+	if !h.llmModels.Supports(h.cfg.ModelProvider, h.cfg.ModelName) {
+		return false, append(missing, "MODEL_NAME")
+	}
+	return true, missing
+}
+
+// resolveLLMSelection validates a requested provider and model, falling back
+// to MODEL_PROVIDER and MODEL_NAME, or to the provider's default model. A
+// requested model must be in the provider's model list.
+func (h *Handler) resolveLLMSelection(provider, model string) (llmSelection, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	model = strings.TrimSpace(model)
+	if provider == "" {
+		provider = h.cfg.ModelProvider
+	}
+
+	defaultModel := h.cfg.ModelName
+	if provider != h.cfg.ModelProvider {
+		status, ok := h.providerStatuses()[provider]
+		if !ok {
+			return llmSelection{}, fmt.Errorf("unknown provider %q", provider)
+		}
+		if !status.Configured {
+			return llmSelection{}, fmt.Errorf("provider %q is not configured (missing %s)",
+				provider, strings.Join(status.MissingConfig, ", "))
+		}
+		defaultModel = status.DefaultModel
+	}
+
+	if model == "" {
+		return llmSelection{provider: provider, model: defaultModel}, nil
+	}
+	if !h.llmModels.Supports(provider, model) {
+		return llmSelection{}, fmt.Errorf("%w: %q is not available from %s", errUnsupportedModel, model, provider)
+	}
+	return llmSelection{provider: provider, model: model}, nil
+}
+
+// GenerateWorkflow handles POST /projects/{id}/generate. The optional
+// provider and model are checked against the configured providers and sent
+// to the worker in LLMProviderHeader and LLMModelHeader; the body is
+// forwarded unchanged.
+func (h *Handler) GenerateWorkflow(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	var req models.WorkflowGenerateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	sel, err := h.resolveLLMSelection(req.Provider, req.Model)
+	if errors.Is(err, errUnsupportedModel) {
+		h.writeError(w, http.StatusBadRequest, "invalid_model", err.Error())
+		return
+	}
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_provider", err.Error())
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	start := time.Now()
+	h.ProxyWorkerStream(w, r.WithContext(withLLMSelection(r.Context(), sel)))
+	observability.RecordLLMRequest(sel.provider, sel.model, time.Since(start))
+}
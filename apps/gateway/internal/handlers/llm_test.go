@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestResolveLLMSelection(t *testing.T) {
+	h := newTestHandler(&config.Config{
+		ModelProvider:         "openrouter",
+		ModelName:             "gpt-4o-mini",
+		ModelProvidersEnabled: []string{" OpenAI"},
+	})
+	h.llmModels = models.DefaultProviderModels()
+
+	tests := []struct {
+		name      string
+		provider  string
+		model     string
+		want      llmSelection
+		wantError bool
+	}{
+		{"defaults", "", "", llmSelection{"openrouter", "gpt-4o-mini"}, false},
+		{"default provider with model", "", "claude-3-haiku", llmSelection{"openrouter", "claude-3-haiku"}, false},
+		{"keyless provider", "bedrock", "", llmSelection{"bedrock", "anthropic.claude-3-sonnet-20240229-v1:0"}, false},
+		{"enabled provider ignores case", "OpenAI", "gpt-4o", llmSelection{"openai", "gpt-4o"}, false},
+		{"unconfigured provider", "azure", "", llmSelection{}, true},
+		{"unknown provider", "acme", "", llmSelection{}, true},
+		{"unsupported model", "openai", "gemini-1.5-pro", llmSelection{}, true},
+		{"unsupported model on default provider", "", "gpt-5-ultra", llmSelection{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.resolveLLMSelection(tt.provider, tt.model)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("resolveLLMSelection() error = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("resolveLLMSelection() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateWorkflowForwardsSelection(t *testing.T) {
+	type forwarded struct{ provider, model, body string }
+	got := make(chan forwarded, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- forwarded{r.Header.Get(LLMProviderHeader), r.Header.Get(LLMModelHeader), string(body)}
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	h := newTestHandler(&config.Config{ModelProvider: "openrouter", ModelName: "gpt-4o-mini"})
+	h.validate = validator.New()
+	h.llmModels = models.DefaultProviderModels()
+	h.workerProxy = newWorkerProxy(target, nil, nil, "", h.log)
+
+	body := `{"prompt":"Build a todo list app","provider":"bedrock"}`
+	req := httptest.NewRequest(http.MethodPost, "/projects/x/generate", strings.NewReader(body))
+	req.Header.Set(LLMModelHeader, "spoofed")
+	rec := httptest.NewRecorder()
+	h.GenerateWorkflow(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	f := <-got
+	if f.provider != "bedrock" || f.model != "anthropic.claude-3-sonnet-20240229-v1:0" {
+		t.Errorf("forwarded provider/model = %q/%q", f.provider, f.model)
+	}
+	if f.body != body {
+		t.Errorf("forwarded body = %q, want %q", f.body, body)
+	}
+}
+
+func TestGenerateWorkflowRejectsUnconfiguredProvider(t *testing.T) {
+	h := newTestHandler(&config.Config{ModelProvider: "openrouter"})
+	h.validate = validator.New()
+	h.llmModels = models.DefaultProviderModels()
+	h.workerProxy = newWorkerProxy(&url.URL{Scheme: "http", Host: "worker.invalid"}, nil, nil, "", h.log)
+
+	req := httptest.NewRequest(http.MethodPost, "/projects/x/generate",
+		strings.NewReader(`{"prompt":"Build a todo list app","provider":"azure"}`))
+	rec := httptest.NewRecorder()
+	h.GenerateWorkflow(rec, req)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid_provider") {
+		t.Errorf("got %d %s, want 400 invalid_provider", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCurrentModelStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		provider    string
+		model       string
+		extra       []string
+		wantValid   bool
+		wantMissing []string
+	}{
+		{"default config", "openrouter", "gpt-4o-mini", nil, true, []string{}},
+		{"model from another provider", "vertex", "gpt-4o", nil, false, []string{"MODEL_NAME"}},
+		{"unknown provider", "acme", "gpt-4o", nil, false, []string{"MODEL_PROVIDER"}},
+		{"extended model list", "openai", "gpt-4.1", []string{"openai=gpt-4.1"}, true, []string{}},
+		{"extended provider", "groq", "llama-3.1-8b-instant", []string{"groq=llama-3.1-8b-instant"}, true, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(&config.Config{ModelProvider: tt.provider, ModelName: tt.model})
+			h.llmModels = models.DefaultProviderModels()
+			if err := h.llmModels.Extend(tt.extra); err != nil {
+				t.Fatalf("Extend: %v", err)
+			}
+
+			valid, missing := h.CurrentModelStatus()
+			if valid != tt.wantValid || !slices.Equal(missing, tt.wantMissing) {
+				t.Errorf("CurrentModelStatus() = %v, %v; want %v, %v", valid, missing, tt.wantValid, tt.wantMissing)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/db"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// ---- Organization Handlers ----
+
+// CreateOrganization handles POST /admin/orgs - creates a tenant and makes an
+// existing user its first org admin.
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	admin := auth.GetUserFromContext(r.Context())
+	if admin == nil {
+		h.writeError(w, http.StatusUnauthorized, "unauthorized", "Not authenticated")
+		return
+	}
+
+	var req models.CreateOrganizationRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+
+	owner, err := h.db.GetUserByEmail(r.Context(), req.AdminEmail)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+
+	org := &models.Organization{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Slug:      strings.ToLower(req.Slug),
+		CreatedAt: time.Now().UTC(),
+	}
+	switch err := h.db.CreateOrganization(r.Context(), org, owner.ID); {
+	case errors.Is(err, db.ErrOrgSlugTaken):
+		h.writeError(w, http.StatusConflict, "slug_taken", "Organization slug already in use")
+		return
+	case errors.Is(err, db.ErrUserInOtherOrg):
+		h.writeError(w, http.StatusConflict, "user_in_org", "User already belongs to an organization")
+		return
+	case err != nil:
+		h.logger(r).Error("failed to create organization", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to create organization")
+		return
+	}
+
+	h.audit(r, auditOrgCreated, admin.ID.String(), "org:"+org.ID.String(), "create", "success",
+		"slug", org.Slug, "org_admin", owner.ID.String())
+	h.writeJSON(w, r, http.StatusCreated, org)
+}
+
+// GetMyOrganization handles GET /org - the caller's organization.
+func (h *Handler) GetMyOrganization(w http.ResponseWriter, r *http.Request) {
+	orgID := auth.GetOrgIDFromContext(r.Context())
+	if orgID == nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Not a member of any organization")
+		return
+	}
+
+	org, err := h.db.GetOrganizationByID(r.Context(), *orgID)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "Organization not found")
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, org)
+}
+
+// AddOrgMember handles POST /org/members - adds an existing user without an
+// organization to the caller's, or changes a member's org role. Org admins only.
+func (h *Handler) AddOrgMember(w http.ResponseWriter, r *http.Request) {
+	admin := auth.GetUserFromContext(r.Context())
+	orgID := auth.GetOrgIDFromContext(r.Context())
+	if admin == nil || orgID == nil {
+		h.writeError(w, http.StatusForbidden, "forbidden", "Organization admin access required")
+		return
+	}
+
+	var req models.AddOrgMemberRequest
+	if err := h.decodeAndValidate(r, &req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+		return
+	}
+	role := req.Role
+	if role == "" {
+		role = models.OrgRoleMember
+	}
+
+	member, err := h.db.GetUserByEmail(r.Context(), req.Email)
+	if err != nil {
+		h.writeError(w, http.StatusNotFound, "not_found", "User not found")
+		return
+	}
+	if member.ID == admin.ID && role != models.OrgRoleAdmin {
+		h.writeError(w, http.StatusBadRequest, "self_demotion", "Org admins can't demote themselves")
+		return
+	}
+
+	if err := h.db.SetOrgMembership(r.Context(), member.ID, *orgID, role); err != nil {
+		if errors.Is(err, db.ErrUserInOtherOrg) {
+			// Same answer as an unknown email, so other tenants' users can't be enumerated
+			h.writeError(w, http.StatusNotFound, "not_found", "User not found")
+			return
+		}
+		h.logger(r).Error("failed to set org membership", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to add member")
+		return
+	}
+
+	h.audit(r, auditOrgMemberSet, admin.ID.String(), "user:"+member.ID.String(), "set_org_role", "success",
+		"org_id", orgID.String(), "org_role", role)
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"user_id":  member.ID,
+		"org_id":   orgID,
+		"org_role": role,
+	})
+}
+
+// ProjectInOrg is a middleware for /projects/{id} routes served outside the
+// gateway's own queries, such as the worker proxy: it answers 404 or 403
+// unless the caller may access the project (see authorizeProjectAccess).
+func (h *Handler) ProjectInOrg(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		projectID, ok := h.parseUUIDParam(w, r, "id")
+		if !ok {
+			return
+		}
+		if _, err := h.authorizeProjectAccess(r.Context(), projectID, models.OrgRoleMember); err != nil {
+			h.writeProjectAccessError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+var (
+	// errProjectNotFound is returned when the project is missing, outside the
+	// caller's organization, or untenanted and not the caller's; all three
+	// answer 404 so project IDs can't be probed.
+	errProjectNotFound = errors.New("project not found")
+	// errProjectForbidden is returned when the caller can see the project but
+	// lacks the role the route requires.
+	errProjectForbidden = errors.New("insufficient role for project")
+)
+
+// authorizeProjectAccess loads a project in the caller's organization and
+// checks the caller may use it with requiredRole, one of the OrgRole
+// constants. The project's owner and platform admins always may; other
+// members of the project's organization need requiredRole. Failures are
+// errProjectNotFound, errProjectForbidden, or a database error; pass them to
+// writeProjectAccessError.
+func (h *Handler) authorizeProjectAccess(ctx context.Context, projectID uuid.UUID, requiredRole string) (*models.Project, error) {
+	user := auth.GetUserFromContext(ctx)
+	if user == nil {
+		return nil, errProjectNotFound
+	}
+	project, err := h.db.GetProjectInOrg(ctx, projectID, auth.GetOrgIDFromContext(ctx))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errProjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkProjectRole(user, project, requiredRole); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// checkProjectRole is the access decision of authorizeProjectAccess for a
+// project already known to be in the caller's organization.
+func checkProjectRole(user *models.User, project *models.Project, requiredRole string) error {
+	if user.Role == "admin" || (project.UserID != nil && *project.UserID == user.ID) {
+		return nil
+	}
+	// Untenanted projects are private to their owner
+	if project.OrgID == nil || user.OrgID == nil || *project.OrgID != *user.OrgID {
+		return errProjectNotFound
+	}
+	if requiredRole == models.OrgRoleAdmin && user.OrgRole != models.OrgRoleAdmin {
+		return errProjectForbidden
+	}
+	return nil
+}
+
+// writeProjectAccessError answers an authorizeProjectAccess failure with
+// 404, 403, or 500 for a database error.
+func (h *Handler) writeProjectAccessError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errProjectNotFound):
+		h.writeError(w, http.StatusNotFound, "not_found", "Project not found")
+	case errors.Is(err, errProjectForbidden):
+		h.writeError(w, http.StatusForbidden, "forbidden", "Insufficient project role")
+	default:
+		h.logger(r).Error("failed to load project", "error", err)
+		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to load project")
+	}
+}
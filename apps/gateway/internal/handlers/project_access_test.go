@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestCheckProjectRole(t *testing.T) {
+	ownerID, orgID, otherOrgID := uuid.New(), uuid.New(), uuid.New()
+	orgProject := &models.Project{UserID: &ownerID, OrgID: &orgID}
+	untenanted := &models.Project{UserID: &ownerID}
+
+	owner := &models.User{ID: ownerID, OrgID: &orgID, OrgRole: models.OrgRoleMember}
+	member := &models.User{ID: uuid.New(), OrgID: &orgID, OrgRole: models.OrgRoleMember}
+	orgAdmin := &models.User{ID: uuid.New(), OrgID: &orgID, OrgRole: models.OrgRoleAdmin}
+	outsider := &models.User{ID: uuid.New(), OrgID: &otherOrgID, OrgRole: models.OrgRoleAdmin}
+	stranger := &models.User{ID: uuid.New()}
+	platformAdmin := &models.User{ID: uuid.New(), Role: "admin"}
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		project *models.Project
+		role    string
+		want    error
+	}{
+		{"owner", owner, orgProject, models.OrgRoleAdmin, nil},
+		{"member reads", member, orgProject, models.OrgRoleMember, nil},
+		{"member needs admin", member, orgProject, models.OrgRoleAdmin, errProjectForbidden},
+		{"org admin", orgAdmin, orgProject, models.OrgRoleAdmin, nil},
+		{"other organization", outsider, orgProject, models.OrgRoleMember, errProjectNotFound},
+		{"untenanted owner", &models.User{ID: ownerID}, untenanted, models.OrgRoleAdmin, nil},
+		{"untenanted stranger", stranger, untenanted, models.OrgRoleMember, errProjectNotFound},
+		{"platform admin", platformAdmin, untenanted, models.OrgRoleAdmin, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkProjectRole(tt.user, tt.project, tt.role); !errors.Is(err, tt.want) {
+				t.Errorf("checkProjectRole() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,24 +1,297 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
 )
 
-// ProxyWorker proxies requests to the Python worker service.
-// It relies on the workerProxy initialized in New().
+// statusClientClosedRequest is the nginx convention for a client that went away
+// before the response was written. It is only used for logging.
+const statusClientClosedRequest = 499
+
+// pathRewrite maps gateway paths onto worker paths: a prefix is stripped,
+// then the first rule whose From prefix matches is swapped for its To.
+type pathRewrite struct {
+	stripPrefix string
+	rules       []pathRule
+}
+
+type pathRule struct {
+	from, to string
+}
+
+// parsePathRewrite builds a pathRewrite from WORKER_PATH_PREFIX and
+// "from=to" WORKER_PATH_REWRITES entries.
+func parsePathRewrite(stripPrefix string, entries []string) (*pathRewrite, error) {
+	rw := &pathRewrite{stripPrefix: strings.TrimSuffix(stripPrefix, "/")}
+	if rw.stripPrefix != "" && !strings.HasPrefix(rw.stripPrefix, "/") {
+		return nil, fmt.Errorf("worker path prefix %q must start with /", stripPrefix)
+	}
+	for _, entry := range entries {
+		from, to, ok := strings.Cut(entry, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || !strings.HasPrefix(from, "/") || !strings.HasPrefix(to, "/") {
+			return nil, fmt.Errorf("invalid worker path rewrite %q: want /from=/to", entry)
+		}
+		rw.rules = append(rw.rules, pathRule{from: strings.TrimSuffix(from, "/"), to: to})
+	}
+	return rw, nil
+}
+
+// apply returns the worker path for path. A nil pathRewrite leaves it as is.
+func (rw *pathRewrite) apply(path string) string {
+	if rw == nil {
+		return path
+	}
+	if rw.stripPrefix != "" {
+		path, _ = stripPathPrefix(path, rw.stripPrefix)
+	}
+	for _, rule := range rw.rules {
+		if rest, ok := stripPathPrefix(path, rule.from); ok {
+			if rest == "/" {
+				return rule.to
+			}
+			return strings.TrimSuffix(rule.to, "/") + rest
+		}
+	}
+	return path
+}
+
+// WorkerTokenHeader carries WORKER_AUTH_TOKEN to the worker, which should
+// reject requests without it. The client's Authorization header is left
+// alone because the worker reads the user's bearer token from it.
+const WorkerTokenHeader = "X-Worker-Token"
+
+// newWorkerProxy builds the reverse proxy to the Python worker service.
+// The outgoing request carries the client's context, so a client disconnect
+// cancels the upstream call and frees the worker (e.g. an abandoned LLM run).
+// Any client-supplied WorkerTokenHeader is dropped, and token, when set,
+// takes its place. The LLM selection headers are handled the same way.
+func newWorkerProxy(target *url.URL, rewrite *pathRewrite, transport http.RoundTripper, token string, log *slog.Logger) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = instrumentedTransport{next: transport}
+	// Modify Director to handle path correctly if needed, generally default is fine for direct mapping
+	originalDirector := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		// The worker's routes are unversioned; /v1/projects/... maps to /projects/...
+		path, _ := stripPathPrefix(req.URL.Path, "/"+APIVersion)
+		if path = rewrite.apply(path); path != req.URL.Path {
+			req.URL.Path, req.URL.RawPath = path, ""
+		}
+		originalDirector(req)
+		req.Header.Del(WorkerTokenHeader)
+		if token != "" {
+			req.Header.Set(WorkerTokenHeader, token)
+		}
+		req.Header.Del(LLMProviderHeader)
+		req.Header.Del(LLMModelHeader)
+		if sel, ok := llmSelectionFromContext(req.Context()); ok {
+			req.Header.Set(LLMProviderHeader, sel.provider)
+			req.Header.Set(LLMModelHeader, sel.model)
+		}
+		// Don't overwrite Host if you want to respect the target's virtual host,
+		// but for internal docker networking, preserving original Host or setting to target is usually fine.
+		// Let's set it to target host to be safe for some servers.
+		req.Host = target.Host
+	}
+
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		log := observability.LoggerFrom(r.Context(), log)
+		if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+			observability.Metrics.ProxyErrors.WithLabelValues("deadline").Inc()
+			log.Error("worker request timed out", "method", r.Method, "path", r.URL.Path)
+			writeProxyError(w, http.StatusGatewayTimeout, "worker_timeout", "Worker did not respond in time")
+			return
+		}
+		if errors.Is(err, context.Canceled) || r.Context().Err() != nil {
+			observability.Metrics.ProxyCancelled.Inc()
+			log.Info("proxied request cancelled by client",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", statusClientClosedRequest,
+			)
+			return // Client is gone; nothing to write
+		}
+
+		// The body limit middleware caps chunked uploads mid-stream, which
+		// surfaces here as the worker request failing to read the body
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Warn("request body too large for worker", "method", r.Method, "path", r.URL.Path, "limit", tooLarge.Limit)
+			writeProxyError(w, http.StatusRequestEntityTooLarge, "request_too_large", fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+
+		reason := proxyErrorReason(err)
+		observability.Metrics.ProxyErrors.WithLabelValues(reason).Inc()
+		log.Error("worker unavailable",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"worker", target.Host,
+			"reason", reason,
+			"error", err,
+		)
+		writeProxyError(w, http.StatusServiceUnavailable, "worker_unavailable", "Worker service is unavailable")
+	}
+
+	return proxy
+}
+
+// proxyErrorReason classifies a failed worker call for logs and metrics, so a
+// worker that is down (connection_refused) can be told from one that is slow
+// (timeout) or unresolvable (dns).
+func proxyErrorReason(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "connection_refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "connection_reset"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "upstream"
+	}
+}
+
+// writeProxyError writes the standard JSON error body from the proxy's error
+// handler, which runs without a Handler.
+func writeProxyError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(models.ErrorResponse{Error: code, Message: message})
+}
+
+// instrumentedTransport records the status and latency of each worker call.
+// Latency runs to the response headers, so a stream's duration isn't counted.
+// Failed calls are counted by the proxy's error handler, which knows why.
+type instrumentedTransport struct {
+	next http.RoundTripper // nil uses http.DefaultTransport
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	observability.Metrics.ProxyDuration.Observe(time.Since(start).Seconds())
+	if err == nil {
+		observability.Metrics.ProxyRequests.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
+
+// Fixed worker transport timeouts; the response header timeout and pool size
+// come from config.
+const (
+	workerDialTimeout         = 5 * time.Second
+	workerTLSHandshakeTimeout = 10 * time.Second
+	workerIdleConnTimeout     = 90 * time.Second
+)
+
+// newWorkerTransport builds the proxy's transport so a hung worker can't hold
+// connections forever: dialing and TLS are bounded, and the worker must start
+// its response within headerTimeout (0 waits indefinitely). All idle
+// connections go to the one worker host, so the per-host pool matches maxIdle.
+func newWorkerTransport(headerTimeout time.Duration, maxIdle int) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   workerDialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   workerTLSHandshakeTimeout,
+		ResponseHeaderTimeout: headerTimeout,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          maxIdle,
+		MaxIdleConnsPerHost:   maxIdle,
+		IdleConnTimeout:       workerIdleConnTimeout,
+	}
+}
+
+// stripPathPrefix removes prefix from path when it ends at a segment
+// boundary, so "/v1" strips from "/v1/x" but not from "/v1x".
+func stripPathPrefix(path, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+		return path, false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
+
+// ProxyWorker proxies requests to the Python worker service, bounded by
+// WORKER_TIMEOUT_SECONDS. It relies on the workerProxy initialized in New().
 func (h *Handler) ProxyWorker(w http.ResponseWriter, r *http.Request) {
+	h.proxyWorker(w, r, time.Duration(h.cfg.WorkerTimeoutSecs)*time.Second)
+}
+
+// ProxyWorkerStream proxies a long-running worker call, such as an LLM
+// stream, bounded by STREAM_WRITE_TIMEOUT_SECONDS instead.
+func (h *Handler) ProxyWorkerStream(w http.ResponseWriter, r *http.Request) {
+	h.proxyWorker(w, r, time.Duration(h.cfg.StreamWriteTimeoutSecs)*time.Second)
+}
+
+// proxyWorker forwards r to the worker, cancelling the upstream call after
+// timeout; 0 leaves it unbounded.
+func (h *Handler) proxyWorker(w http.ResponseWriter, r *http.Request, timeout time.Duration) {
 	if h.workerProxy == nil {
 		h.writeError(w, http.StatusServiceUnavailable, "service_unavailable", "Worker service not configured")
 		return
 	}
 
 	// Logging could be enhanced here to track proxied requests
-	h.log.Info("proxying request to worker",
+	h.logger(r).Info("proxying request to worker",
 		"method", r.Method,
 		"path", r.URL.Path,
 		"remote_addr", r.RemoteAddr,
 	)
 
+	// A client disconnect mid-stream surfaces as an ErrAbortHandler panic from
+	// the proxy; record it before letting the server abort the connection.
+	defer func() {
+		if rec := recover(); rec != nil {
+			if rec == http.ErrAbortHandler && r.Context().Err() != nil {
+				observability.Metrics.ProxyCancelled.Inc()
+				h.logger(r).Info("proxied stream cancelled by client",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", statusClientClosedRequest,
+				)
+			}
+			panic(rec)
+		}
+	}()
+
 	// Proxy the request
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		h.workerProxy.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
 	h.workerProxy.ServeHTTP(w, r)
 }
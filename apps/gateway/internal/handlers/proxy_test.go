@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestProxyWorkerCancelsUpstreamOnClientDisconnect(t *testing.T) {
+	upstreamDone := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(upstreamDone)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("chunk\n"))
+		w.(http.Flusher).Flush()
+
+		// Simulate a long LLM stream that only stops when cancelled
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+			t.Error("upstream request was not cancelled")
+		}
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	h := newTestHandler(&config.Config{})
+	h.workerProxy = newWorkerProxy(target, nil, nil, "", h.log)
+
+	gateway := httptest.NewServer(http.HandlerFunc(h.ProxyWorker))
+	defer gateway.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, gateway.URL+"/projects/x/status", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if _, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil {
+		t.Fatalf("failed to read first chunk: %v", err)
+	}
+
+	// Client walks away mid-stream
+	cancel()
+
+	select {
+	case <-upstreamDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream call kept running after client disconnect")
+	}
+}
+
+func TestProxyWorkerStripsAPIVersion(t *testing.T) {
+	gotPath := make(chan string, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath <- r.URL.RequestURI()
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	h := newTestHandler(&config.Config{})
+	h.workerProxy = newWorkerProxy(target, nil, nil, "", h.log)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1/projects/x/status?verbose=1", "/projects/x/status?verbose=1"},
+		{"/projects/x/status", "/projects/x/status"},
+		{"/v1x/projects", "/v1x/projects"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ProxyWorker(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if got := <-gotPath; got != tt.want {
+			t.Errorf("%s proxied to %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPathRewriteApply(t *testing.T) {
+	rw, err := parsePathRewrite("/worker", []string{"/gen=/generate", "/legacy/=/v2/"})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/worker/foo", "/foo"},
+		{"/worker", "/"},
+		{"/workers/foo", "/workers/foo"},
+		{"/worker/gen", "/generate"},
+		{"/gen/stream", "/generate/stream"},
+		{"/legacy/a/b", "/v2/a/b"},
+		{"/general", "/general"},
+	}
+	for _, tt := range tests {
+		if got := rw.apply(tt.path); got != tt.want {
+			t.Errorf("apply(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, bad := range [][]string{{"gen=/generate"}, {"/gen"}, {"/gen=generate"}} {
+		if _, err := parsePathRewrite("", bad); err == nil {
+			t.Errorf("parsePathRewrite(%q) succeeded, want error", bad)
+		}
+	}
+	if _, err := parsePathRewrite("worker", nil); err == nil {
+		t.Error("relative prefix accepted")
+	}
+}
+
+func TestProxyWorkerRewritesPathKeepingQueryAndHost(t *testing.T) {
+	type seen struct{ uri, host string }
+	got := make(chan seen, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- seen{r.URL.RequestURI(), r.Host}
+	}))
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL)
+	rewrite, err := parsePathRewrite("/worker", nil)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	h := newTestHandler(&config.Config{})
+	h.workerProxy = newWorkerProxy(target, rewrite, nil, "", h.log)
+
+	req := httptest.NewRequest(http.MethodGet, "/worker/foo?page=2", nil)
+	req.Host = "gateway.example.com"
+	h.ProxyWorker(httptest.NewRecorder(), req)
+
+	s := <-got
+	if s.uri != "/foo?page=2" {
+		t.Errorf("worker saw %s, want /foo?page=2", s.uri)
+	}
+	if s.host != target.Host {
+		t.Errorf("worker saw Host %s, want %s", s.host, target.Host)
+	}
+}
+
+func TestProxyWorkerTimesOutHungWorker(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	target, _ := url.Parse(upstream.URL)
+	h := newTestHandler(&config.Config{WorkerTimeoutSecs: 1})
+	h.workerProxy = newWorkerProxy(target, nil, newWorkerTransport(0, 1), "", h.log)
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	h.ProxyWorker(rec, httptest.NewRequest(http.MethodGet, "/projects/x/status", nil))
+
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), `"error":"worker_timeout"`) {
+		t.Errorf("got %d %s, want %d worker_timeout", rec.Code, rec.Body.String(), http.StatusGatewayTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("proxy waited %s for a hung worker", elapsed)
+	}
+}
+
+func TestProxyWorkerInjectsWorkerToken(t *testing.T) {
+	var got []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Values(WorkerTokenHeader)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	tests := []struct {
+		name  string
+		token string
+		want  []string
+	}{
+		{"token replaces client value", "s3cret", []string{"s3cret"}},
+		{"no token strips client value", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(&config.Config{})
+			h.workerProxy = newWorkerProxy(target, nil, nil, tt.token, h.log)
+
+			req := httptest.NewRequest(http.MethodGet, "/projects/x/status", nil)
+			req.Header.Set(WorkerTokenHeader, "forged")
+			h.ProxyWorker(httptest.NewRecorder(), req)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("worker saw %s = %q, want %q", WorkerTokenHeader, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyWorkerCountsResponsesAndFailures(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	target, _ := url.Parse(upstream.URL)
+	h := newTestHandler(&config.Config{})
+	h.workerProxy = newWorkerProxy(target, nil, nil, "", h.log)
+
+	accepted := observability.Metrics.ProxyRequests.WithLabelValues("202")
+	before := testutil.ToFloat64(accepted)
+	h.ProxyWorker(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/projects/x/status", nil))
+	if got := testutil.ToFloat64(accepted) - before; got != 1 {
+		t.Errorf("202 responses counted = %v, want 1", got)
+	}
+
+	// A worker that has gone away refuses the connection
+	upstream.Close()
+	refused := observability.Metrics.ProxyErrors.WithLabelValues("connection_refused")
+	before = testutil.ToFloat64(refused)
+	rec := httptest.NewRecorder()
+	h.ProxyWorker(rec, httptest.NewRequest(http.MethodGet, "/projects/x/status", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"error":"worker_unavailable"`) {
+		t.Errorf("got %d %q, want 503 worker_unavailable", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", rec.Header().Get("Content-Type"))
+	}
+	if got := testutil.ToFloat64(refused) - before; got != 1 {
+		t.Errorf("refused connections counted = %v, want 1", got)
+	}
+}
+
+func TestProxyErrorReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"refused", &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, "connection_refused"},
+		{"reset", &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}, "connection_reset"},
+		{"closed mid-response", io.ErrUnexpectedEOF, "connection_reset"},
+		{"dns", &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "worker"}}, "dns"},
+		{"dial timeout", &net.OpError{Op: "dial", Err: timeoutError{}}, "timeout"},
+		{"other", errors.New("malformed HTTP response"), "upstream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := proxyErrorReason(fmt.Errorf("proxy: %w", tt.err)); got != tt.want {
+				t.Errorf("proxyErrorReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// timeoutError is a net.Error that reports a timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestProxyWorkerRejectsOversizedStreamingBody(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+	h := newTestHandler(&config.Config{})
+	h.workerProxy = newWorkerProxy(target, nil, nil, "", h.log)
+
+	// An unknown length gets past the Content-Length check, so the limit
+	// trips while the proxy is copying the body to the worker
+	rec := httptest.NewRecorder()
+	body := io.MultiReader(strings.NewReader(strings.Repeat("x", 64)))
+	req := httptest.NewRequest(http.MethodPost, "/projects/x/approve", body)
+	req.ContentLength = -1
+	req.Body = http.MaxBytesReader(rec, req.Body, 16)
+	h.ProxyWorker(rec, req)
+
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), `"error":"request_too_large"`) {
+		t.Errorf("got %d %q, want 413 request_too_large", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"net/netip"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kyros-praxis/gateway/internal/middleware"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// ---- Rate Limiter Admin Handlers ----
+
+// rateLimitIP parses the {ip} URL parameter, writing a 400 if it is invalid.
+func (h *Handler) rateLimitIP(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !h.requireFeature(w, h.rateLimiter != nil, "Rate limiter not configured") {
+		return "", false
+	}
+	addr, err := netip.ParseAddr(chi.URLParam(r, "ip"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid_ip", "Invalid IP address")
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+// GetRateLimit handles GET /admin/ratelimit/{ip} - reports an IP's current
+// request counts against the global and any per-route limits.
+func (h *Handler) GetRateLimit(w http.ResponseWriter, r *http.Request) {
+	ip, ok := h.rateLimitIP(w, r)
+	if !ok {
+		return
+	}
+
+	usage := h.rateLimiter.Usage(ip)
+	windows := make([]map[string]interface{}, len(usage))
+	for i, u := range usage {
+		window := map[string]interface{}{
+			"route":   u.Route,
+			"client":  u.Client,
+			"count":   u.Count,
+			"limit":   u.Limit,
+			"limited": u.Count >= u.Limit,
+		}
+		if u.Count > 0 {
+			window["reset_at"] = models.FormatTime(u.ResetAt)
+		}
+		windows[i] = window
+	}
+
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"ip":             ip,
+		"window_seconds": int(middleware.RateLimitWindow.Seconds()),
+		"windows":        windows,
+	})
+}
+
+// ResetRateLimit handles DELETE /admin/ratelimit/{ip} - clears an IP's
+// request counts so a throttled client is let through immediately.
+func (h *Handler) ResetRateLimit(w http.ResponseWriter, r *http.Request) {
+	ip, ok := h.rateLimitIP(w, r)
+	if !ok {
+		return
+	}
+
+	cleared := h.rateLimiter.Reset(ip)
+	h.logger(r).Info("rate limit reset", "ip", ip, "cleared", cleared)
+
+	h.writeJSON(w, r, http.StatusOK, map[string]interface{}{
+		"ip":      ip,
+		"cleared": cleared,
+	})
+}
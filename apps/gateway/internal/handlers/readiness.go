@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// Readiness states, from best to worst.
+const (
+	readinessHealthy   = "healthy"
+	readinessDegraded  = "degraded"
+	readinessUnhealthy = "unhealthy"
+)
+
+// readinessCheckTimeout bounds each dependency check.
+const readinessCheckTimeout = 2 * time.Second
+
+// readinessCheck is a dependency probed by GET /ready. A failing critical
+// dependency makes the gateway unhealthy; any other failure only degrades it.
+type readinessCheck struct {
+	name     string
+	critical bool
+	check    func(context.Context) error
+}
+
+// AddReadinessCheck registers a dependency for the readiness endpoint.
+func (h *Handler) AddReadinessCheck(name string, critical bool, check func(context.Context) error) {
+	h.readiness = append(h.readiness, readinessCheck{name: name, critical: critical, check: check})
+}
+
+// Ready handles GET /ready. It reports each dependency and an overall state:
+// healthy, degraded (a non-critical dependency such as Redis is down; still
+// 200 so load balancers keep routing), or unhealthy (a critical dependency
+// such as Postgres is down; 503).
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	results := make([]models.DependencyStatus, len(h.readiness))
+
+	var wg sync.WaitGroup
+	for i, c := range h.readiness {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := c.check(ctx)
+			results[i] = models.DependencyStatus{
+				Name:      c.name,
+				Status:    "up",
+				Critical:  c.critical,
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				results[i].Status = "down"
+				// Details stay in the logs; the endpoint is unauthenticated
+				h.logger(r).Warn("readiness check failed", "dependency", c.name, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	status := readinessHealthy
+	for _, res := range results {
+		if res.Status == "up" {
+			continue
+		}
+		if res.Critical {
+			status = readinessUnhealthy
+			break
+		}
+		status = readinessDegraded
+	}
+
+	code := http.StatusOK
+	if status == readinessUnhealthy {
+		code = http.StatusServiceUnavailable
+	}
+	h.writeJSON(w, r, code, models.ReadinessResponse{
+		Status:       status,
+		Dependencies: results,
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestReadyStates(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name     string
+		postgres func(context.Context) error
+		redis    func(context.Context) error
+		code     int
+		status   string
+	}{
+		{"all up", up, up, http.StatusOK, "healthy"},
+		{"redis down", up, down, http.StatusOK, "degraded"},
+		{"postgres down", down, up, http.StatusServiceUnavailable, "unhealthy"},
+		{"both down", down, down, http.StatusServiceUnavailable, "unhealthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(&config.Config{})
+			h.AddReadinessCheck("postgres", true, tt.postgres)
+			h.AddReadinessCheck("redis", false, tt.redis)
+
+			rec := httptest.NewRecorder()
+			h.Ready(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			if rec.Code != tt.code {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.code)
+			}
+			var resp models.ReadinessResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Status != tt.status {
+				t.Errorf("status = %q, want %q", resp.Status, tt.status)
+			}
+			if len(resp.Dependencies) != 2 {
+				t.Errorf("got %d dependencies, want 2", len(resp.Dependencies))
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/pagination"
+)
+
+// envelopeMediaType lets a client opt in to the envelope per request,
+// regardless of the RESPONSE_ENVELOPE setting.
+const envelopeMediaType = "application/vnd.kyros.envelope+json"
+
+// paginated is implemented by list responses; their page details are lifted
+// into the envelope's meta block.
+type paginated interface {
+	PaginationMeta() *models.PaginationMeta
+}
+
+// wantsEnvelope reports whether the response should be wrapped in an envelope.
+func (h *Handler) wantsEnvelope(r *http.Request) bool {
+	if h.cfg.ResponseEnvelope {
+		return true
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err == nil && mediaType == envelopeMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// envelope wraps data as {"data": ..., "meta": ...}.
+func (h *Handler) envelope(r *http.Request, data interface{}) models.Envelope {
+	meta := &models.EnvelopeMeta{
+		RequestID: chimw.GetReqID(r.Context()),
+	}
+	if p, ok := data.(paginated); ok {
+		meta.Pagination = p.PaginationMeta()
+	}
+	return models.Envelope{Data: data, Meta: meta}
+}
+
+// paginate builds the standard list response for one page of items.
+func paginate[T any](items []T, total int, p pagination.Params) models.PaginatedResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return models.PaginatedResponse[T]{
+		Items:      items,
+		Total:      total,
+		Limit:      p.Limit,
+		Offset:     p.Offset,
+		HasMore:    p.HasMore(total),
+		NextCursor: p.NextCursor(total),
+	}
+}
+
+// writeList writes one page of a list whose clients predate pagination.
+// They keep getting a bare array; the page object, with total and has_more,
+// is only served to clients that opt in to the envelope.
+func writeList[T any](h *Handler, w http.ResponseWriter, r *http.Request, items []T, total int, p pagination.Params) {
+	if h.wantsEnvelope(r) {
+		h.writeJSON(w, r, http.StatusOK, paginate(items, total, p))
+		return
+	}
+	if items == nil {
+		items = []T{}
+	}
+	h.encodeJSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/pagination"
+)
+
+func newTestHandler(cfg *config.Config) *Handler {
+	return &Handler{
+		cfg: cfg,
+		log: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestWriteJSONEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope bool
+		accept   string
+		wrapped  bool
+	}{
+		{"default is bare", false, "", false},
+		{"plain json is bare", false, "application/json", false},
+		{"config forces envelope", true, "application/json", true},
+		{"vendor media type", false, envelopeMediaType, true},
+		{"vendor media type with params", false, envelopeMediaType + "; q=0.9; charset=utf-8", true},
+		{"vendor media type in list", false, "application/json, " + envelopeMediaType + ";q=0.5", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(&config.Config{ResponseEnvelope: tt.envelope})
+			handler := chimw.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				h.writeJSON(w, r, http.StatusOK, map[string]string{"name": "demo"})
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid JSON response: %v", err)
+			}
+
+			_, hasData := body["data"]
+			if hasData != tt.wrapped {
+				t.Fatalf("wrapped = %v, want %v (body %s)", hasData, tt.wrapped, rec.Body.String())
+			}
+			if !tt.wrapped {
+				if _, ok := body["name"]; !ok {
+					t.Fatalf("bare response missing fields: %s", rec.Body.String())
+				}
+				return
+			}
+
+			var meta struct {
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(body["meta"], &meta); err != nil {
+				t.Fatalf("invalid meta: %v", err)
+			}
+			if meta.RequestID == "" {
+				t.Fatalf("meta.request_id not populated: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteErrorIsNeverWrapped(t *testing.T) {
+	h := newTestHandler(&config.Config{ResponseEnvelope: true})
+	rec := httptest.NewRecorder()
+
+	h.writeError(rec, http.StatusBadRequest, "validation_error", "bad input")
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if _, ok := body["error"]; !ok {
+		t.Fatalf("error response was wrapped: %s", rec.Body.String())
+	}
+}
+
+func TestWriteJSONUnencodableValue(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	rec := httptest.NewRecorder()
+
+	h.writeJSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusOK, map[string]interface{}{
+		"ok":     true,
+		"broken": make(chan int),
+	})
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	var body models.ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v: %s", err, rec.Body.String())
+	}
+	if body.Error != "internal_error" {
+		t.Errorf("error = %q, want internal_error", body.Error)
+	}
+}
+
+func TestEnvelopeCarriesPagination(t *testing.T) {
+	h := newTestHandler(&config.Config{ResponseEnvelope: true})
+	page := pagination.Params{Limit: 2, Offset: 0}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+	rec := httptest.NewRecorder()
+	h.writeJSON(rec, req, http.StatusOK, paginate([]string{"a", "b"}, 5, page))
+
+	var body struct {
+		Meta struct {
+			Pagination *models.PaginationMeta `json:"pagination"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	pm := body.Meta.Pagination
+	if pm == nil || pm.Total != 5 || pm.Limit != 2 || !pm.HasMore || pm.NextCursor == "" {
+		t.Fatalf("unexpected pagination meta: %+v", pm)
+	}
+}
+
+func TestWriteListPageObjectIsOptIn(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+	page := pagination.Params{Limit: 2, Offset: 0}
+
+	// Clients that don't opt in keep the bare array, even for an empty page
+	rec := httptest.NewRecorder()
+	writeList(h, rec, httptest.NewRequest(http.MethodGet, "/projects", nil), []string{"a", "b"}, 5, page)
+	var items []string
+	if err := json.Unmarshal(rec.Body.Bytes(), &items); err != nil || len(items) != 2 {
+		t.Fatalf("default response = %s, want a bare array", rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	writeList[string](h, rec, httptest.NewRequest(http.MethodGet, "/projects", nil), nil, 0, page)
+	if got := rec.Body.String(); got != "[]\n" {
+		t.Errorf("empty default response = %q, want []", got)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+	req.Header.Set("Accept", envelopeMediaType)
+	rec = httptest.NewRecorder()
+	writeList(h, rec, req, []string{"a", "b"}, 5, page)
+	var body struct {
+		Data models.PaginatedResponse[string] `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON response: %v", err)
+	}
+	if len(body.Data.Items) != 2 || body.Data.Total != 5 || !body.Data.HasMore {
+		t.Fatalf("opted-in response = %s, want the page object", rec.Body.String())
+	}
+}
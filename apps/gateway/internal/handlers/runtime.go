@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+// Runtime handles GET /admin/runtime: goroutine, heap, GC and database pool
+// stats for a quick look during an incident, without enabling pprof.
+func (h *Handler) Runtime(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	resp := models.RuntimeResponse{
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		HeapSysBytes:   mem.HeapSys,
+		HeapObjects:    mem.HeapObjects,
+		GC: models.GCStats{
+			NumGC:        mem.NumGC,
+			PauseTotalMS: float64(mem.PauseTotalNs) / float64(time.Millisecond),
+			CPUFraction:  mem.GCCPUFraction,
+		},
+	}
+	if mem.NumGC > 0 {
+		// PauseNs is a ring buffer; the latest pause is at (NumGC+255)%256
+		resp.GC.LastPauseMS = float64(mem.PauseNs[(mem.NumGC+255)%256]) / float64(time.Millisecond)
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		resp.GC.LastGC = &lastGC
+	}
+	if h.db != nil {
+		pool := h.db.PoolStats()
+		resp.DBPool = &pool
+	}
+	h.writeJSON(w, r, http.StatusOK, resp)
+}
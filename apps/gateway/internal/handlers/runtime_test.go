@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+)
+
+func TestRuntimeReportsProcessStats(t *testing.T) {
+	// No database: the pool section is left out
+	h := newTestHandler(&config.Config{})
+	runtime.GC()
+
+	rec := httptest.NewRecorder()
+	h.Runtime(rec, httptest.NewRequest(http.MethodGet, "/admin/runtime", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var resp models.RuntimeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Goroutines < 1 || resp.HeapAllocBytes == 0 {
+		t.Errorf("goroutines = %d, heap_alloc_bytes = %d, want both set", resp.Goroutines, resp.HeapAllocBytes)
+	}
+	if resp.GC.NumGC == 0 || resp.GC.LastGC == nil {
+		t.Errorf("gc = %+v, want the forced collection counted", resp.GC)
+	}
+	if resp.DBPool != nil {
+		t.Errorf("db_pool = %+v, want omitted without a database", resp.DBPool)
+	}
+}
@@ -0,0 +1,78 @@
+// Package jobs provides periodic background maintenance jobs.
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ExemptRoles are never flagged or removed by account cleanup.
+var ExemptRoles = []string{"admin", "service"}
+
+// AccountStore is the subset of the database used by AccountCleanup.
+type AccountStore interface {
+	DeactivateDormantUsers(ctx context.Context, idleSince time.Time, exemptRoles, exemptEmails []string) (int64, error)
+}
+
+// AccountPolicy controls account cleanup. A zero DormantAfter skips the job.
+type AccountPolicy struct {
+	DormantAfter time.Duration // Deactivate accounts with no login for this long
+	ExemptEmails []string      // Accounts to leave alone regardless of role
+}
+
+// AccountCleanup periodically deactivates dormant accounts according to its
+// policy.
+type AccountCleanup struct {
+	store    AccountStore
+	policy   AccountPolicy
+	interval time.Duration
+	log      *slog.Logger
+	now      func() time.Time
+}
+
+// NewAccountCleanup creates a cleanup job that runs every interval.
+func NewAccountCleanup(store AccountStore, policy AccountPolicy, interval time.Duration, log *slog.Logger) *AccountCleanup {
+	return &AccountCleanup{
+		store:    store,
+		policy:   policy,
+		interval: interval,
+		log:      log,
+		now:      time.Now,
+	}
+}
+
+// Enabled reports whether the policy turns the job on.
+func (c *AccountCleanup) Enabled() bool {
+	return c.policy.DormantAfter > 0
+}
+
+// Run executes the cleanup immediately and then every interval until ctx is cancelled.
+func (c *AccountCleanup) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce executes the job once, if enabled, and logs how many accounts it
+// affected.
+func (c *AccountCleanup) RunOnce(ctx context.Context) {
+	now := c.now()
+
+	if c.policy.DormantAfter > 0 {
+		n, err := c.store.DeactivateDormantUsers(ctx, now.Add(-c.policy.DormantAfter), ExemptRoles, c.policy.ExemptEmails)
+		if err != nil {
+			c.log.Error("dormant account cleanup failed", "error", err)
+		} else {
+			c.log.Info("dormant account cleanup", "deactivated", n, "dormant_after", c.policy.DormantAfter.String())
+		}
+	}
+}
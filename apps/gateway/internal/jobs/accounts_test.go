@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type fakeAccountStore struct {
+	dormantCutoff time.Time
+	exemptRoles   []string
+	exemptEmails  []string
+	dormantCalls  int
+}
+
+func (f *fakeAccountStore) DeactivateDormantUsers(ctx context.Context, idleSince time.Time, exemptRoles, exemptEmails []string) (int64, error) {
+	f.dormantCalls++
+	f.dormantCutoff = idleSince
+	f.exemptRoles, f.exemptEmails = exemptRoles, exemptEmails
+	return 1, nil
+}
+
+func TestAccountCleanupRunOnce(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name        string
+		policy      AccountPolicy
+		wantEnabled bool
+		wantDormant int
+	}{
+		{"skipped", AccountPolicy{}, false, 0},
+		{"dormant", AccountPolicy{DormantAfter: 180 * day}, true, 1},
+		{"dormant with exempt emails", AccountPolicy{DormantAfter: 180 * day, ExemptEmails: []string{"ops@example.com"}}, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeAccountStore{}
+			c := NewAccountCleanup(store, tt.policy, time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			c.now = func() time.Time { return now }
+
+			if c.Enabled() != tt.wantEnabled {
+				t.Fatalf("Enabled() = %v, want %v", c.Enabled(), tt.wantEnabled)
+			}
+			c.RunOnce(context.Background())
+
+			if store.dormantCalls != tt.wantDormant {
+				t.Fatalf("dormant calls = %d, want %d", store.dormantCalls, tt.wantDormant)
+			}
+			if tt.wantDormant > 0 && !store.dormantCutoff.Equal(now.Add(-tt.policy.DormantAfter)) {
+				t.Fatalf("dormant cutoff = %v", store.dormantCutoff)
+			}
+			if tt.wantEnabled && len(store.exemptRoles) != len(ExemptRoles) {
+				t.Fatalf("exempt roles = %v, want %v", store.exemptRoles, ExemptRoles)
+			}
+			if len(store.exemptEmails) != len(tt.policy.ExemptEmails) {
+				t.Fatalf("exempt emails = %v, want %v", store.exemptEmails, tt.policy.ExemptEmails)
+			}
+		})
+	}
+}
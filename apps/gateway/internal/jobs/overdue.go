@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/events"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// OverdueStore is the subset of the database used by OverdueChecker.
+type OverdueStore interface {
+	MarkNewlyOverdueTasks(ctx context.Context) ([]models.Task, error)
+	CountOverdueTasks(ctx context.Context) (int, error)
+}
+
+// Publisher publishes events to the workers.
+type Publisher interface {
+	Publish(ctx context.Context, projectID string, eventType events.EventType, payload interface{}) error
+}
+
+// OverdueChecker periodically publishes task_overdue for tasks that have
+// passed their due date and keeps the overdue gauge current.
+type OverdueChecker struct {
+	store     OverdueStore
+	publisher Publisher // May be nil when Redis is not configured
+	interval  time.Duration
+	log       *slog.Logger
+}
+
+// NewOverdueChecker creates a checker that runs every interval. With a nil
+// publisher, newly overdue tasks are only counted.
+func NewOverdueChecker(store OverdueStore, publisher Publisher, interval time.Duration, log *slog.Logger) *OverdueChecker {
+	return &OverdueChecker{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		log:       log,
+	}
+}
+
+// Run checks immediately and then every interval until ctx is cancelled.
+func (c *OverdueChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce publishes events for newly overdue tasks and updates the gauge.
+func (c *OverdueChecker) RunOnce(ctx context.Context) {
+	tasks, err := c.store.MarkNewlyOverdueTasks(ctx)
+	if err != nil {
+		c.log.Error("overdue task check failed", "error", err)
+	}
+
+	if len(tasks) > 0 {
+		c.log.Info("tasks became overdue", "count", len(tasks))
+	}
+	if c.publisher != nil {
+		for _, t := range tasks {
+			payload := events.TaskOverduePayload{
+				TaskID:    t.ID.String(),
+				ProjectID: t.ProjectID.String(),
+				Title:     t.Title,
+				DueAt:     t.DueAt.UTC().Format(time.RFC3339),
+			}
+			if err := c.publisher.Publish(ctx, t.ProjectID.String(), events.EventTypeTaskOverdue, payload); err != nil {
+				c.log.Error("failed to publish task_overdue event", "task_id", t.ID, "error", err)
+			}
+		}
+	}
+
+	count, err := c.store.CountOverdueTasks(ctx)
+	if err != nil {
+		c.log.Error("failed to count overdue tasks", "error", err)
+		return
+	}
+	observability.Metrics.TasksOverdue.Set(float64(count))
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/events"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeOverdueStore struct {
+	newlyOverdue []models.Task
+	overdue      int
+}
+
+func (f *fakeOverdueStore) MarkNewlyOverdueTasks(ctx context.Context) ([]models.Task, error) {
+	tasks := f.newlyOverdue
+	f.newlyOverdue = nil // Each task is only reported once
+	return tasks, nil
+}
+
+func (f *fakeOverdueStore) CountOverdueTasks(ctx context.Context) (int, error) {
+	return f.overdue, nil
+}
+
+type fakePublisher struct {
+	published []interface{}
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, projectID string, eventType events.EventType, payload interface{}) error {
+	if _, err := events.ValidatePayload(eventType, payload); err != nil {
+		return err
+	}
+	f.published = append(f.published, payload)
+	return nil
+}
+
+func TestOverdueCheckerPublishesOnce(t *testing.T) {
+	due := time.Now().Add(-time.Hour)
+	store := &fakeOverdueStore{
+		newlyOverdue: []models.Task{{ID: uuid.New(), ProjectID: uuid.New(), Title: "Ship", DueAt: &due}},
+		overdue:      3,
+	}
+	pub := &fakePublisher{}
+	c := NewOverdueChecker(store, pub, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	c.RunOnce(context.Background())
+	c.RunOnce(context.Background())
+
+	if len(pub.published) != 1 {
+		t.Fatalf("published %d task_overdue events, want 1", len(pub.published))
+	}
+	if got := testutil.ToFloat64(observability.Metrics.TasksOverdue); got != 3 {
+		t.Fatalf("overdue gauge = %v, want 3", got)
+	}
+}
+
+func TestOverdueCheckerWithoutPublisher(t *testing.T) {
+	due := time.Now().Add(-time.Hour)
+	store := &fakeOverdueStore{
+		newlyOverdue: []models.Task{{ID: uuid.New(), ProjectID: uuid.New(), DueAt: &due}},
+		overdue:      1,
+	}
+	c := NewOverdueChecker(store, nil, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	c.RunOnce(context.Background())
+
+	if got := testutil.ToFloat64(observability.Metrics.TasksOverdue); got != 1 {
+		t.Fatalf("overdue gauge = %v, want 1", got)
+	}
+}
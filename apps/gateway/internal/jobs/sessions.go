@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// SessionStore is the subset of the session manager used by SessionPruner.
+type SessionStore interface {
+	PruneStaleSessionRefs(ctx context.Context) (int, error)
+}
+
+// SessionPruner periodically removes references to expired sessions from
+// the per-user session sets in Redis.
+type SessionPruner struct {
+	store    SessionStore
+	interval time.Duration
+	log      *slog.Logger
+}
+
+// NewSessionPruner creates a pruner that runs every interval.
+func NewSessionPruner(store SessionStore, interval time.Duration, log *slog.Logger) *SessionPruner {
+	return &SessionPruner{
+		store:    store,
+		interval: interval,
+		log:      log,
+	}
+}
+
+// Run prunes immediately and then every interval until ctx is cancelled.
+func (p *SessionPruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce prunes stale session references and counts them.
+func (p *SessionPruner) RunOnce(ctx context.Context) {
+	pruned, err := p.store.PruneStaleSessionRefs(ctx)
+	observability.Metrics.SessionsPruned.Add(float64(pruned))
+	if err != nil {
+		p.log.Error("session pruning failed", "pruned", pruned, "error", err)
+		return
+	}
+	if pruned > 0 {
+		p.log.Info("pruned stale session references", "count", pruned)
+	}
+}
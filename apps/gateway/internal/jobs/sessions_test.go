@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type fakeSessionStore struct {
+	pruned int
+	err    error
+}
+
+func (f *fakeSessionStore) PruneStaleSessionRefs(ctx context.Context) (int, error) {
+	return f.pruned, f.err
+}
+
+func TestSessionPrunerCountsPrunedRefs(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	before := testutil.ToFloat64(observability.Metrics.SessionsPruned)
+
+	NewSessionPruner(&fakeSessionStore{pruned: 4}, time.Minute, log).RunOnce(context.Background())
+	// Refs removed before a failure still count
+	NewSessionPruner(&fakeSessionStore{pruned: 2, err: errors.New("redis down")}, time.Minute, log).RunOnce(context.Background())
+
+	if got := testutil.ToFloat64(observability.Metrics.SessionsPruned) - before; got != 6 {
+		t.Errorf("pruned counter grew by %v, want 6", got)
+	}
+}
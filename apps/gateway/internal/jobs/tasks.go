@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// archiveBatchSize bounds each archive statement so a large backlog is worked
+// through without holding locks on many rows at once.
+const archiveBatchSize = 1000
+
+// TaskStore is the subset of the database used by TaskArchiver.
+type TaskStore interface {
+	ArchiveCompletedTasks(ctx context.Context, completedBefore time.Time, hardDelete bool, limit int) (int64, error)
+}
+
+// TaskRetention controls how long completed tasks stay in default listings.
+type TaskRetention struct {
+	After      time.Duration // Archive tasks completed this long ago
+	HardDelete bool          // Delete them instead of archiving
+}
+
+// TaskArchiver periodically archives or deletes tasks that have been
+// completed for longer than the retention period.
+type TaskArchiver struct {
+	store     TaskStore
+	retention TaskRetention
+	interval  time.Duration
+	log       *slog.Logger
+	now       func() time.Time
+}
+
+// NewTaskArchiver creates an archiver that runs every interval.
+func NewTaskArchiver(store TaskStore, retention TaskRetention, interval time.Duration, log *slog.Logger) *TaskArchiver {
+	return &TaskArchiver{
+		store:     store,
+		retention: retention,
+		interval:  interval,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// Run archives immediately and then every interval until ctx is cancelled.
+func (a *TaskArchiver) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		a.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce archives every expired task in batches, counting them as it goes.
+func (a *TaskArchiver) RunOnce(ctx context.Context) {
+	action := "archived"
+	if a.retention.HardDelete {
+		action = "deleted"
+	}
+	cutoff := a.now().Add(-a.retention.After)
+
+	var total int64
+	for ctx.Err() == nil {
+		n, err := a.store.ArchiveCompletedTasks(ctx, cutoff, a.retention.HardDelete, archiveBatchSize)
+		total += n
+		observability.Metrics.TasksArchived.WithLabelValues(action).Add(float64(n))
+		if err != nil {
+			a.log.Error("task retention failed", "action", action, "affected", total, "error", err)
+			return
+		}
+		if n < archiveBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		a.log.Info("task retention", "action", action, "tasks", total, "completed_before", cutoff)
+	}
+}
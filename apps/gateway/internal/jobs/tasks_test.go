@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeTaskStore hands out remaining expired tasks a batch at a time.
+type fakeTaskStore struct {
+	remaining  int64
+	cutoff     time.Time
+	hardDelete bool
+	calls      int
+}
+
+func (f *fakeTaskStore) ArchiveCompletedTasks(ctx context.Context, completedBefore time.Time, hardDelete bool, limit int) (int64, error) {
+	f.calls++
+	f.cutoff, f.hardDelete = completedBefore, hardDelete
+	n := min(f.remaining, int64(limit))
+	f.remaining -= n
+	return n, nil
+}
+
+func TestTaskArchiverRunOnce(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name      string
+		retention TaskRetention
+		expired   int64
+		wantCalls int
+		action    string
+	}{
+		{"nothing to archive", TaskRetention{After: 30 * 24 * time.Hour}, 0, 1, "archived"},
+		{"several batches", TaskRetention{After: 30 * 24 * time.Hour}, 2*archiveBatchSize + 5, 3, "archived"},
+		{"exact batch needs a second look", TaskRetention{After: 24 * time.Hour}, archiveBatchSize, 2, "archived"},
+		{"hard delete", TaskRetention{After: 24 * time.Hour, HardDelete: true}, 7, 1, "deleted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeTaskStore{remaining: tt.expired}
+			a := NewTaskArchiver(store, tt.retention, time.Hour, log)
+			a.now = func() time.Time { return now }
+			counter := observability.Metrics.TasksArchived.WithLabelValues(tt.action)
+			before := testutil.ToFloat64(counter)
+
+			a.RunOnce(context.Background())
+
+			if store.calls != tt.wantCalls {
+				t.Errorf("store called %d times, want %d", store.calls, tt.wantCalls)
+			}
+			if want := now.Add(-tt.retention.After); !store.cutoff.Equal(want) {
+				t.Errorf("cutoff = %v, want %v", store.cutoff, want)
+			}
+			if store.hardDelete != tt.retention.HardDelete {
+				t.Errorf("hardDelete = %v, want %v", store.hardDelete, tt.retention.HardDelete)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != float64(tt.expired) {
+				t.Errorf("%s counter grew by %v, want %d", tt.action, got, tt.expired)
+			}
+		})
+	}
+}
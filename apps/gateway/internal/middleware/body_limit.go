@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BodyLimiter caps request body sizes before handlers or the worker proxy
+// read them, so a client can't stream an unbounded body through the gateway.
+type BodyLimiter struct {
+	defaultLimit int64
+	routeLimits  map[string]int // Keyed by normalized route pattern; 0 means no limit
+}
+
+// NewBodyLimiter creates a limiter allowing defaultLimit bytes per request
+// body; 0 or less disables the default.
+func NewBodyLimiter(defaultLimit int64) *BodyLimiter {
+	return &BodyLimiter{defaultLimit: defaultLimit}
+}
+
+// SetRouteLimits overrides the default for the given route patterns. A limit
+// of 0 exempts the route, e.g. a streaming upload. Call before serving.
+func (bl *BodyLimiter) SetRouteLimits(limits map[string]int) {
+	normalized := make(map[string]int, len(limits))
+	for pattern, limit := range limits {
+		normalized[normalizePattern(pattern)] = limit
+	}
+	bl.routeLimits = normalized
+}
+
+// ParseBodyLimits parses "pattern=bytes" entries such as "/projects=65536"
+// into body limits keyed by route pattern. Unlike ParseRouteLimits, 0 is
+// allowed and exempts the route.
+func ParseBodyLimits(entries []string) (map[string]int, error) {
+	limits := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		pattern, value, ok := strings.Cut(entry, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid body limit %q: want /pattern=bytes", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid body limit %q: bytes must be a non-negative integer", entry)
+		}
+		limits[normalizePattern(pattern)] = limit
+	}
+	return limits, nil
+}
+
+// Middleware rejects bodies declared larger than the route's limit with 413
+// and wraps the rest in http.MaxBytesReader, so a chunked body that runs over
+// fails the read that crosses the limit.
+func (bl *BodyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := bl.defaultLimit
+		if routeLimit, ok := bl.routeLimits[normalizePattern(routePattern(r))]; ok {
+			limit = int64(routeLimit)
+		}
+		if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ContentLength > limit {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Connection", "close")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			_, _ = fmt.Fprintf(w, `{"error":"request_too_large","message":"Request body exceeds %d bytes"}`, limit)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestBodyLimiter(t *testing.T) {
+	limiter := NewBodyLimiter(16)
+	limits, err := ParseBodyLimits([]string{"/upload=0", " /small = 4"})
+	if err != nil {
+		t.Fatalf("ParseBodyLimits: %v", err)
+	}
+	limiter.SetRouteLimits(limits)
+
+	// Handlers report whether reading the whole body hit the limit
+	read := func(w http.ResponseWriter, r *http.Request) {
+		_, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}
+	r := chi.NewRouter()
+	r.Use(limiter.Middleware)
+	r.Post("/default", read)
+	r.Post("/upload", read)
+	r.Post("/small", read)
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		want    int
+	}{
+		{"within default", "/default", 16, false, http.StatusOK},
+		{"declared over default", "/default", 17, false, http.StatusRequestEntityTooLarge},
+		{"chunked over default", "/default", 17, true, http.StatusRequestEntityTooLarge},
+		{"exempt route", "/upload", 1 << 16, false, http.StatusOK},
+		{"lower route limit", "/small", 5, false, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = strings.NewReader(strings.Repeat("x", tt.size))
+			if tt.chunked {
+				body = io.MultiReader(body) // Hides the length, as a chunked upload would
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBodyLimitsRejectsMalformedEntries(t *testing.T) {
+	for _, entry := range []string{"projects=10", "/projects", "/projects=-1", "/projects=lots"} {
+		if _, err := ParseBodyLimits([]string{entry}); err == nil {
+			t.Errorf("ParseBodyLimits(%q) succeeded, want an error", entry)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+)
+
+// maxLoggedBody caps how much of each request and response body BodyLogger
+// keeps; the rest still reaches the handler and client, just unlogged.
+const maxLoggedBody = 64 << 10
+
+// redactedValue replaces the value of every sensitive field.
+const redactedValue = "[REDACTED]"
+
+// sensitiveKeys are matched case-insensitively as substrings of field names.
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "api_key", "apikey", "cookie", "session", "code", "otp"}
+
+// BodyLogger logs the redacted request and response bodies of requests to
+// one chi route pattern, e.g. "/projects/{id}/generate", at Debug level. The
+// pattern also matches under apiPrefix (e.g. "/v1"). It is meant to be added
+// only while debugging an integration: other routes pass straight through,
+// and matched bodies are buffered up to 64 KiB so the handler still reads
+// the request normally.
+func BodyLogger(log *slog.Logger, pattern, apiPrefix string) func(http.Handler) http.Handler {
+	pattern = normalizePattern(pattern)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := normalizePattern(routePattern(r))
+			if rest, ok := strings.CutPrefix(route, apiPrefix); ok && apiPrefix != "" && strings.HasPrefix(rest, "/") {
+				route = rest
+			}
+			if route != pattern {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Re-wrap the body so the handler sees all of it, logged or not
+			reqBody, _ := io.ReadAll(io.LimitReader(r.Body, maxLoggedBody+1))
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+
+			rec := &cappedBodyRecorder{responseWriter: responseWriter{ResponseWriter: w, status: http.StatusOK}}
+			next.ServeHTTP(rec, r)
+
+			log.Debug("request body",
+				"request_id", chimw.GetReqID(r.Context()),
+				"method", r.Method,
+				"route", routePattern(r),
+				"body", redactBody(reqBody, r.Header.Get("Content-Type")),
+				"truncated", len(reqBody) > maxLoggedBody,
+			)
+			log.Debug("response body",
+				"request_id", chimw.GetReqID(r.Context()),
+				"status", rec.status,
+				"body", redactBody(rec.body.Bytes(), rec.Header().Get("Content-Type")),
+				"truncated", rec.truncated,
+			)
+		})
+	}
+}
+
+// cappedBodyRecorder keeps a copy of the first maxLoggedBody bytes of a
+// response while writing through.
+type cappedBodyRecorder struct {
+	responseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (rec *cappedBodyRecorder) Write(b []byte) (int, error) {
+	if room := maxLoggedBody - rec.body.Len(); room > 0 {
+		rec.body.Write(b[:min(len(b), room)])
+		rec.truncated = rec.truncated || len(b) > room
+	} else if len(b) > 0 {
+		rec.truncated = true
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// redactBody returns body for logging with sensitive fields replaced. JSON
+// and form bodies are redacted field by field; anything else, including a
+// body cut off at the size cap, is summarized rather than logged verbatim.
+func redactBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	body = body[:min(len(body), maxLoggedBody)]
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "[unparseable JSON body]"
+		}
+		out, _ := json.Marshal(redactJSON(v))
+		return string(out)
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "[unparseable form body]"
+		}
+		for key := range form {
+			if isSensitiveKey(key) {
+				form[key] = []string{redactedValue}
+			}
+		}
+		return form.Encode()
+	case mediaType == "":
+		return "[untyped body omitted]"
+	default:
+		return "[" + mediaType + " body omitted]"
+	}
+}
+
+// redactJSON replaces sensitive fields at any depth of a decoded JSON value.
+func redactJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if isSensitiveKey(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactJSON(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactJSON(value)
+		}
+	}
+	return v
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
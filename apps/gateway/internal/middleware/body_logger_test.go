@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestBodyLoggerLogsOnlyItsRoute(t *testing.T) {
+	var logs bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	r := chi.NewRouter()
+	r.Use(BodyLogger(log, "/projects/{id}/generate", "/v1"))
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}
+	r.Post("/v1/projects/{id}/generate", echo)
+	r.Post("/v1/auth/login", echo)
+
+	body := `{"prompt":"Build a todo app","api_key":"sk-123","nested":{"refresh_token":"rt"}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/projects/42/generate", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Body.String() != body {
+		t.Fatalf("handler did not see the full body: %q", rec.Body.String())
+	}
+
+	var entries []map[string]interface{}
+	dec := json.NewDecoder(&logs)
+	for dec.More() {
+		var entry map[string]interface{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("decode log: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want request and response", len(entries))
+	}
+	for _, entry := range entries {
+		logged, _ := entry["body"].(string)
+		if strings.Contains(logged, "sk-123") || strings.Contains(logged, `"rt"`) {
+			t.Errorf("%s not redacted: %s", entry["msg"], logged)
+		}
+		if !strings.Contains(logged, "Build a todo app") {
+			t.Errorf("%s lost non-sensitive fields: %s", entry["msg"], logged)
+		}
+	}
+
+	logs.Reset()
+	req = httptest.NewRequest(http.MethodPost, "/v1/auth/login", strings.NewReader(`{"email":"a@example.com"}`))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if logs.Len() != 0 {
+		t.Errorf("other routes should not be logged: %s", logs.String())
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+	}{
+		{"json", `{"email":"a@example.com","password":"hunter2"}`, "application/json", `{"email":"a@example.com","password":"[REDACTED]"}`},
+		{"json array", `[{"Token":"x"}]`, "application/json; charset=utf-8", `[{"Token":"[REDACTED]"}]`},
+		{"form", "code=123456&state=abc", "application/x-www-form-urlencoded", "code=%5BREDACTED%5D&state=abc"},
+		{"other", "plain text", "text/plain", "[text/plain body omitted]"},
+		{"bad json", `{"password":`, "application/json", "[unparseable JSON body]"},
+		{"empty", "", "application/json", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactBody([]byte(tt.body), tt.contentType); got != tt.want {
+				t.Errorf("redactBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
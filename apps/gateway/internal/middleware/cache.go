@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/redis/go-redis/v9"
+)
+
+const cacheKeyPrefix = "kyros:cache:"
+
+// ResponseCache caches successful GET responses in Redis, keyed by user, path,
+// query, and Accept header. Each scope has a generation counter; any
+// successful write through the same scope bumps it, which invalidates every
+// cached response in that scope at once. Stale entries age out via the TTL.
+//
+// A nil *ResponseCache is valid and caches nothing.
+type ResponseCache struct {
+	redis *redis.Client
+	ttl   time.Duration
+}
+
+// NewResponseCache creates a response cache with the given entry lifetime.
+func NewResponseCache(client *redis.Client, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{redis: client, ttl: ttl}
+}
+
+type cachedResponse struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+// Middleware caches reads and invalidates on writes for the named scope
+// (e.g. "projects"). Mount it on both the read and write routes of a resource.
+func (c *ResponseCache) Middleware(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if c == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				c.invalidateOnSuccess(scope, next, w, r)
+				return
+			}
+
+			reqCC := r.Header.Get("Cache-Control")
+			if hasDirective(reqCC, "no-store") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := c.key(r.Context(), scope, r)
+			if err != nil {
+				// Redis unavailable - serve uncached
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !hasDirective(reqCC, "no-cache") {
+				if cached, ok := c.get(r.Context(), key); ok {
+					observability.Metrics.CacheHits.WithLabelValues(scope).Inc()
+					w.Header().Set("Content-Type", cached.ContentType)
+					if cached.ETag != "" {
+						w.Header().Set("ETag", cached.ETag)
+					}
+					w.Header().Set("X-Cache", "HIT")
+					w.WriteHeader(http.StatusOK)
+					_, _ = w.Write(cached.Body)
+					return
+				}
+			}
+			observability.Metrics.CacheMisses.WithLabelValues(scope).Inc()
+
+			w.Header().Set("X-Cache", "MISS")
+			rec := &bodyRecorder{responseWriter: responseWriter{ResponseWriter: w, status: http.StatusOK}}
+			next.ServeHTTP(rec, r)
+
+			header := w.Header()
+			if rec.status != http.StatusOK ||
+				hasDirective(header.Get("Cache-Control"), "no-store") ||
+				header.Get("Set-Cookie") != "" {
+				return
+			}
+			c.set(r.Context(), key, cachedResponse{
+				ContentType: header.Get("Content-Type"),
+				ETag:        header.Get("ETag"),
+				Body:        rec.body.Bytes(),
+			})
+		})
+	}
+}
+
+// Invalidate drops every cached response in scope.
+func (c *ResponseCache) Invalidate(ctx context.Context, scope string) error {
+	if c == nil {
+		return nil
+	}
+	return c.redis.Incr(ctx, cacheKeyPrefix+scope+":gen").Err()
+}
+
+func (c *ResponseCache) invalidateOnSuccess(scope string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	rec := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+	next.ServeHTTP(rec, r)
+	if rec.status < 300 {
+		// Use a fresh context: the write has happened even if the client left
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = c.Invalidate(ctx, scope)
+	}
+}
+
+func (c *ResponseCache) key(ctx context.Context, scope string, r *http.Request) (string, error) {
+	gen, err := c.redis.Get(ctx, cacheKeyPrefix+scope+":gen").Result()
+	if err == redis.Nil {
+		gen = "0"
+	} else if err != nil {
+		return "", err
+	}
+
+	principal := "anon"
+	if user := auth.GetUserFromContext(r.Context()); user != nil {
+		principal = user.ID.String()
+	}
+	if orgID := auth.GetOrgIDFromContext(r.Context()); orgID != nil {
+		principal += "@" + orgID.String()
+	}
+
+	// Encode() sorts by key, so equivalent queries share an entry
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		principal, r.URL.Path, r.URL.Query().Encode(), r.Header.Get("Accept"),
+	}, "\n")))
+	return cacheKeyPrefix + scope + ":" + gen + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+func (c *ResponseCache) get(ctx context.Context, key string) (cachedResponse, bool) {
+	var cached cachedResponse
+	raw, err := c.redis.Get(ctx, key).Bytes()
+	if err != nil {
+		return cached, false
+	}
+	if err := json.Unmarshal(raw, &cached); err != nil {
+		return cached, false
+	}
+	return cached, true
+}
+
+func (c *ResponseCache) set(ctx context.Context, key string, resp cachedResponse) {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	_ = c.redis.Set(ctx, key, raw, c.ttl).Err()
+}
+
+// hasDirective reports whether a Cache-Control header contains directive.
+func hasDirective(cacheControl, directive string) bool {
+	for _, d := range strings.Split(cacheControl, ",") {
+		if strings.EqualFold(strings.TrimSpace(d), directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyRecorder captures the status and a copy of the body while writing through.
+type bodyRecorder struct {
+	responseWriter
+	body bytes.Buffer
+}
+
+func (br *bodyRecorder) Write(b []byte) (int, error) {
+	br.body.Write(b)
+	return br.ResponseWriter.Write(b)
+}
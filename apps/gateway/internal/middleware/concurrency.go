@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// ConcurrencyLimiter caps how many requests to a route execute at once,
+// across all clients. Unlike the rate limiter it allows no bursts, so it
+// suits routes whose cost is held for the whole request, such as workflow
+// generation occupying a worker until the stream ends.
+type ConcurrencyLimiter struct {
+	apiPrefix string
+	slots     map[string]chan struct{} // Keyed by unversioned route pattern
+}
+
+// NewConcurrencyLimiter creates a limiter with no limited routes. A route and
+// its form under apiPrefix (e.g. "/v1") share one limit.
+func NewConcurrencyLimiter(apiPrefix string) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{apiPrefix: apiPrefix}
+}
+
+// SetRouteLimits sets the most requests allowed in flight per route pattern.
+// Call before serving.
+func (cl *ConcurrencyLimiter) SetRouteLimits(limits map[string]int) {
+	slots := make(map[string]chan struct{}, len(limits))
+	for pattern, limit := range limits {
+		key := cl.key(pattern)
+		if _, ok := slots[key]; !ok && limit > 0 {
+			slots[key] = make(chan struct{}, limit)
+		}
+	}
+	cl.slots = slots
+}
+
+// key strips the API prefix so both forms of a route find the same slots.
+func (cl *ConcurrencyLimiter) key(pattern string) string {
+	pattern = normalizePattern(pattern)
+	if rest, ok := strings.CutPrefix(pattern, cl.apiPrefix); ok && cl.apiPrefix != "" && (rest == "" || strings.HasPrefix(rest, "/")) {
+		pattern = normalizePattern("/" + strings.TrimPrefix(rest, "/"))
+	}
+	return pattern
+}
+
+// Middleware answers 503 with Retry-After when the route is at its limit;
+// otherwise it holds a slot until the handler returns.
+func (cl *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(cl.slots) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := cl.key(routePattern(r))
+		slots, ok := cl.slots[route]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			observability.Metrics.ConcurrencyHits.WithLabelValues(route).Inc()
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", "5")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":"concurrency_limit","message":"Too many requests in progress for this endpoint. Try again shortly."}`))
+			return
+		}
+
+		inFlight := observability.Metrics.RouteInFlight.WithLabelValues(route)
+		inFlight.Inc()
+		defer func() {
+			inFlight.Dec()
+			<-slots
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
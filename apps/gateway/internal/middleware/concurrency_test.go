@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	cl := NewConcurrencyLimiter("/v1")
+	cl.SetRouteLimits(map[string]int{"/projects/{id}/generate": 2, "/v1/projects/{id}/generate": 2})
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}
+	r := chi.NewRouter()
+	r.Use(cl.Middleware)
+	r.Post("/projects/{id}/generate", slow)
+	r.Post("/v1/projects/{id}/generate", slow)
+	r.Get("/projects", func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(method, path string) int {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code
+	}
+
+	// Fill both slots, one through each form of the route
+	var wg sync.WaitGroup
+	for _, path := range []string{"/projects/1/generate", "/v1/projects/2/generate"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			serve(http.MethodPost, path)
+		}()
+		<-started
+	}
+	inFlight := observability.Metrics.RouteInFlight.WithLabelValues("/projects/{id}/generate")
+	if got := testutil.ToFloat64(inFlight); got != 2 {
+		t.Errorf("in flight = %v, want 2", got)
+	}
+
+	if code := serve(http.MethodPost, "/projects/3/generate"); code != http.StatusServiceUnavailable {
+		t.Errorf("over the limit: status = %d, want 503", code)
+	}
+	if code := serve(http.MethodGet, "/projects"); code != http.StatusOK {
+		t.Errorf("unlimited route: status = %d, want 200", code)
+	}
+
+	// Finished requests free their slots
+	close(release)
+	wg.Wait()
+	if got := testutil.ToFloat64(inFlight); got != 0 {
+		t.Errorf("in flight after release = %v, want 0", got)
+	}
+	done := make(chan int)
+	go func() { done <- serve(http.MethodPost, "/projects/4/generate") }()
+	<-started
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("after release: status = %d, want 200", code)
+	}
+}
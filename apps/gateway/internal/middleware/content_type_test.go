@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestJSONContentType(t *testing.T) {
+	jc := NewJSONContentType()
+	jc.SetExemptRoutes([]string{"/projects/{id}/approve"})
+
+	r := chi.NewRouter()
+	r.Use(jc.Middleware)
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	r.Post("/projects", ok)
+	r.Patch("/projects/{id}", ok)
+	r.Post("/projects/{id}/approve", ok)
+	r.Post("/auth/mfa/setup", ok)
+	r.Get("/projects", ok)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"json", http.MethodPost, "/projects", "application/json", `{}`, http.StatusOK},
+		{"json with charset", http.MethodPatch, "/projects/1", "application/json; charset=utf-8", `{}`, http.StatusOK},
+		{"json suffix", http.MethodPost, "/projects", "application/merge-patch+json", `{}`, http.StatusOK},
+		{"text/plain", http.MethodPost, "/projects", "text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{"form", http.MethodPatch, "/projects/1", "application/x-www-form-urlencoded", `{}`, http.StatusUnsupportedMediaType},
+		{"missing", http.MethodPost, "/projects", "", `{}`, http.StatusUnsupportedMediaType},
+		{"no body", http.MethodPost, "/auth/mfa/setup", "", "", http.StatusOK},
+		{"exempt route", http.MethodPost, "/projects/7/approve", "text/plain", `anything`, http.StatusOK},
+		{"read", http.MethodGet, "/projects", "text/plain", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.want == http.StatusUnsupportedMediaType && !strings.Contains(rec.Body.String(), `"error":"unsupported_media_type"`) {
+				t.Errorf("body = %s, want unsupported_media_type", rec.Body.String())
+			}
+		})
+	}
+}
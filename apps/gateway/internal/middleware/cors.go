@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/cors"
+)
+
+// credentialedPrefixes are route prefixes that carry sessions or admin
+// access and so must never get the credential-free public CORS policy.
+var credentialedPrefixes = []string{"/auth", "/admin", "/org"}
+
+// MonitoringRoutes are the unversioned health and metrics endpoints. They
+// never use cookies, so they get a CORS policy without credentials.
+var MonitoringRoutes = []string{"/health", "/ready", "/metrics"}
+
+// RouteCORS applies the global CORS policy everywhere except to:
+//   - MonitoringRoutes, which get monitoringOrigins with safe methods only
+//     and credentials off;
+//   - requests for public routes from origins the global policy doesn't
+//     allow. Those get a public policy with publicOrigins, safe methods only
+//     and credentials off, so read-only routes can be embedded more widely
+//     without widening the set of sites that may send cookies.
+//
+// Public route prefixes match at segment boundaries, both as given and under
+// apiPrefix (e.g. "/v1").
+func RouteCORS(global cors.Options, publicRoutes, publicOrigins, monitoringOrigins []string, apiPrefix string) (func(http.Handler) http.Handler, error) {
+	if len(publicRoutes) > 0 && len(publicOrigins) == 0 {
+		return nil, fmt.Errorf("public CORS routes need at least one public origin")
+	}
+	for _, route := range publicRoutes {
+		if !strings.HasPrefix(route, "/") || route == "/" {
+			return nil, fmt.Errorf("invalid public CORS route %q: want a path prefix such as /projects", route)
+		}
+		for _, prefix := range credentialedPrefixes {
+			if hasPathPrefix(route, prefix) || hasPathPrefix(prefix, route) {
+				return nil, fmt.Errorf("public CORS route %q overlaps credentialed routes under %s", route, prefix)
+			}
+		}
+	}
+
+	globalCORS := cors.Handler(global)
+	publicCORS := cors.Handler(cors.Options{
+		AllowedOrigins:   publicOrigins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodHead, http.MethodOptions},
+		AllowedHeaders:   global.AllowedHeaders,
+		ExposedHeaders:   global.ExposedHeaders,
+		AllowCredentials: false,
+		MaxAge:           global.MaxAge,
+	})
+	monitoringCORS := cors.Handler(cors.Options{
+		AllowedOrigins:   monitoringOrigins,
+		AllowedMethods:   []string{http.MethodGet, http.MethodHead, http.MethodOptions},
+		AllowedHeaders:   []string{"Accept"},
+		AllowCredentials: false,
+		MaxAge:           global.MaxAge,
+	})
+
+	return func(next http.Handler) http.Handler {
+		globalNext, publicNext, monitoringNext := globalCORS(next), publicCORS(next), monitoringCORS(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			path := r.URL.Path
+			if slices.Contains(MonitoringRoutes, path) {
+				monitoringNext.ServeHTTP(w, r)
+				return
+			}
+			if rest, ok := strings.CutPrefix(path, apiPrefix); ok && apiPrefix != "" && strings.HasPrefix(rest, "/") {
+				path = rest
+			}
+			if origin != "" && !originAllowed(global.AllowedOrigins, origin) && isPublicRoute(publicRoutes, path) {
+				publicNext.ServeHTTP(w, r)
+				return
+			}
+			globalNext.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+func isPublicRoute(routes []string, path string) bool {
+	for _, route := range routes {
+		if hasPathPrefix(path, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether prefix covers path at a segment boundary, so
+// "/projects" covers "/projects/1" but not "/projectsx".
+func hasPathPrefix(path, prefix string) bool {
+	rest, ok := strings.CutPrefix(path, strings.TrimSuffix(prefix, "/"))
+	return ok && (rest == "" || strings.HasPrefix(rest, "/"))
+}
+
+// originAllowed matches origin the way go-chi/cors does: "*" allows any
+// origin and a single "*" inside a pattern matches any substring.
+func originAllowed(patterns []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if prefix, suffix, ok := strings.Cut(pattern, "*"); ok &&
+			len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
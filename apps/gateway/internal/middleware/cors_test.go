@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/cors"
+)
+
+func TestRouteCORS(t *testing.T) {
+	global := cors.Options{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		AllowCredentials: true,
+	}
+	mw, err := RouteCORS(global, []string{"/projects"}, []string{"*"}, []string{"*"}, "/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		origin    string
+		preflight string // Access-Control-Request-Method
+		allowed   bool
+		creds     bool
+	}{
+		{"app origin keeps credentials", http.MethodGet, "/projects", "https://app.example.com", "", true, true},
+		{"public read from any origin", http.MethodGet, "/v1/projects/42", "https://embed.example.org", "", true, false},
+		{"public preflight for write refused", http.MethodOptions, "/projects", "https://embed.example.org", "POST", false, false},
+		{"auth stays restricted", http.MethodGet, "/auth/me", "https://embed.example.org", "", false, false},
+		{"segment boundary", http.MethodGet, "/projectsx", "https://embed.example.org", "", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.Header.Set("Origin", tt.origin)
+			if tt.preflight != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin") != ""; got != tt.allowed {
+				t.Errorf("allowed = %v, want %v", got, tt.allowed)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.creds {
+				t.Errorf("credentials = %v, want %v", got, tt.creds)
+			}
+		})
+	}
+}
+
+func TestRouteCORSRejectsConflictingRoutes(t *testing.T) {
+	for _, routes := range [][]string{{"/auth"}, {"/admin/projects"}, {"/"}, {"projects"}} {
+		if _, err := RouteCORS(cors.Options{}, routes, []string{"*"}, nil, "/v1"); err == nil {
+			t.Errorf("RouteCORS(%q) succeeded, want error", routes)
+		}
+	}
+	if _, err := RouteCORS(cors.Options{}, []string{"/projects"}, nil, nil, "/v1"); err == nil {
+		t.Error("public routes without public origins should be rejected")
+	}
+}
+
+func TestRouteCORSMonitoringWithoutCredentials(t *testing.T) {
+	global := cors.Options{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+		AllowCredentials: true,
+	}
+	mw, err := RouteCORS(global, nil, nil, []string{"https://app.example.com", "https://grafana.example.com"}, "/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name      string
+		path      string
+		origin    string
+		preflight string
+		allowed   bool
+		creds     bool
+	}{
+		{"health drops credentials for app origin", "/health", "https://app.example.com", "", true, false},
+		{"metrics open to monitoring origin", "/metrics", "https://grafana.example.com", "", true, false},
+		{"ready refuses other origins", "/ready", "https://evil.example.org", "", false, false},
+		{"monitoring preflight for write refused", "/health", "https://grafana.example.com", "POST", false, false},
+		{"monitoring origin gets nothing elsewhere", "/projects", "https://grafana.example.com", "", false, false},
+		{"api keeps credentials", "/v1/projects", "https://app.example.com", "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodGet
+			if tt.preflight != "" {
+				method = http.MethodOptions
+			}
+			r := httptest.NewRequest(method, tt.path, nil)
+			r.Header.Set("Origin", tt.origin)
+			if tt.preflight != "" {
+				r.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin") != ""; got != tt.allowed {
+				t.Errorf("allowed = %v, want %v", got, tt.allowed)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.creds {
+				t.Errorf("credentials = %v, want %v", got, tt.creds)
+			}
+		})
+	}
+}
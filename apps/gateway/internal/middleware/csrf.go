@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
 )
 
 // CSRFConfig holds CSRF configuration.
@@ -130,12 +132,14 @@ func (c *CSRFProtection) Middleware(next http.Handler) http.Handler {
 		cookieToken, err := r.Cookie(c.config.CookieName)
 
 		if err != nil || headerToken == "" {
+			observability.Metrics.CSRFFailures.WithLabelValues("missing").Inc()
 			http.Error(w, `{"error":"csrf_token_missing","message":"CSRF token required"}`, http.StatusForbidden)
 			return
 		}
 
 		// Both tokens must match and be valid
 		if headerToken != cookieToken.Value || !c.ValidateToken(headerToken) {
+			observability.Metrics.CSRFFailures.WithLabelValues("invalid").Inc()
 			http.Error(w, `{"error":"csrf_token_invalid","message":"Invalid CSRF token"}`, http.StatusForbidden)
 			return
 		}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCSRFFailuresCountedByReason(t *testing.T) {
+	csrf := NewCSRFProtection(DefaultCSRFConfig())
+	h := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request without a valid CSRF token reached the handler")
+	}))
+
+	tests := []struct {
+		name   string
+		token  string
+		reason string
+	}{
+		{"missing token", "", "missing"},
+		{"unknown token", "forged", "invalid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := observability.Metrics.CSRFFailures.WithLabelValues(tt.reason)
+			before := testutil.ToFloat64(counter)
+
+			r := httptest.NewRequest(http.MethodPost, "/projects", nil)
+			if tt.token != "" {
+				r.Header.Set("X-CSRF-Token", tt.token)
+				r.AddCookie(&http.Cookie{Name: "csrf_token", Value: tt.token})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("%s failures increased by %v, want 1", tt.reason, got)
+			}
+		})
+	}
+}
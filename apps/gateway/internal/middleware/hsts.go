@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// hstsPreloadMinAge is the shortest max-age the browser preload list accepts.
+const hstsPreloadMinAge = 365 * 24 * 60 * 60
+
+// HSTS sends Strict-Transport-Security with the given max-age in seconds.
+// A maxAge of 0 sends nothing. Preload is opt-in and, as the preload list
+// requires, needs includeSubdomains and a max-age of at least a year.
+func HSTS(maxAge int, includeSubdomains, preload bool) (func(http.Handler) http.Handler, error) {
+	if maxAge < 0 {
+		return nil, fmt.Errorf("HSTS max-age must not be negative, got %d", maxAge)
+	}
+	if preload && (maxAge < hstsPreloadMinAge || !includeSubdomains) {
+		return nil, fmt.Errorf("HSTS preload needs includeSubDomains and a max-age of at least %d seconds", hstsPreloadMinAge)
+	}
+	if maxAge == 0 {
+		return func(next http.Handler) http.Handler { return next }, nil
+	}
+
+	value := "max-age=" + strconv.Itoa(maxAge)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if preload {
+		value += "; preload"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Strict-Transport-Security", value)
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHSTS(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxAge     int
+		subdomains bool
+		preload    bool
+		want       string
+	}{
+		{"off", 0, false, false, ""},
+		{"max-age only", 86400, false, false, "max-age=86400"},
+		{"subdomains", 86400, true, false, "max-age=86400; includeSubDomains"},
+		{"preload", 31536000, true, true, "max-age=31536000; includeSubDomains; preload"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw, err := HSTS(tt.maxAge, tt.subdomains, tt.preload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rec := httptest.NewRecorder()
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHSTSRejectsUnsafePreload(t *testing.T) {
+	for _, tt := range []struct {
+		maxAge     int
+		subdomains bool
+	}{
+		{86400, true},     // Too short for the preload list
+		{31536000, false}, // Preload covers subdomains
+		{0, true},
+	} {
+		if _, err := HSTS(tt.maxAge, tt.subdomains, true); err == nil {
+			t.Errorf("HSTS(%d, %v, preload) succeeded, want error", tt.maxAge, tt.subdomains)
+		}
+	}
+	if _, err := HSTS(-1, false, false); err == nil {
+		t.Error("negative max-age should be rejected")
+	}
+}
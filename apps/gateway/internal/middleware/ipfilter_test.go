@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		addr  string
+		want  int
+	}{
+		{"no lists", nil, nil, "203.0.113.7", http.StatusOK},
+		{"allowlist match", []string{"10.0.0.0/8"}, nil, "10.1.2.3", http.StatusOK},
+		{"allowlist miss", []string{"10.0.0.0/8"}, nil, "203.0.113.7", http.StatusForbidden},
+		{"blocklist match", nil, []string{"203.0.113.0/24"}, "203.0.113.7", http.StatusForbidden},
+		{"blocklist miss", nil, []string{"203.0.113.0/24"}, "198.51.100.1", http.StatusOK},
+		{"deny overrides allow", []string{"10.0.0.0/8"}, []string{"10.0.0.5"}, "10.0.0.5", http.StatusForbidden},
+		{"unparseable address", []string{"10.0.0.0/8"}, nil, "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, _ := netip.ParseAddr(tt.addr)
+			f, err := NewIPFilter(tt.allow, tt.deny, func(*http.Request) netip.Addr { return addr },
+				slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if err != nil {
+				t.Fatalf("NewIPFilter: %v", err)
+			}
+			h := f.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/admin/providers", nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCIDRsRejectsInvalid(t *testing.T) {
+	if _, err := ParseCIDRs([]string{"10.0.0.0/8", "not-a-cidr"}); err == nil {
+		t.Fatal("expected an error for an invalid entry")
+	}
+}
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/observability"
+)
+
+// LoginLimiter throttles login attempts per client, judged by the response:
+// failed attempts (401, 403) count against a low limit and successful ones
+// against a higher one, so password guessing is cut off quickly while people
+// who sign in successfully, e.g. from a shared office address, are not.
+type LoginLimiter struct {
+	failures     map[string][]time.Time
+	successes    map[string][]time.Time
+	mu           sync.Mutex
+	maxFailures  int
+	maxSuccesses int
+	window       time.Duration
+	clientIP     func(*http.Request) netip.Addr
+	stopCleanup  chan struct{}
+}
+
+// NewLoginLimiter creates a login limiter allowing maxFailures failed and
+// maxSuccesses successful attempts per client within window. clientIP
+// resolves the request's address, so a client behind trusted proxies is
+// counted once however it fills in X-Forwarded-For.
+func NewLoginLimiter(maxFailures, maxSuccesses int, window time.Duration, clientIP func(*http.Request) netip.Addr) *LoginLimiter {
+	ll := &LoginLimiter{
+		failures:     make(map[string][]time.Time),
+		successes:    make(map[string][]time.Time),
+		maxFailures:  maxFailures,
+		maxSuccesses: maxSuccesses,
+		window:       window,
+		clientIP:     clientIP,
+		stopCleanup:  make(chan struct{}),
+	}
+	go ll.cleanupLoop()
+	return ll
+}
+
+func (ll *LoginLimiter) cleanupLoop() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ll.cleanup()
+		case <-ll.stopCleanup:
+			return
+		}
+	}
+}
+
+func (ll *LoginLimiter) cleanup() {
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+
+	cutoff := time.Now().Add(-ll.window)
+	for _, attempts := range []map[string][]time.Time{ll.failures, ll.successes} {
+		for client := range attempts {
+			if recent := pruneAttempts(attempts[client], cutoff); len(recent) == 0 {
+				delete(attempts, client)
+			} else {
+				attempts[client] = recent
+			}
+		}
+	}
+}
+
+// Stop stops the cleanup goroutine.
+func (ll *LoginLimiter) Stop() {
+	close(ll.stopCleanup)
+}
+
+// Middleware returns an HTTP middleware that applies the login limits.
+func (ll *LoginLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP := ll.clientIP(r).String()
+
+		ll.mu.Lock()
+		now := time.Now()
+		cutoff := now.Add(-ll.window)
+		failures := pruneAttempts(ll.failures[clientIP], cutoff)
+		successes := pruneAttempts(ll.successes[clientIP], cutoff)
+		ll.failures[clientIP], ll.successes[clientIP] = failures, successes
+
+		var oldest time.Time
+		switch {
+		case len(failures) >= ll.maxFailures:
+			oldest = failures[0]
+		case len(successes) >= ll.maxSuccesses:
+			oldest = successes[0]
+		}
+		if !oldest.IsZero() {
+			ll.mu.Unlock()
+			observability.Metrics.RateLimitHits.WithLabelValues(routePattern(r)).Inc()
+			retryAfter := int(oldest.Add(ll.window).Sub(now).Seconds()) + 1
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprintf(w, `{"error":"login_rate_limit","message":"Too many login attempts. Try again in %d seconds."}`, retryAfter)
+			return
+		}
+
+		// Count the attempt as failed until the response says otherwise, so
+		// concurrent guesses can't all slip in under the limit
+		ll.failures[clientIP] = append(failures, now)
+		ll.mu.Unlock()
+
+		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrapped, r)
+
+		if wrapped.status == http.StatusUnauthorized || wrapped.status == http.StatusForbidden {
+			return
+		}
+		ll.mu.Lock()
+		ll.failures[clientIP] = removeAttempt(ll.failures[clientIP], now)
+		if wrapped.status >= 200 && wrapped.status < 300 {
+			ll.successes[clientIP] = append(ll.successes[clientIP], now)
+		}
+		ll.mu.Unlock()
+	})
+}
+
+// pruneAttempts drops attempts at or before cutoff, reusing the slice.
+func pruneAttempts(attempts []time.Time, cutoff time.Time) []time.Time {
+	recent := attempts[:0]
+	for _, t := range attempts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// removeAttempt removes the attempt recorded at t.
+func removeAttempt(attempts []time.Time, t time.Time) []time.Time {
+	for i, at := range attempts {
+		if at.Equal(t) {
+			return append(attempts[:i], attempts[i+1:]...)
+		}
+	}
+	return attempts
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/config"
+)
+
+func TestLoginLimiterCountsFailuresAndSuccessesSeparately(t *testing.T) {
+	ll := NewLoginLimiter(2, 3, time.Minute, auth.New(&config.Config{}, nil).ClientIP)
+	defer ll.Stop()
+
+	// The handler answers with whatever status the test asks for
+	handler := ll.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		w.WriteHeader(status)
+	}))
+	login := func(client string, status int) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login?status="+strconv.Itoa(status), nil)
+		req.RemoteAddr = client
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Successes and malformed requests leave the failure budget alone
+	for _, status := range []int{http.StatusOK, http.StatusBadRequest, http.StatusOK} {
+		if got := login("10.0.0.1:1", status); got != status {
+			t.Fatalf("status = %d, want %d", got, status)
+		}
+	}
+	login("10.0.0.1:1", http.StatusUnauthorized)
+	login("10.0.0.1:1", http.StatusUnauthorized)
+	if got := login("10.0.0.1:1", http.StatusOK); got != http.StatusTooManyRequests {
+		t.Errorf("after 2 failures status = %d, want 429", got)
+	}
+
+	// A client that only succeeds hits the higher success limit
+	for i := 0; i < 3; i++ {
+		if got := login("10.0.0.2:1", http.StatusOK); got != http.StatusOK {
+			t.Fatalf("success %d: status = %d, want 200", i+1, got)
+		}
+	}
+	if got := login("10.0.0.2:1", http.StatusOK); got != http.StatusTooManyRequests {
+		t.Errorf("after 3 successes status = %d, want 429", got)
+	}
+}
+
+func TestLoginLimiterHoldsConcurrentAttempts(t *testing.T) {
+	ll := NewLoginLimiter(2, 100, time.Minute, auth.New(&config.Config{}, nil).ClientIP)
+	defer ll.Stop()
+
+	// Requests block until all have passed the limiter, as parallel guesses would
+	release := make(chan struct{})
+	handler := ll.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	const attempts = 5
+	codes := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+			req.RemoteAddr = "10.0.0.3:1"
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			codes <- rec.Code
+		}()
+	}
+	// The over-limit attempts return at once; let the admitted ones finish
+	deadline := time.After(5 * time.Second)
+	throttled := 0
+	for throttled < attempts-2 {
+		select {
+		case code := <-codes:
+			if code != http.StatusTooManyRequests {
+				t.Fatalf("status = %d before release, want 429", code)
+			}
+			throttled++
+		case <-deadline:
+			t.Fatalf("%d attempts throttled, want %d", throttled, attempts-2)
+		}
+	}
+	close(release)
+	wg.Wait()
+}
+
+func TestLoginLimiterIgnoresSpoofedForwardedFor(t *testing.T) {
+	authService := auth.New(&config.Config{TrustedProxyCIDRs: []string{"10.1.0.0/16"}}, nil)
+	ll := NewLoginLimiter(2, 100, time.Minute, authService.ClientIP)
+	defer ll.Stop()
+
+	handler := ll.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	login := func(remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A direct client can't reset its count with a fresh header per guess
+	login("203.0.113.7:1", "198.51.100.1")
+	login("203.0.113.7:1", "198.51.100.2")
+	if got := login("203.0.113.7:1", "198.51.100.3"); got != http.StatusTooManyRequests {
+		t.Errorf("direct client with new X-Forwarded-For: status = %d, want 429", got)
+	}
+
+	// Behind the trusted proxy, entries the client prepends are ignored too
+	login("10.1.0.1:1", "198.51.100.4, 203.0.113.8")
+	login("10.1.0.1:1", "198.51.100.5, 203.0.113.8")
+	if got := login("10.1.0.1:1", "198.51.100.6, 203.0.113.8"); got != http.StatusTooManyRequests {
+		t.Errorf("proxied client with prepended entries: status = %d, want 429", got)
+	}
+
+	ll.mu.Lock()
+	defer ll.mu.Unlock()
+	if len(ll.failures) != 2 {
+		t.Errorf("tracked %d clients, want 2", len(ll.failures))
+	}
+}
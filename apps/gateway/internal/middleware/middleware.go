@@ -2,10 +2,22 @@
 package middleware
 
 import (
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/netip"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/observability"
 )
 
 // RateLimiter implements a simple in-memory rate limiter with cleanup.
@@ -13,14 +25,23 @@ type RateLimiter struct {
 	requests       map[string][]time.Time
 	mu             sync.RWMutex
 	requestsPerMin int
+	apiPrefix      string
+	routeLimits    map[string]int // Unversioned route pattern -> requests per minute, overriding requestsPerMin
+	warnPercent    int            // Share of a limit at which responses carry X-RateLimit-Warning; 0 disables
+	clientIP       func(*http.Request) netip.Addr
 	stopCleanup    chan struct{}
 }
 
-// NewRateLimiter creates a new rate limiter with periodic cleanup.
-func NewRateLimiter(requestsPerMin int) *RateLimiter {
+// NewRateLimiter creates a new rate limiter with periodic cleanup. A route and
+// its form under apiPrefix (e.g. "/v1") share one window. clientIP resolves
+// the request's address, so a client behind trusted proxies keeps one window
+// however it fills in X-Forwarded-For.
+func NewRateLimiter(requestsPerMin int, apiPrefix string, clientIP func(*http.Request) netip.Addr) *RateLimiter {
 	rl := &RateLimiter{
 		requests:       make(map[string][]time.Time),
 		requestsPerMin: requestsPerMin,
+		apiPrefix:      apiPrefix,
+		clientIP:       clientIP,
 		stopCleanup:    make(chan struct{}),
 	}
 	// Start cleanup goroutine
@@ -70,37 +91,163 @@ func (rl *RateLimiter) Stop() {
 	close(rl.stopCleanup)
 }
 
+// SetRouteLimits sets per-route limits keyed by chi route pattern. Requests
+// to those routes are counted in their own per-IP window instead of the
+// global one. Call it before serving requests.
+func (rl *RateLimiter) SetRouteLimits(limits map[string]int) {
+	rl.routeLimits = make(map[string]int, len(limits))
+	for pattern, limit := range limits {
+		rl.routeLimits[rl.route(pattern)] = limit
+	}
+}
+
+// route strips the API prefix so both forms of a route count in one window.
+func (rl *RateLimiter) route(pattern string) string {
+	pattern = normalizePattern(pattern)
+	if rest, ok := strings.CutPrefix(pattern, rl.apiPrefix); ok && rl.apiPrefix != "" && (rest == "" || strings.HasPrefix(rest, "/")) {
+		pattern = normalizePattern("/" + strings.TrimPrefix(rest, "/"))
+	}
+	return pattern
+}
+
+// SetWarnPercent makes responses carry X-RateLimit-Warning once a client has
+// used percent of its limit in the current window, so it can back off before
+// being throttled. 0 disables the warning. Call it before serving requests.
+func (rl *RateLimiter) SetWarnPercent(percent int) {
+	rl.warnPercent = percent
+}
+
+// ClientUsage is the state of one rate-limit window for a client.
+type ClientUsage struct {
+	Route   string    // Unversioned route pattern with its own limit; empty for the global limit
+	Client  string    // Client address as resolved by the limiter
+	Count   int       // Requests in the current window
+	Limit   int       // Requests allowed per window
+	ResetAt time.Time // When the oldest counted request leaves the window
+}
+
+// RateLimitWindow is the sliding window the limiter counts requests over.
+const RateLimitWindow = time.Minute
+
+// Usage reports the windows of the client whose address is ip.
+func (rl *RateLimiter) Usage(ip string) []ClientUsage {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+
+	cutoff := time.Now().Add(-RateLimitWindow)
+	var usage []ClientUsage
+	for key, times := range rl.requests {
+		route, client := splitLimiterKey(key)
+		if client != ip {
+			continue
+		}
+		u := ClientUsage{Route: route, Client: client, Limit: rl.requestsPerMin}
+		if route != "" {
+			u.Limit = rl.routeLimits[route]
+		}
+		for _, t := range times {
+			if t.After(cutoff) {
+				if u.Count == 0 {
+					u.ResetAt = t.Add(RateLimitWindow)
+				}
+				u.Count++
+			}
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+// Reset clears every window for ip and returns how many were cleared.
+func (rl *RateLimiter) Reset(ip string) int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	cleared := 0
+	for key := range rl.requests {
+		if _, client := splitLimiterKey(key); client == ip {
+			delete(rl.requests, key)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// splitLimiterKey separates a route-limited key ("pattern client") into its
+// parts. Patterns always start with "/" and client keys never do.
+func splitLimiterKey(key string) (route, client string) {
+	if strings.HasPrefix(key, "/") {
+		route, client, _ = strings.Cut(key, " ")
+		return route, client
+	}
+	return "", key
+}
+
+// ParseRouteLimits parses "pattern=limit" entries such as "/auth/login=10"
+// into requests-per-minute limits keyed by route pattern.
+func ParseRouteLimits(entries []string) (map[string]int, error) {
+	limits := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		pattern, value, ok := strings.Cut(entry, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid route limit %q: want /pattern=limit", entry)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid route limit %q: limit must be a positive integer", entry)
+		}
+		limits[normalizePattern(pattern)] = limit
+	}
+	return limits, nil
+}
+
+// normalizePattern drops a trailing slash so "/projects" and the "/projects/"
+// chi reports for a subrouter's index route are the same key.
+func normalizePattern(pattern string) string {
+	if len(pattern) > 1 {
+		return strings.TrimSuffix(pattern, "/")
+	}
+	return pattern
+}
+
 // Middleware returns an HTTP middleware that rate limits requests.
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Skip rate limiting for health checks
-		if r.URL.Path == "/health" || r.URL.Path == "/metrics" {
+		if r.URL.Path == "/health" || r.URL.Path == "/ready" || r.URL.Path == "/metrics" {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		clientIP := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			clientIP = forwarded
+		clientIP := rl.clientIP(r).String()
+
+		// Routes with their own limit get a separate window per IP
+		pattern := routePattern(r)
+		key, limit := clientIP, rl.requestsPerMin
+		route := rl.route(pattern)
+		if routeLimit, ok := rl.routeLimits[route]; ok {
+			key, limit = route+" "+clientIP, routeLimit
 		}
 
 		rl.mu.Lock()
 		now := time.Now()
-		cutoff := now.Add(-time.Minute)
+		cutoff := now.Add(-RateLimitWindow)
 
-		// Clean old requests for this IP
-		reqs := rl.requests[clientIP]
+		// Clean old requests for this key
+		reqs := rl.requests[key]
 		filtered := reqs[:0]
 		for _, t := range reqs {
 			if t.After(cutoff) {
 				filtered = append(filtered, t)
 			}
 		}
-		rl.requests[clientIP] = filtered
+		rl.requests[key] = filtered
 
 		// Check limit
-		if len(filtered) >= rl.requestsPerMin {
+		if len(filtered) >= limit {
 			rl.mu.Unlock()
+			observability.Metrics.RateLimitHits.WithLabelValues(pattern).Inc()
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("Retry-After", "60")
 			w.WriteHeader(http.StatusTooManyRequests)
@@ -109,9 +256,16 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 		}
 
 		// Add current request
-		rl.requests[clientIP] = append(rl.requests[clientIP], now)
+		rl.requests[key] = append(rl.requests[key], now)
+		used := len(rl.requests[key])
 		rl.mu.Unlock()
 
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(limit-used))
+		if rl.warnPercent > 0 && used*100 >= limit*rl.warnPercent {
+			w.Header().Set("X-RateLimit-Warning", fmt.Sprintf("%d of %d requests per minute used; slow down to avoid throttling", used, limit))
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
@@ -123,16 +277,19 @@ type MFALimiter struct {
 	mu             sync.RWMutex
 	maxAttempts    int           // Max attempts in window
 	windowDuration time.Duration // Time window
+	clientIP       func(*http.Request) netip.Addr
 	stopCleanup    chan struct{}
 }
 
 // NewMFALimiter creates a new MFA-specific rate limiter.
-// Default: 5 attempts per 5 minutes.
-func NewMFALimiter() *MFALimiter {
+// Default: 5 attempts per 5 minutes. clientIP resolves the request's address,
+// as for NewRateLimiter.
+func NewMFALimiter(clientIP func(*http.Request) netip.Addr) *MFALimiter {
 	ml := &MFALimiter{
 		attempts:       make(map[string][]time.Time),
 		maxAttempts:    5,
 		windowDuration: 5 * time.Minute,
+		clientIP:       clientIP,
 		stopCleanup:    make(chan struct{}),
 	}
 	go ml.cleanupLoop()
@@ -181,10 +338,7 @@ func (ml *MFALimiter) Stop() {
 // Middleware returns an HTTP middleware that applies MFA-specific rate limiting.
 func (ml *MFALimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			clientIP = forwarded
-		}
+		clientIP := ml.clientIP(r).String()
 
 		ml.mu.Lock()
 		now := time.Now()
@@ -203,6 +357,7 @@ func (ml *MFALimiter) Middleware(next http.Handler) http.Handler {
 		// Check limit - 5 attempts per 5 minutes
 		if len(filtered) >= ml.maxAttempts {
 			ml.mu.Unlock()
+			observability.Metrics.RateLimitHits.WithLabelValues(routePattern(r)).Inc()
 			w.Header().Set("Content-Type", "application/json")
 			w.Header().Set("Retry-After", "300")
 			w.WriteHeader(http.StatusTooManyRequests)
@@ -218,6 +373,26 @@ func (ml *MFALimiter) Middleware(next http.Handler) http.Handler {
 	})
 }
 
+// routePattern returns the chi route pattern for r (e.g. "/projects/{id}") so
+// metric labels don't explode with one series per ID. Limiters mounted with
+// r.Use run before routing, so the pattern is resolved by matching here.
+func routePattern(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return "unmatched"
+	}
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+	if rctx.Routes != nil {
+		tctx := chi.NewRouteContext()
+		if rctx.Routes.Match(tctx, r.Method, r.URL.Path) {
+			return tctx.RoutePattern()
+		}
+	}
+	return "unmatched"
+}
+
 // Logger returns an HTTP middleware that logs requests.
 func Logger(log *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -240,6 +415,21 @@ func Logger(log *slog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// RequestLogger stores a logger carrying the request ID and, when
+// authenticated, the user ID in the request context. It must run after the
+// auth middleware; handlers read it back with observability.LoggerFrom.
+func RequestLogger(log *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := log.With("request_id", chimw.GetReqID(r.Context()))
+			if user := auth.GetIdentityFromContext(r.Context()); user != nil {
+				reqLog = reqLog.With("user_id", user.ID.String())
+			}
+			next.ServeHTTP(w, r.WithContext(observability.WithLogger(r.Context(), reqLog)))
+		})
+	}
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	status int
@@ -250,16 +440,63 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// Recoverer returns an HTTP middleware that recovers from panics.
-func Recoverer(log *slog.Logger) func(http.Handler) http.Handler {
+// Unwrap lets http.ResponseController reach the underlying writer to flush
+// and adjust deadlines.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Streaming returns a middleware for long-lived responses (LLM streams, SSE)
+// that replaces the server-wide WriteTimeout with writeTimeout for the
+// request, or removes the deadline when writeTimeout is 0. The server-wide
+// timeout stays short so ordinary handlers can't hold connections open;
+// only routes marked streaming trade that protection for long responses.
+func Streaming(writeTimeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var deadline time.Time // Zero means no deadline
+			if writeTimeout > 0 {
+				deadline = time.Now().Add(writeTimeout)
+			}
+			_ = http.NewResponseController(w).SetWriteDeadline(deadline)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Recoverer returns an HTTP middleware that recovers from panics and logs
+// them with the stack trace, request ID and route. With exposeStack, meant
+// for local development only, the response also carries the panic value and
+// a trimmed stack; main never enables it in production.
+func Recoverer(log *slog.Logger, exposeStack bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
-					log.Error("panic recovered", "error", err, "path", r.URL.Path)
+					if err == http.ErrAbortHandler {
+						panic(err) // Deliberate abort (e.g. client went away mid-proxy)
+					}
+					stack := debug.Stack()
+					log.Error("panic recovered",
+						"error", err,
+						"request_id", chimw.GetReqID(r.Context()),
+						"method", r.Method,
+						"path", r.URL.Path,
+						"route", routePattern(r),
+						"stack", string(stack),
+					)
+
+					body := map[string]interface{}{
+						"error":   "internal_error",
+						"message": "An unexpected error occurred",
+					}
+					if exposeStack {
+						body["panic"] = fmt.Sprint(err)
+						body["stack"] = sanitizeStack(stack)
+					}
 					w.Header().Set("Content-Type", "application/json")
 					w.WriteHeader(http.StatusInternalServerError)
-					_, _ = w.Write([]byte(`{"error":"internal_error","message":"An unexpected error occurred"}`))
+					_ = json.NewEncoder(w).Encode(body)
 				}
 			}()
 			next.ServeHTTP(w, r)
@@ -267,6 +504,41 @@ func Recoverer(log *slog.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// sanitizeStack turns a debug.Stack trace into one "function file:line" entry
+// per frame. Argument values, directories and the goroutine header are
+// dropped, as are the frames of the panic machinery itself.
+func sanitizeStack(stack []byte) []string {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	frames := []string{}
+	for i := 1; i+1 < len(lines); i += 2 { // Skip the "goroutine N [running]:" header
+		fn := strings.TrimSpace(lines[i])
+		if open := strings.LastIndex(fn, "("); open > 0 {
+			fn = fn[:open]
+		}
+		if strings.HasPrefix(fn, "runtime/debug.") || fn == "panic" || strings.HasPrefix(fn, "runtime.") {
+			continue
+		}
+		loc := strings.TrimSpace(lines[i+1])
+		if offset := strings.LastIndex(loc, " +0x"); offset > 0 {
+			loc = loc[:offset]
+		}
+		frames = append(frames, fn+" "+path.Base(loc))
+	}
+	return frames
+}
+
+// RequestIDHeader echoes the request ID assigned by chi's RequestID middleware
+// as an X-Request-Id response header, so it reaches clients on every response,
+// including errors.
+func RequestIDHeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := chimw.GetReqID(r.Context()); id != "" {
+			w.Header().Set(chimw.RequestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // SecurityHeaders adds security headers including Content-Security-Policy.
 func SecurityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
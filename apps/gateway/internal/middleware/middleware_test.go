@@ -0,0 +1,518 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimw "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/kyros-praxis/gateway/internal/auth"
+	"github.com/kyros-praxis/gateway/internal/config"
+	"github.com/kyros-praxis/gateway/internal/models"
+	"github.com/kyros-praxis/gateway/internal/observability"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRateLimitHitsRecordedByRoutePattern(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		router  func() (chi.Router, func())
+		method  string
+		paths   []string
+		pattern string
+	}{
+		{
+			name: "global limiter",
+			router: func() (chi.Router, func()) {
+				rl := NewRateLimiter(1, "/v1", auth.New(&config.Config{}, nil).ClientIP)
+				r := chi.NewRouter()
+				r.Use(rl.Middleware)
+				r.Get("/projects/{id}", ok)
+				return r, rl.Stop
+			},
+			method:  http.MethodGet,
+			paths:   []string{"/projects/a", "/projects/b"},
+			pattern: "/projects/{id}",
+		},
+		{
+			name: "mfa limiter",
+			router: func() (chi.Router, func()) {
+				ml := NewMFALimiter(auth.New(&config.Config{}, nil).ClientIP)
+				r := chi.NewRouter()
+				r.Route("/auth", func(r chi.Router) {
+					r.With(ml.Middleware).Post("/mfa/verify", ok)
+				})
+				return r, ml.Stop
+			},
+			method:  http.MethodPost,
+			paths:   []string{"/auth/mfa/verify", "/auth/mfa/verify", "/auth/mfa/verify", "/auth/mfa/verify", "/auth/mfa/verify", "/auth/mfa/verify"},
+			pattern: "/auth/mfa/verify",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, stop := tt.router()
+			defer stop()
+
+			counter := observability.Metrics.RateLimitHits.WithLabelValues(tt.pattern)
+			before := testutil.ToFloat64(counter)
+
+			var throttled int
+			for _, path := range tt.paths {
+				req := httptest.NewRequest(tt.method, path, nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, req)
+				if rec.Code == http.StatusTooManyRequests {
+					throttled++
+				}
+			}
+
+			// Only the last request exceeds the limit
+			if throttled != 1 {
+				t.Fatalf("throttled = %d, want 1", throttled)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Fatalf("rate limit hits for %q increased by %v, want 1", tt.pattern, got)
+			}
+		})
+	}
+}
+
+func TestHasDirective(t *testing.T) {
+	tests := []struct {
+		header    string
+		directive string
+		want      bool
+	}{
+		{"", "no-cache", false},
+		{"no-cache", "no-cache", true},
+		{"max-age=0, No-Store", "no-store", true},
+		{"no-cache-please", "no-cache", false},
+		{"private", "no-store", false},
+	}
+	for _, tt := range tests {
+		if got := hasDirective(tt.header, tt.directive); got != tt.want {
+			t.Errorf("hasDirective(%q, %q) = %v, want %v", tt.header, tt.directive, got, tt.want)
+		}
+	}
+}
+
+func TestNilResponseCachePassesThrough(t *testing.T) {
+	var c *ResponseCache
+	called := false
+	h := c.Middleware("projects")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/projects", nil))
+	if !called {
+		t.Fatal("nil cache did not call the next handler")
+	}
+}
+
+func TestStreamingOutlivesServerWriteTimeout(t *testing.T) {
+	// Scaled down: a 90s stream against a 60s server timeout behaves the
+	// same as a 600ms stream against a 200ms one.
+	const chunks = 12
+	stream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		for i := 0; i < chunks; i++ {
+			io.WriteString(w, "chunk\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+
+	r := chi.NewRouter()
+	r.Use(Logger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	r.Get("/plain", stream)
+	r.With(Streaming(0)).Get("/stream", stream)
+
+	srv := httptest.NewUnstartedServer(r)
+	srv.Config.WriteTimeout = 200 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	read := func(path string) (int, error) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return strings.Count(string(body), "chunk"), err
+	}
+
+	if n, err := read("/stream"); err != nil || n != chunks {
+		t.Errorf("streaming route: got %d chunks (err %v), want %d", n, err, chunks)
+	}
+	if n, _ := read("/plain"); n == chunks {
+		t.Error("unmarked route was not cut off by the server write timeout")
+	}
+}
+
+func TestRequestLoggerAddsRequestIdentity(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	user := &models.User{ID: uuid.New()}
+
+	withUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), auth.UserContextKey, user)))
+		})
+	}
+	handler := chimw.RequestID(withUser(RequestLogger(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observability.LoggerFrom(r.Context(), nil).Info("handled")
+	}))))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if line["request_id"] == nil || line["request_id"] == "" {
+		t.Error("log line has no request_id")
+	}
+	if line["user_id"] != user.ID.String() {
+		t.Errorf("user_id = %v, want %s", line["user_id"], user.ID)
+	}
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    map[string]int
+		wantErr bool
+	}{
+		{"valid", []string{"/auth/login=10", " /projects/ = 200 "}, map[string]int{"/auth/login": 10, "/projects": 200}, false},
+		{"missing limit", []string{"/auth/login"}, nil, true},
+		{"relative pattern", []string{"auth/login=10"}, nil, true},
+		{"zero limit", []string{"/auth/login=0"}, nil, true},
+		{"non-numeric limit", []string{"/auth/login=ten"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRouteLimits(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for pattern, limit := range tt.want {
+				if got[pattern] != limit {
+					t.Errorf("%s = %d, want %d", pattern, got[pattern], limit)
+				}
+			}
+		})
+	}
+}
+
+func TestMFALimiterKeysOnClientAddress(t *testing.T) {
+	authService := auth.New(&config.Config{TrustedProxyCIDRs: []string{"10.1.0.0/16"}}, nil)
+	ml := NewMFALimiter(authService.ClientIP)
+	defer ml.Stop()
+
+	handler := ml.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	verify := func(remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/mfa/verify", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// New source ports and forged headers from one client share its window
+	for i := 0; i < 5; i++ {
+		verify("203.0.113.7:"+strconv.Itoa(1000+i), "198.51.100."+strconv.Itoa(i))
+	}
+	if got := verify("203.0.113.7:2000", "198.51.100.99"); got != http.StatusTooManyRequests {
+		t.Errorf("sixth attempt: status = %d, want 429", got)
+	}
+	if got := verify("203.0.113.8:1000", ""); got != http.StatusUnauthorized {
+		t.Errorf("another client: status = %d, want 401", got)
+	}
+}
+
+func TestRouteLimitOverridesGlobal(t *testing.T) {
+	rl := NewRateLimiter(3, "/v1", auth.New(&config.Config{}, nil).ClientIP)
+	defer rl.Stop()
+	rl.SetRouteLimits(map[string]int{"/auth/login": 1})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Post("/auth/login", ok)
+	r.Get("/projects", ok)
+
+	send := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = "10.0.0.2:1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := send(http.MethodPost, "/auth/login"); code != http.StatusOK {
+		t.Fatalf("first login = %d, want 200", code)
+	}
+	if code := send(http.MethodPost, "/auth/login"); code != http.StatusTooManyRequests {
+		t.Fatalf("second login = %d, want 429", code)
+	}
+	// Other routes keep the global limit and aren't charged for login attempts
+	for i := 0; i < 3; i++ {
+		if code := send(http.MethodGet, "/projects"); code != http.StatusOK {
+			t.Fatalf("projects request %d = %d, want 200", i+1, code)
+		}
+	}
+	if code := send(http.MethodGet, "/projects"); code != http.StatusTooManyRequests {
+		t.Fatalf("fourth projects request = %d, want 429", code)
+	}
+}
+
+func TestRouteLimitSharedAcrossAPIVersions(t *testing.T) {
+	rl := NewRateLimiter(100, "/v1", auth.New(&config.Config{}, nil).ClientIP)
+	defer rl.Stop()
+	rl.SetRouteLimits(map[string]int{"/auth/login": 2, "/v1/auth/login": 2})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Post("/auth/login", ok)
+	r.Route("/v1", func(r chi.Router) {
+		r.Post("/auth/login", ok)
+	})
+
+	send := func(path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "10.0.0.5:1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Alternating between the two forms must not double the allowance
+	for i, path := range []string{"/v1/auth/login", "/auth/login"} {
+		if code := send(path); code != http.StatusOK {
+			t.Fatalf("request %d to %s = %d, want 200", i+1, path, code)
+		}
+	}
+	if code := send("/v1/auth/login"); code != http.StatusTooManyRequests {
+		t.Fatalf("third request = %d, want 429", code)
+	}
+	if code := send("/auth/login"); code != http.StatusTooManyRequests {
+		t.Fatalf("fourth request = %d, want 429", code)
+	}
+
+	usage := rl.Usage("10.0.0.5")
+	if len(usage) != 1 || usage[0].Route != "/auth/login" || usage[0].Count != 2 || usage[0].Limit != 2 {
+		t.Errorf("Usage = %+v, want one /auth/login window with count 2 of 2", usage)
+	}
+}
+
+func TestRateLimiterUsageAndReset(t *testing.T) {
+	rl := NewRateLimiter(2, "/v1", auth.New(&config.Config{}, nil).ClientIP)
+	defer rl.Stop()
+	rl.SetRouteLimits(map[string]int{"/auth/login": 5})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Get("/projects", ok)
+	r.Post("/auth/login", ok)
+
+	send := func(method, path, remote string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	send(http.MethodGet, "/projects", "10.0.0.3:1111")
+	send(http.MethodGet, "/projects", "10.0.0.3:1111")
+	send(http.MethodPost, "/auth/login", "10.0.0.3:1111")
+	send(http.MethodGet, "/projects", "10.0.0.4:2222")
+
+	counts := make(map[string]ClientUsage)
+	for _, u := range rl.Usage("10.0.0.3") {
+		counts[u.Route] = u
+	}
+	if len(counts) != 2 {
+		t.Fatalf("got %d windows for 10.0.0.3, want 2", len(counts))
+	}
+	if g := counts[""]; g.Count != 2 || g.Limit != 2 || g.ResetAt.IsZero() {
+		t.Errorf("global window = %+v, want count 2 of 2 with a reset time", g)
+	}
+	if l := counts["/auth/login"]; l.Count != 1 || l.Limit != 5 {
+		t.Errorf("login window = %+v, want count 1 of 5", l)
+	}
+
+	if code := send(http.MethodGet, "/projects", "10.0.0.3:1111"); code != http.StatusTooManyRequests {
+		t.Fatalf("third request = %d, want 429", code)
+	}
+	if cleared := rl.Reset("10.0.0.3"); cleared != 2 {
+		t.Errorf("Reset cleared %d windows, want 2", cleared)
+	}
+	if code := send(http.MethodGet, "/projects", "10.0.0.3:1111"); code != http.StatusOK {
+		t.Errorf("request after reset = %d, want 200", code)
+	}
+	if len(rl.Usage("10.0.0.4")) != 1 {
+		t.Error("reset affected another client")
+	}
+}
+
+func TestRateLimiterIgnoresSpoofedForwardedFor(t *testing.T) {
+	authService := auth.New(&config.Config{TrustedProxyCIDRs: []string{"10.1.0.0/16"}}, nil)
+	rl := NewRateLimiter(2, "/v1", authService.ClientIP)
+	defer rl.Stop()
+
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Get("/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	send := func(remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+		req.RemoteAddr = remote
+		req.Header.Set("X-Forwarded-For", forwarded)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A direct client can't open a fresh window with a new header per request
+	send("203.0.113.7:1", "198.51.100.1")
+	send("203.0.113.7:1", "198.51.100.2")
+	if got := send("203.0.113.7:1", "198.51.100.3"); got != http.StatusTooManyRequests {
+		t.Errorf("direct client with new X-Forwarded-For: status = %d, want 429", got)
+	}
+
+	// Behind the trusted proxy, entries the client prepends are ignored too
+	send("10.1.0.1:1", "198.51.100.4, 203.0.113.8")
+	send("10.1.0.1:1", "198.51.100.5, 203.0.113.8")
+	if got := send("10.1.0.1:1", "198.51.100.6, 203.0.113.8"); got != http.StatusTooManyRequests {
+		t.Errorf("proxied client with prepended entries: status = %d, want 429", got)
+	}
+
+	// Usage matches the resolved address exactly, not a substring of the header
+	if got := len(rl.Usage("203.0.113.8")); got != 1 {
+		t.Errorf("Usage(203.0.113.8) returned %d windows, want 1", got)
+	}
+	if got := len(rl.Usage("198.51.100.4")); got != 0 {
+		t.Errorf("Usage(198.51.100.4) returned %d windows, want 0", got)
+	}
+}
+
+func TestRateLimitWarningBeforeThrottling(t *testing.T) {
+	rl := NewRateLimiter(5, "/v1", auth.New(&config.Config{}, nil).ClientIP)
+	defer rl.Stop()
+	rl.SetWarnPercent(80)
+
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Get("/projects", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// 80% of 5 is the 4th request; the 6th is throttled
+	for i, wantWarn := range []bool{false, false, false, true, true} {
+		req := httptest.NewRequest(http.MethodGet, "/projects", nil)
+		req.RemoteAddr = "10.0.0.9:1111"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Warning") != ""; got != wantWarn {
+			t.Errorf("request %d: warning = %v, want %v", i+1, got, wantWarn)
+		}
+		if got, want := rec.Header().Get("X-RateLimit-Remaining"), strconv.Itoa(4-i); got != want {
+			t.Errorf("request %d: remaining = %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestRecovererLogsStackAndRoute(t *testing.T) {
+	for _, exposeStack := range []bool{false, true} {
+		t.Run("exposeStack="+strconv.FormatBool(exposeStack), func(t *testing.T) {
+			var logs bytes.Buffer
+			log := slog.New(slog.NewJSONHandler(&logs, nil))
+
+			r := chi.NewRouter()
+			r.Use(chimw.RequestID, Recoverer(log, exposeStack))
+			r.Get("/projects/{id}", func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/projects/42", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", rec.Code)
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("log is not JSON: %v", err)
+			}
+			if entry["route"] != "/projects/{id}" || entry["request_id"] == "" {
+				t.Errorf("log route/request_id = %v/%v", entry["route"], entry["request_id"])
+			}
+			if stack, _ := entry["stack"].(string); !strings.Contains(stack, "middleware_test.go") {
+				t.Errorf("log stack missing handler frame: %q", stack)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body is not JSON: %v", err)
+			}
+			if body["error"] != "internal_error" {
+				t.Errorf("error = %v, want internal_error", body["error"])
+			}
+			stack, hasStack := body["stack"].([]interface{})
+			if hasStack != exposeStack || (body["panic"] != nil) != exposeStack {
+				t.Fatalf("body = %v, want stack exposed: %v", body, exposeStack)
+			}
+			if exposeStack {
+				if body["panic"] != "boom" || len(stack) == 0 {
+					t.Errorf("panic/stack = %v/%v", body["panic"], stack)
+				}
+				for _, frame := range stack {
+					s := frame.(string)
+					if loc := s[strings.LastIndex(s, " ")+1:]; strings.Contains(loc, "/") || strings.Contains(s, "0x") || strings.HasPrefix(s, "runtime") {
+						t.Errorf("frame not sanitized: %q", s)
+					}
+				}
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,13 +19,30 @@ type User struct {
 	MFAEnabled   bool      `json:"mfa_enabled"`
 	MFASecret    *string   `json:"-"` // Never expose
 	BackupCodes  []string  `json:"-"` // Never expose
-	CreatedAt    time.Time `json:"created_at"`
+	// OrgID is the user's organization; nil users share the untenanted
+	// namespace. OrgRole is one of the OrgRole constants.
+	OrgID   *uuid.UUID `json:"org_id,omitempty"`
+	OrgRole string     `json:"org_role,omitempty"`
+	// EmailVerifiedAt is nil until the email is confirmed (OAuth emails are
+	// verified by the provider).
+	EmailVerifiedAt *time.Time `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// MarshalJSON formats timestamps with TimeFormat.
+func (u User) MarshalJSON() ([]byte, error) {
+	type user User
+	return json.Marshal(struct {
+		user
+		CreatedAt JSONTime `json:"created_at"`
+	}{user(u), JSONTime(u.CreatedAt)})
 }
 
 // Project represents a multi-agent project.
 type Project struct {
 	ID          uuid.UUID  `json:"id"`
 	UserID      *uuid.UUID `json:"user_id,omitempty"`
+	OrgID       *uuid.UUID `json:"org_id,omitempty"`
 	Name        string     `json:"name"`
 	Description string     `json:"description"`
 	Status      string     `json:"status"`
@@ -32,6 +50,35 @@ type Project struct {
 	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// MarshalJSON formats timestamps with TimeFormat.
+func (p Project) MarshalJSON() ([]byte, error) {
+	type project Project
+	return json.Marshal(struct {
+		project
+		CreatedAt JSONTime `json:"created_at"`
+		UpdatedAt JSONTime `json:"updated_at"`
+	}{project(p), JSONTime(p.CreatedAt), JSONTime(p.UpdatedAt)})
+}
+
+// AdminProject is a project as listed to platform admins, with its owner's
+// username; the username is empty for projects without an owner.
+type AdminProject struct {
+	Project
+	OwnerUsername string `json:"owner_username,omitempty"`
+}
+
+// MarshalJSON formats timestamps with TimeFormat. Without it the embedded
+// Project's MarshalJSON would drop OwnerUsername.
+func (p AdminProject) MarshalJSON() ([]byte, error) {
+	type project Project
+	return json.Marshal(struct {
+		project
+		CreatedAt     JSONTime `json:"created_at"`
+		UpdatedAt     JSONTime `json:"updated_at"`
+		OwnerUsername string   `json:"owner_username,omitempty"`
+	}{project(p.Project), JSONTime(p.CreatedAt), JSONTime(p.UpdatedAt), p.OwnerUsername})
+}
+
 // Task represents a task within a project.
 type Task struct {
 	ID           uuid.UUID  `json:"id"`
@@ -42,10 +89,72 @@ type Task struct {
 	Status       string     `json:"status"`
 	CrewRunID    *uuid.UUID `json:"crew_run_id,omitempty"`
 	Dependencies []string   `json:"dependencies,omitempty"`
+	DueAt        *time.Time `json:"due_at,omitempty"`
+	Overdue      bool       `json:"overdue"` // Computed; see IsOverdue
+	Archived     bool       `json:"archived"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
+// MarshalJSON formats timestamps with TimeFormat.
+func (t Task) MarshalJSON() ([]byte, error) {
+	type task Task
+	return json.Marshal(struct {
+		task
+		DueAt     *JSONTime `json:"due_at,omitempty"`
+		CreatedAt JSONTime  `json:"created_at"`
+		UpdatedAt JSONTime  `json:"updated_at"`
+	}{task(t), jsonTimePtr(t.DueAt), JSONTime(t.CreatedAt), JSONTime(t.UpdatedAt)})
+}
+
+// IsOverdue reports whether the task is past its due date and not completed.
+// Keep in sync with taskOverdueCond in the db package.
+func (t *Task) IsOverdue(now time.Time) bool {
+	return t.DueAt != nil && t.DueAt.Before(now) && t.Status != TaskStatusCompleted
+}
+
+// MemoryEvent is an event persisted in the memory_events table.
+type MemoryEvent struct {
+	ID          int64           `json:"id"`
+	ProjectID   uuid.UUID       `json:"project_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	PublishedAt time.Time       `json:"published_at"`
+}
+
+// MarshalJSON formats timestamps with TimeFormat.
+func (e MemoryEvent) MarshalJSON() ([]byte, error) {
+	type memoryEvent MemoryEvent
+	return json.Marshal(struct {
+		memoryEvent
+		PublishedAt JSONTime `json:"published_at"`
+	}{memoryEvent(e), JSONTime(e.PublishedAt)})
+}
+
+// AuditEvent is a security audit record persisted in the audit_events table.
+type AuditEvent struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	EventType  string          `json:"event_type"`
+	Actor      string          `json:"actor"`
+	Resource   string          `json:"resource"`
+	Action     string          `json:"action"`
+	Outcome    string          `json:"outcome"`
+	IPAddress  string          `json:"ip_address"`
+	UserAgent  string          `json:"user_agent"`
+	RequestID  string          `json:"request_id"`
+	Details    json.RawMessage `json:"details,omitempty"`
+}
+
+// MarshalJSON formats timestamps with TimeFormat.
+func (e AuditEvent) MarshalJSON() ([]byte, error) {
+	type auditEvent AuditEvent
+	return json.Marshal(struct {
+		auditEvent
+		OccurredAt JSONTime `json:"occurred_at"`
+	}{auditEvent(e), JSONTime(e.OccurredAt)})
+}
+
 // ---- Request Types ----
 
 // RegisterRequest is the request body for user registration.
@@ -61,11 +170,28 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// MFAVerifyRequest is the request body for the second step of a login.
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
 // RefreshRequest is the request body for token refresh.
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// ChangePasswordRequest is the request body for changing the caller's password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// IntrospectRequest is the request body for token introspection.
+type IntrospectRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
 // CreateProjectRequest is the request body for creating a project.
 type CreateProjectRequest struct {
 	Name        string `json:"name" validate:"required,min=1,max=255"`
@@ -76,28 +202,32 @@ type CreateProjectRequest struct {
 type UpdateProjectRequest struct {
 	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=255"`
 	Description *string `json:"description,omitempty"`
-	Status      *string `json:"status,omitempty"`
+	Status      *string `json:"status,omitempty" validate:"omitempty,min=1,max=50"`
 }
 
 // CreateTaskRequest is the request body for creating a task.
 type CreateTaskRequest struct {
-	Title        string   `json:"title" validate:"required,min=1,max=255"`
-	Description  string   `json:"description"`
-	Priority     string   `json:"priority" validate:"omitempty,oneof=P0 P1 P2 P3"`
-	Dependencies []string `json:"dependencies"`
+	Title        string     `json:"title" validate:"required,min=1,max=255"`
+	Description  string     `json:"description"`
+	Priority     string     `json:"priority" validate:"omitempty,task_priority"`
+	Dependencies []string   `json:"dependencies"`
+	DueAt        *time.Time `json:"due_at,omitempty"` // Must be in the future
 }
 
 // UpdateTaskRequest is the request body for updating a task.
 type UpdateTaskRequest struct {
-	Title       *string `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
-	Description *string `json:"description,omitempty"`
-	Priority    *string `json:"priority,omitempty" validate:"omitempty,oneof=P0 P1 P2 P3"`
-	Status      *string `json:"status,omitempty"`
+	Title       *string    `json:"title,omitempty" validate:"omitempty,min=1,max=255"`
+	Description *string    `json:"description,omitempty"`
+	Priority    *string    `json:"priority,omitempty" validate:"omitempty,task_priority"`
+	Status      *string    `json:"status,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
 }
 
 // WorkflowGenerateRequest is the request to start workflow generation.
 type WorkflowGenerateRequest struct {
-	Prompt string `json:"prompt" validate:"required,min=10"`
+	Prompt   string `json:"prompt" validate:"required,min=10"`
+	Provider string `json:"provider,omitempty" validate:"omitempty,max=50"`          // Defaults to MODEL_PROVIDER
+	Model    string `json:"model,omitempty" validate:"omitempty,max=200,printascii"` // Defaults to the provider's model
 }
 
 // WorkflowApproveRequest is the request to approve a specification.
@@ -111,6 +241,26 @@ type WorkflowRefineRequest struct {
 	RefinementNotes string `json:"refinement_notes" validate:"required,min=10"`
 }
 
+// ReplayEventsRequest is the request body for replaying persisted events.
+type ReplayEventsRequest struct {
+	ProjectID string     `json:"project_id" validate:"required,uuid"`
+	Since     time.Time  `json:"since" validate:"required"`
+	Until     *time.Time `json:"until,omitempty"`
+	DryRun    bool       `json:"dry_run"`
+	MaxCount  int        `json:"max_count" validate:"omitempty,min=1"`
+}
+
+// BatchRequest is the request body for POST /batch.
+type BatchRequest struct {
+	Requests []BatchSubRequest `json:"requests" validate:"required,min=1,dive"`
+}
+
+// BatchSubRequest is one read-only request within a batch.
+type BatchSubRequest struct {
+	Method string `json:"method"` // GET or HEAD; defaults to GET
+	Path   string `json:"path" validate:"required,startswith=/"`
+}
+
 // ---- Response Types ----
 
 // TokenResponse is the response for authentication endpoints.
@@ -119,6 +269,24 @@ type TokenResponse struct {
 	TokenType    string `json:"token_type"`
 	RefreshToken string `json:"refresh_token,omitempty"`
 	ExpiresIn    int    `json:"expires_in"`
+	SessionID    string `json:"session_id,omitempty"` // Send back as X-Session-ID; empty without Redis
+}
+
+// MFAChallengeResponse answers a password login for a user with MFA. The
+// client sends MFAToken with a code to POST /auth/mfa/verify for real tokens.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// IntrospectResponse describes a token's state (RFC 7662). Inactive tokens
+// carry no other fields, so callers learn nothing about why a token failed.
+type IntrospectResponse struct {
+	Active bool   `json:"active"`
+	Sub    string `json:"sub,omitempty"`
+	Exp    int64  `json:"exp,omitempty"`
+	Scope  string `json:"scope,omitempty"`
 }
 
 // UserResponse is the public user information.
@@ -128,21 +296,124 @@ type UserResponse struct {
 	Email     string    `json:"email"`
 	Role      string    `json:"role"`
 	Active    bool      `json:"active"`
-	CreatedAt string    `json:"created_at"`
+	CreatedAt string    `json:"created_at,omitempty"` // FormatTime; empty when served from token claims
+
+	LinkedProviders []string `json:"linked_providers,omitempty"` // OAuth providers that sign in to this account
 }
 
 // HealthResponse is the response for the health endpoint.
 type HealthResponse struct {
-	Status   string                 `json:"status"`
-	Env      string                 `json:"env"`
-	Features map[string]interface{} `json:"features,omitempty"`
+	Status     string                 `json:"status"`
+	Env        string                 `json:"env"`
+	APIVersion string                 `json:"api_version"`
+	Features   map[string]interface{} `json:"features,omitempty"`
+}
+
+// ReadinessResponse is the response for the readiness endpoint.
+type ReadinessResponse struct {
+	Status       string             `json:"status"` // healthy, degraded, or unhealthy
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// DependencyStatus reports one backing service in a readiness check.
+type DependencyStatus struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"` // up or down
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// RuntimeResponse is a snapshot of the gateway process for GET /admin/runtime.
+type RuntimeResponse struct {
+	Goroutines     int          `json:"goroutines"`
+	HeapAllocBytes uint64       `json:"heap_alloc_bytes"`
+	HeapSysBytes   uint64       `json:"heap_sys_bytes"`
+	HeapObjects    uint64       `json:"heap_objects"`
+	GC             GCStats      `json:"gc"`
+	DBPool         *DBPoolStats `json:"db_pool,omitempty"`
+}
+
+// GCStats summarizes garbage collection since the process started.
+type GCStats struct {
+	NumGC        uint32     `json:"num_gc"`
+	PauseTotalMS float64    `json:"pause_total_ms"`
+	LastPauseMS  float64    `json:"last_pause_ms"`
+	LastGC       *time.Time `json:"last_gc,omitempty"`
+	CPUFraction  float64    `json:"cpu_fraction"`
+}
+
+// DBPoolStats describes the Postgres connection pool.
+type DBPoolStats struct {
+	AcquiredConns        int32   `json:"acquired_conns"`
+	IdleConns            int32   `json:"idle_conns"`
+	TotalConns           int32   `json:"total_conns"`
+	MaxConns             int32   `json:"max_conns"`
+	AcquireCount         int64   `json:"acquire_count"`
+	EmptyAcquireCount    int64   `json:"empty_acquire_count"` // Acquires that had to wait for a connection
+	CanceledAcquireCount int64   `json:"canceled_acquire_count"`
+	AcquireWaitMS        float64 `json:"acquire_wait_ms"` // Total time spent waiting
+}
+
+// BatchResponse holds the responses to a batch, in request order.
+type BatchResponse struct {
+	Responses []BatchSubResponse `json:"responses"`
+}
+
+// BatchSubResponse is the response to one sub-request. Body is the JSON the
+// route returned, or a JSON string for any other content.
+type BatchSubResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
 }
 
 // ErrorResponse is the standard error response format.
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
-	Details string `json:"details,omitempty"`
+	Error      string `json:"error"`
+	Message    string `json:"message,omitempty"`
+	Details    string `json:"details,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"` // Seconds, for errors that lift by themselves
+}
+
+// Envelope is the optional wrapper for successful responses.
+type Envelope struct {
+	Data interface{}   `json:"data"`
+	Meta *EnvelopeMeta `json:"meta,omitempty"`
+}
+
+// EnvelopeMeta carries request metadata alongside enveloped data.
+type EnvelopeMeta struct {
+	RequestID  string          `json:"request_id,omitempty"`
+	Pagination *PaginationMeta `json:"pagination,omitempty"`
+}
+
+// PaginationMeta describes the page returned by a list endpoint.
+type PaginationMeta struct {
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PaginatedResponse is the standard response for list endpoints.
+type PaginatedResponse[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasMore    bool   `json:"has_more"` // Whether a page follows this one
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// PaginationMeta returns the page details for the response envelope.
+func (p PaginatedResponse[T]) PaginationMeta() *PaginationMeta {
+	return &PaginationMeta{
+		Total:      p.Total,
+		Limit:      p.Limit,
+		Offset:     p.Offset,
+		HasMore:    p.HasMore,
+		NextCursor: p.NextCursor,
+	}
 }
 
 // ValidationError represents a validation error for user-friendly messages.
@@ -184,4 +455,5 @@ type ProviderStatus struct {
 	Configured    bool     `json:"configured"`
 	MissingConfig []string `json:"missing_config"`
 	DefaultModel  string   `json:"default_model"`
+	Models        []string `json:"models"`
 }
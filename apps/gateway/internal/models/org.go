@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization roles held by users within their organization. They are
+// separate from the platform-wide User.Role.
+const (
+	OrgRoleMember = "member"
+	OrgRoleAdmin  = "admin"
+)
+
+// Organization is a tenant: its users only ever see its projects.
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MarshalJSON formats timestamps with TimeFormat.
+func (o Organization) MarshalJSON() ([]byte, error) {
+	type organization Organization
+	return json.Marshal(struct {
+		organization
+		CreatedAt JSONTime `json:"created_at"`
+	}{organization(o), JSONTime(o.CreatedAt)})
+}
+
+// CreateOrganizationRequest is the request body for creating an organization.
+// AdminEmail names an existing user, not yet in any organization, who becomes
+// its first org admin.
+type CreateOrganizationRequest struct {
+	Name       string `json:"name" validate:"required,min=1,max=255"`
+	Slug       string `json:"slug" validate:"required,min=2,max=100,hostname_rfc1123"`
+	AdminEmail string `json:"admin_email" validate:"required,email"`
+}
+
+// AddOrgMemberRequest is the request body for adding a user to the caller's
+// organization, or changing the role of an existing member.
+type AddOrgMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"omitempty,oneof=member admin"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"slices"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// TaskPriorities are the allowed task priorities, most urgent first.
+var TaskPriorities = []string{"P0", "P1", "P2", "P3"}
+
+// DefaultTaskPriority is used when a task is created without a priority and
+// DEFAULT_TASK_PRIORITY is not set.
+const DefaultTaskPriority = "P2"
+
+// IsValidPriority reports whether p is one of TaskPriorities.
+func IsValidPriority(p string) bool {
+	return slices.Contains(TaskPriorities, p)
+}
+
+// RegisterValidators adds the custom validation tags used by the request
+// types to v. Use `validate:"task_priority"` for priority fields.
+func RegisterValidators(v *validator.Validate) {
+	_ = v.RegisterValidation("task_priority", func(fl validator.FieldLevel) bool {
+		return IsValidPriority(fl.Field().String())
+	})
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ProviderModels maps each LLM provider to the models it can serve. An entry
+// ending in "*" matches any model with that prefix.
+type ProviderModels map[string][]string
+
+// DefaultProviderModels returns the built-in model lists, which include each
+// provider's default model.
+func DefaultProviderModels() ProviderModels {
+	return ProviderModels{
+		"openrouter": {
+			"openai/gpt-4o-mini",
+			"openai/gpt-4o",
+			"anthropic/claude-3.5-sonnet",
+			"anthropic/claude-3-haiku",
+			"google/gemini-pro-1.5",
+			"meta-llama/llama-3.1-70b-instruct",
+		},
+		"openai":  {"gpt-4o-mini", "gpt-4o", "gpt-4-turbo", "o1-mini"},
+		"vertex":  {"gemini-1.5-pro", "gemini-1.5-flash"},
+		"bedrock": {"anthropic.claude-3-sonnet-20240229-v1:0", "anthropic.claude-3-haiku-20240307-v1:0", "amazon.titan-text-express-v1"},
+		"azure":   {"gpt-4o", "gpt-4o-mini"},
+	}
+}
+
+// Extend adds models given as "provider=model1|model2" entries, e.g.
+// "openai=gpt-4.1|o3-*". New providers may be introduced this way.
+func (p ProviderModels) Extend(entries []string) error {
+	for _, entry := range entries {
+		provider, list, ok := strings.Cut(entry, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !ok || provider == "" || strings.TrimSpace(list) == "" {
+			return fmt.Errorf("invalid provider models %q: want provider=model1|model2", entry)
+		}
+		for _, model := range strings.Split(list, "|") {
+			model = strings.TrimSpace(model)
+			if model == "" {
+				return fmt.Errorf("invalid provider models %q: empty model", entry)
+			}
+			if !slices.Contains(p[provider], model) {
+				p[provider] = append(p[provider], model)
+			}
+		}
+	}
+	return nil
+}
+
+// Supports reports whether provider can serve model. A "provider/" prefix on
+// model is ignored, and a bare name matches a namespaced entry, so
+// "gpt-4o-mini" matches OpenRouter's "openai/gpt-4o-mini".
+func (p ProviderModels) Supports(provider, model string) bool {
+	model = strings.TrimPrefix(model, provider+"/")
+	if model == "" {
+		return false
+	}
+	for _, entry := range p[provider] {
+		if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+			continue
+		}
+		if entry == model || strings.HasSuffix(entry, "/"+model) {
+			return true
+		}
+	}
+	return false
+}
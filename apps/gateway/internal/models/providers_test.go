@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestProviderModels(t *testing.T) {
+	pm := DefaultProviderModels()
+	if err := pm.Extend([]string{"openai=gpt-4.1|o3-*", " Groq = llama-3.1-8b-instant"}); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+
+	tests := []struct {
+		provider, model string
+		want            bool
+	}{
+		{"openai", "gpt-4o-mini", true},
+		{"openai", "gpt-4.1", true}, // added by Extend
+		{"openai", "o3-mini", true}, // wildcard
+		{"openai", "o3", false},     // wildcard needs the prefix
+		{"openai", "openai/gpt-4o", true},
+		{"openrouter", "gpt-4o-mini", true}, // bare name matches namespaced entry
+		{"openrouter", "openrouter/openai/gpt-4o-mini", true},
+		{"openrouter", "4o-mini", false},
+		{"groq", "llama-3.1-8b-instant", true},
+		{"vertex", "gpt-4o", false},
+		{"acme", "gpt-4o", false},
+		{"openai", "", false},
+	}
+	for _, tt := range tests {
+		if got := pm.Supports(tt.provider, tt.model); got != tt.want {
+			t.Errorf("Supports(%s, %s) = %v, want %v", tt.provider, tt.model, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"openai", "=gpt-4o", "openai=", "openai=gpt-4o|"} {
+		if err := DefaultProviderModels().Extend([]string{bad}); err == nil {
+			t.Errorf("Extend(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Task statuses. Tasks start queued; completed is terminal.
+const (
+	TaskStatusQueued    = "queued"
+	TaskStatusRunning   = "running"
+	TaskStatusCompleted = "completed"
+	TaskStatusFailed    = "failed"
+	TaskStatusCancelled = "cancelled"
+)
+
+// TaskTransitions maps each task status to the statuses it may move to. Every
+// status that appears as a key or a target is an allowed status.
+type TaskTransitions map[string][]string
+
+// DefaultTaskTransitions returns the built-in lifecycle: queued → running →
+// completed or failed, with cancellation before completion and retries of
+// failed or cancelled tasks.
+func DefaultTaskTransitions() TaskTransitions {
+	return TaskTransitions{
+		TaskStatusQueued:    {TaskStatusRunning, TaskStatusCancelled},
+		TaskStatusRunning:   {TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled},
+		TaskStatusFailed:    {TaskStatusQueued},
+		TaskStatusCancelled: {TaskStatusQueued},
+		TaskStatusCompleted: {},
+	}
+}
+
+// IsValid reports whether status is an allowed task status.
+func (t TaskTransitions) IsValid(status string) bool {
+	if _, ok := t[status]; ok {
+		return true
+	}
+	for _, targets := range t {
+		if slices.Contains(targets, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a task may move from one status to another.
+// Keeping the current status is always allowed.
+func (t TaskTransitions) Allows(from, to string) bool {
+	return from == to || slices.Contains(t[from], to)
+}
+
+// Extend adds transitions given as "from=to1|to2" entries, e.g.
+// "completed=queued" to allow reopening completed tasks. New statuses may
+// be introduced this way.
+func (t TaskTransitions) Extend(entries []string) error {
+	for _, entry := range entries {
+		from, targets, ok := strings.Cut(entry, "=")
+		from = strings.TrimSpace(from)
+		if !ok || from == "" || strings.TrimSpace(targets) == "" {
+			return fmt.Errorf("invalid task transition %q: want from=to1|to2", entry)
+		}
+		for _, to := range strings.Split(targets, "|") {
+			to = strings.TrimSpace(to)
+			if to == "" {
+				return fmt.Errorf("invalid task transition %q: empty target status", entry)
+			}
+			if !slices.Contains(t[from], to) {
+				t[from] = append(t[from], to)
+			}
+		}
+	}
+	return nil
+}
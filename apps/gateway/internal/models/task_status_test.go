@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestTaskTransitions(t *testing.T) {
+	tr := DefaultTaskTransitions()
+	if err := tr.Extend([]string{"completed=queued|archived"}); err != nil {
+		t.Fatalf("Extend: %v", err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{TaskStatusQueued, TaskStatusRunning, true},
+		{TaskStatusRunning, TaskStatusCompleted, true},
+		{TaskStatusRunning, TaskStatusFailed, true},
+		{TaskStatusQueued, TaskStatusCompleted, false},
+		{TaskStatusFailed, TaskStatusRunning, false},
+		{TaskStatusRunning, TaskStatusRunning, true},
+		{TaskStatusCompleted, TaskStatusQueued, true}, // added by Extend
+		{TaskStatusCompleted, "archived", true},
+		{TaskStatusQueued, "bogus", false},
+	}
+	for _, tt := range tests {
+		if got := tr.Allows(tt.from, tt.to); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	if !tr.IsValid("archived") || tr.IsValid("bogus") {
+		t.Error("IsValid should accept extended statuses and reject unknown ones")
+	}
+	for _, bad := range []string{"queued", "=running", "queued=", "queued=running|"} {
+		if err := DefaultTaskTransitions().Extend([]string{bad}); err == nil {
+			t.Errorf("Extend(%q) should fail", bad)
+		}
+	}
+}
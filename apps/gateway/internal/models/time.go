@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// TimeFormat is the layout of every timestamp the API returns: RFC 3339 in
+// UTC with second precision.
+const TimeFormat = time.RFC3339
+
+// FormatTime formats t for an API response.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// JSONTime is a time.Time that marshals with TimeFormat. Models keep
+// time.Time fields for scanning and arithmetic and switch to JSONTime only
+// in their MarshalJSON methods.
+type JSONTime time.Time
+
+// MarshalJSON implements json.Marshaler.
+func (t JSONTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(FormatTime(time.Time(t)))
+}
+
+// jsonTimePtr converts an optional time, keeping nil for omitempty.
+func jsonTimePtr(t *time.Time) *JSONTime {
+	if t == nil {
+		return nil
+	}
+	jt := JSONTime(*t)
+	return &jt
+}
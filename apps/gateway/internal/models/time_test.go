@@ -0,0 +1,75 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampsUseOneFormat(t *testing.T) {
+	// Sub-second precision and a non-UTC zone must not leak into responses
+	ts := time.Date(2026, 3, 4, 5, 6, 7, 891011121, time.FixedZone("CET", 3600))
+	const want = "2026-03-04T04:06:07Z"
+
+	tests := []struct {
+		name   string
+		value  any
+		fields []string
+	}{
+		{"task", Task{DueAt: &ts, CreatedAt: ts, UpdatedAt: ts}, []string{"due_at", "created_at", "updated_at"}},
+		{"project", Project{CreatedAt: ts, UpdatedAt: ts}, []string{"created_at", "updated_at"}},
+		{"admin project", AdminProject{Project: Project{CreatedAt: ts, UpdatedAt: ts}}, []string{"created_at", "updated_at"}},
+		{"user", User{CreatedAt: ts}, []string{"created_at"}},
+		{"user response", UserResponse{CreatedAt: FormatTime(ts)}, []string{"created_at"}},
+		{"memory event", MemoryEvent{PublishedAt: ts}, []string{"published_at"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal failed: %v", err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(raw, &got); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			for _, field := range tt.fields {
+				if got[field] != want {
+					t.Errorf("%s = %v, want %s", field, got[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestTaskOmitsMissingDueAt(t *testing.T) {
+	raw, err := json.Marshal(Task{})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if _, ok := got["due_at"]; ok {
+		t.Error("due_at present for a task without a due date")
+	}
+	if _, ok := got["overdue"]; !ok {
+		t.Error("non-time fields missing from output")
+	}
+}
+
+func TestAdminProjectKeepsOwnerUsername(t *testing.T) {
+	raw, err := json.Marshal(AdminProject{Project: Project{Name: "Demo"}, OwnerUsername: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["owner_username"] != "alice" || got["name"] != "Demo" {
+		t.Errorf("got %s, want name and owner_username", raw)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/kyros-praxis/gateway/internal/models"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -22,6 +24,23 @@ var Metrics = struct {
 	LLMLatency      *prometheus.HistogramVec
 	SessionsActive  prometheus.Gauge
 	RateLimitHits   *prometheus.CounterVec
+	EventsDLQDepth  prometheus.Gauge
+	ProxyCancelled  prometheus.Counter
+	ProxyRequests   *prometheus.CounterVec
+	ProxyDuration   prometheus.Histogram
+	ProxyErrors     *prometheus.CounterVec
+	CacheHits       *prometheus.CounterVec
+	CacheMisses     *prometheus.CounterVec
+	DBRetries       *prometheus.CounterVec
+	DBAcquireWait   prometheus.Histogram
+	TasksOverdue    prometheus.Gauge
+	CSRFFailures    *prometheus.CounterVec
+	OAuthLogins     *prometheus.CounterVec
+	SessionsPruned  prometheus.Counter
+	TasksArchived   *prometheus.CounterVec
+	ReadsDeduped    *prometheus.CounterVec
+	RouteInFlight   *prometheus.GaugeVec
+	ConcurrencyHits *prometheus.CounterVec
 }{
 	RequestsTotal: promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -82,10 +101,156 @@ var Metrics = struct {
 	RateLimitHits: promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "gateway_rate_limit_hits_total",
-			Help: "Rate limit hits by IP",
+			Help: "Throttled requests by route pattern",
 		},
 		[]string{"path"},
 	),
+	EventsDLQDepth: promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gateway_events_dlq_depth",
+			Help: "Number of events in the dead-letter queue",
+		},
+	),
+	ProxyCancelled: promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gateway_proxy_client_cancelled_total",
+			Help: "Proxied worker requests abandoned by the client before completion",
+		},
+	),
+	ProxyRequests: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_proxy_requests_total",
+			Help: "Worker responses received by the proxy, by status code",
+		},
+		[]string{"status"},
+	),
+	ProxyDuration: promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "gateway_proxy_duration_seconds",
+			Help:    "Time from sending a proxied request to receiving the worker's response headers, or failing",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+	),
+	ProxyErrors: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_proxy_errors_total",
+			Help: "Proxied worker requests that got no response, by reason",
+		},
+		[]string{"reason"},
+	),
+	CacheHits: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_response_cache_hits_total",
+			Help: "Responses served from the response cache by scope",
+		},
+		[]string{"scope"},
+	),
+	CacheMisses: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_response_cache_misses_total",
+			Help: "Cacheable requests not found in the response cache by scope",
+		},
+		[]string{"scope"},
+	),
+	DBRetries: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_db_retries_total",
+			Help: "Database operations retried after a transient error by operation",
+		},
+		[]string{"operation"},
+	),
+	DBAcquireWait: promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name: "gateway_db_acquire_duration_seconds",
+			Help: "Time spent waiting to acquire a Postgres pool connection",
+			// 100µs to ~1.6s: an idle pool answers in the first bucket
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		},
+	),
+	TasksOverdue: promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "gateway_tasks_overdue",
+			Help: "Number of tasks past their due date and not completed",
+		},
+	),
+	CSRFFailures: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_csrf_failures_total",
+			Help: "Requests rejected by CSRF protection by reason (missing, invalid)",
+		},
+		[]string{"reason"},
+	),
+	OAuthLogins: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_oauth_logins_total",
+			Help: "OAuth login callbacks by provider, outcome (success, failed) and whether a user was auto-provisioned",
+		},
+		[]string{"provider", "outcome", "new_user"},
+	),
+	SessionsPruned: promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "gateway_session_refs_pruned_total",
+			Help: "Expired session IDs removed from user session sets by the pruning job",
+		},
+	),
+	TasksArchived: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_tasks_archived_total",
+			Help: "Completed tasks removed from default listings by the retention job, by action (archived, deleted)",
+		},
+		[]string{"action"},
+	),
+	ReadsDeduped: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_reads_deduplicated_total",
+			Help: "Reads answered from an identical in-flight request instead of querying the database, by handler",
+		},
+		[]string{"handler"},
+	),
+	RouteInFlight: promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_route_in_flight",
+			Help: "Requests executing on routes with a concurrency limit, by route pattern",
+		},
+		[]string{"route"},
+	),
+	ConcurrencyHits: promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_concurrency_limit_hits_total",
+			Help: "Requests refused because their route was at its concurrency limit, by route pattern",
+		},
+		[]string{"route"},
+	),
+}
+
+// RegisterDBPoolMetrics exports the database connection pool as gauges read
+// from stats at scrape time. Call it once, after connecting. Goroutine, heap
+// and GC metrics come from the default registry's Go collector (go_*).
+func RegisterDBPoolMetrics(stats func() models.DBPoolStats) {
+	conns := func(state string, count func(models.DBPoolStats) int32) {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "gateway_db_pool_connections",
+			Help:        "Postgres pool connections by state (acquired, idle, total, max)",
+			ConstLabels: prometheus.Labels{"state": state},
+		}, func() float64 { return float64(count(stats())) })
+	}
+	conns("acquired", func(s models.DBPoolStats) int32 { return s.AcquiredConns })
+	conns("idle", func(s models.DBPoolStats) int32 { return s.IdleConns })
+	conns("total", func(s models.DBPoolStats) int32 { return s.TotalConns })
+	conns("max", func(s models.DBPoolStats) int32 { return s.MaxConns })
+
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "gateway_db_pool_empty_acquires_total",
+		Help: "Pool acquires that waited because no idle connection was available",
+	}, func() float64 { return float64(stats().EmptyAcquireCount) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "gateway_db_pool_canceled_acquires_total",
+		Help: "Pool acquires abandoned because their context was canceled while waiting",
+	}, func() float64 { return float64(stats().CanceledAcquireCount) })
+	promauto.NewCounterFunc(prometheus.CounterOpts{
+		Name: "gateway_db_pool_acquire_wait_seconds_total",
+		Help: "Total time spent waiting to acquire a pool connection",
+	}, func() float64 { return stats().AcquireWaitMS / 1000 })
 }
 
 // MetricsHandler returns the Prometheus metrics handler.
@@ -93,33 +258,46 @@ func MetricsHandler() http.Handler {
 	return promhttp.Handler()
 }
 
-// MetricsMiddleware records request metrics.
+// MetricsMiddleware records request metrics. Mount it inside the recoverer:
+// the active-request gauge is released even when a handler panics.
 func MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		Metrics.ActiveRequests.Inc()
+		defer Metrics.ActiveRequests.Dec()
 
 		// Wrap response writer to capture status
 		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
 		next.ServeHTTP(wrapped, r)
 
-		Metrics.ActiveRequests.Dec()
 		duration := time.Since(start).Seconds()
+		path := requestRoute(r)
 
 		Metrics.RequestsTotal.WithLabelValues(
-			r.URL.Path,
+			path,
 			r.Method,
 			strconv.Itoa(wrapped.status),
 		).Inc()
 
 		Metrics.RequestDuration.WithLabelValues(
-			r.URL.Path,
+			path,
 			r.Method,
 		).Observe(duration)
 	})
 }
 
+// requestRoute returns the chi route pattern matched for r, which routing
+// fills in on the shared route context, so IDs don't each get a series.
+func requestRoute(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
+
 type responseWriter struct {
 	http.ResponseWriter
 	status int
@@ -130,11 +308,25 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // RecordAuthAttempt records an authentication attempt.
 func RecordAuthAttempt(authType string, success bool) {
 	Metrics.AuthAttempts.WithLabelValues(authType, strconv.FormatBool(success)).Inc()
 }
 
+// RecordOAuthLogin records the outcome of an OAuth callback.
+func RecordOAuthLogin(provider string, success, newUser bool) {
+	outcome := "failed"
+	if success {
+		outcome = "success"
+	}
+	Metrics.OAuthLogins.WithLabelValues(provider, outcome, strconv.FormatBool(newUser)).Inc()
+}
+
 // RecordAgentExecution records an agent execution.
 func RecordAgentExecution(agent, status string) {
 	Metrics.AgentExecutions.WithLabelValues(agent, status).Inc()
@@ -0,0 +1,39 @@
+package observability
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddlewareReleasesGaugeOnPanic(t *testing.T) {
+	before := testutil.ToFloat64(Metrics.ActiveRequests)
+	handler := MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() { _ = recover() }() // Stands in for the recoverer
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	if after := testutil.ToFloat64(Metrics.ActiveRequests); after != before {
+		t.Errorf("active requests = %v after a panic, want %v", after, before)
+	}
+}
+
+func TestMetricsMiddlewareLabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(MetricsMiddleware)
+	r.Get("/projects/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+	counter := Metrics.RequestsTotal.WithLabelValues("/projects/{id}", http.MethodGet, "200")
+	before := testutil.ToFloat64(counter)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/projects/abc", nil))
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("requests counted under the route pattern = %v, want 1", got)
+	}
+}
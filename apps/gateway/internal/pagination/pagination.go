@@ -0,0 +1,106 @@
+// Package pagination provides shared limit/offset/cursor handling for list endpoints.
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is used when the client doesn't specify a limit.
+	DefaultLimit = 50
+	// MaxLimit is the largest page a client may request.
+	MaxLimit = 100
+
+	cursorPrefix = "o:"
+)
+
+// ErrInvalidParams is returned when pagination query params are malformed.
+var ErrInvalidParams = errors.New("invalid pagination parameters")
+
+// Params are the parsed pagination query parameters.
+type Params struct {
+	Limit  int
+	Offset int
+}
+
+// Parse reads limit, offset, and cursor from the query string.
+// A cursor takes precedence over offset; both may not be combined.
+func Parse(r *http.Request) (Params, error) {
+	q := r.URL.Query()
+	p := Params{Limit: DefaultLimit}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return p, invalid("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+		}
+		p.Limit = n
+	}
+
+	cursor, offset := q.Get("cursor"), q.Get("offset")
+	if cursor != "" && offset != "" {
+		return p, invalid("cursor and offset cannot be combined")
+	}
+
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return p, invalid("offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+
+	if cursor != "" {
+		n, err := decodeCursor(cursor)
+		if err != nil {
+			return p, invalid("cursor is invalid")
+		}
+		p.Offset = n
+	}
+
+	return p, nil
+}
+
+// HasMore reports whether rows remain after page p out of total.
+func (p Params) HasMore(total int) bool {
+	return p.Offset+p.Limit < total
+}
+
+// NextCursor returns the cursor for the page after p, or "" if p is the last page.
+func (p Params) NextCursor(total int) string {
+	if !p.HasMore(total) {
+		return ""
+	}
+	return encodeCursor(p.Offset + p.Limit)
+}
+
+func invalid(msg string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidParams, msg)
+}
+
+// Cursors are opaque to clients; today they encode an offset, which leaves
+// room to switch to keyset pagination without changing the API.
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	s, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, ErrInvalidParams
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidParams
+	}
+	return n, nil
+}
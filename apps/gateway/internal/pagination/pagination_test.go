@@ -0,0 +1,61 @@
+package pagination
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantLimit  int
+		wantOffset int
+		wantErr    bool
+	}{
+		{"defaults", "", DefaultLimit, 0, false},
+		{"limit and offset", "?limit=10&offset=20", 10, 20, false},
+		{"cursor", "?limit=10&cursor=" + encodeCursor(30), 10, 30, false},
+		{"limit too large", "?limit=1000", 0, 0, true},
+		{"limit zero", "?limit=0", 0, 0, true},
+		{"negative offset", "?offset=-1", 0, 0, true},
+		{"cursor with offset", "?offset=1&cursor=" + encodeCursor(2), 0, 0, true},
+		{"garbage cursor", "?cursor=!!!", 0, 0, true},
+		{"cursor without prefix", "?cursor=MTA", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Parse(httptest.NewRequest("GET", "/items"+tt.query, nil))
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidParams) {
+					t.Fatalf("err = %v, want ErrInvalidParams", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if p.Limit != tt.wantLimit || p.Offset != tt.wantOffset {
+				t.Fatalf("got limit=%d offset=%d, want limit=%d offset=%d", p.Limit, p.Offset, tt.wantLimit, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestNextCursor(t *testing.T) {
+	p := Params{Limit: 10, Offset: 0}
+	next := p.NextCursor(25)
+	if next == "" || !p.HasMore(25) {
+		t.Fatal("expected a next cursor")
+	}
+	if n, err := decodeCursor(next); err != nil || n != 10 {
+		t.Fatalf("decoded cursor = %d, %v; want 10", n, err)
+	}
+
+	last := Params{Limit: 10, Offset: 20}
+	if c := last.NextCursor(25); c != "" || last.HasMore(25) {
+		t.Fatalf("expected no cursor on last page, got %q", c)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
 	"time"
 
 	"golang.org/x/crypto/acme/autocert"
@@ -14,6 +16,7 @@ import (
 
 // Config holds server configuration.
 type Config struct {
+	BindAddress string
 	Port        string
 	TLSEnabled  bool
 	TLSCertFile string
@@ -29,12 +32,26 @@ type Server struct {
 	log        *slog.Logger
 }
 
+// ListenAddr joins a bind address and port into a listen address,
+// bracketing IPv6 literals.
+func ListenAddr(bindAddress, port string) string {
+	return net.JoinHostPort(bindAddress, port)
+}
+
+// ValidateBindAddress checks that a bind address is an IP literal.
+func ValidateBindAddress(bindAddress string) error {
+	if _, err := netip.ParseAddr(bindAddress); err != nil {
+		return fmt.Errorf("bind address %q is not an IP address", bindAddress)
+	}
+	return nil
+}
+
 // New creates a new server with the given handler.
 func New(handler http.Handler, cfg Config, log *slog.Logger) *Server {
-	addr := ":" + cfg.Port
+	addr := ListenAddr(cfg.BindAddress, cfg.Port)
 	if cfg.TLSEnabled && cfg.Port == "8001" {
 		// Default to 443 for HTTPS if using default port
-		addr = ":443"
+		addr = ListenAddr(cfg.BindAddress, "443")
 	}
 
 	return &Server{
@@ -42,7 +59,7 @@ func New(handler http.Handler, cfg Config, log *slog.Logger) *Server {
 			Addr:         addr,
 			Handler:      handler,
 			ReadTimeout:  15 * time.Second,
-			WriteTimeout: 60 * time.Second,
+			WriteTimeout: 60 * time.Second, // Streaming routes override this with middleware.Streaming
 			IdleTimeout:  120 * time.Second,
 		},
 		config: cfg,
@@ -124,10 +141,10 @@ func (s *Server) startWithAutoTLS() error {
 	// Start HTTP server on port 80 for ACME challenges
 	go func() {
 		httpServer := &http.Server{
-			Addr:    ":80",
+			Addr:    ListenAddr(s.config.BindAddress, "80"),
 			Handler: certManager.HTTPHandler(nil),
 		}
-		s.log.Info("Starting HTTP server for ACME challenges", "addr", ":80")
+		s.log.Info("Starting HTTP server for ACME challenges", "addr", httpServer.Addr)
 		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			s.log.Error("ACME HTTP server error", "error", err)
 		}
@@ -158,11 +175,10 @@ func HTTPSRedirectMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// SecurityHeadersMiddleware adds security headers for HTTPS.
+// SecurityHeadersMiddleware adds security headers for HTTPS. It leaves out
+// Strict-Transport-Security, which middleware.HSTS sends as configured.
 func SecurityHeadersMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// HSTS - enforce HTTPS for 1 year
-		w.Header().Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
 		// Prevent clickjacking
 		w.Header().Set("X-Frame-Options", "DENY")
 		// Prevent MIME sniffing
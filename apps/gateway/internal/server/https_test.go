@@ -0,0 +1,31 @@
+package server
+
+import "testing"
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		bind, port, want string
+	}{
+		{"0.0.0.0", "8001", "0.0.0.0:8001"},
+		{"127.0.0.1", "8001", "127.0.0.1:8001"},
+		{"::1", "8001", "[::1]:8001"},
+	}
+	for _, tt := range tests {
+		if got := ListenAddr(tt.bind, tt.port); got != tt.want {
+			t.Errorf("ListenAddr(%q, %q) = %q, want %q", tt.bind, tt.port, got, tt.want)
+		}
+	}
+}
+
+func TestValidateBindAddress(t *testing.T) {
+	for _, addr := range []string{"0.0.0.0", "127.0.0.1", "::", "::1"} {
+		if err := ValidateBindAddress(addr); err != nil {
+			t.Errorf("ValidateBindAddress(%q) = %v, want nil", addr, err)
+		}
+	}
+	for _, addr := range []string{"", "localhost", "127.0.0.1:8001", "256.0.0.1"} {
+		if err := ValidateBindAddress(addr); err == nil {
+			t.Errorf("ValidateBindAddress(%q) = nil, want error", addr)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConnTracker counts a server's open connections. Install Track as the
+// server's ConnState hook.
+type ConnTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+}
+
+// NewConnTracker creates an empty ConnTracker.
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{conns: make(map[net.Conn]http.ConnState)}
+}
+
+// Track records a connection state change. Hijacked connections are no
+// longer managed by the server, so they are dropped along with closed ones.
+func (t *ConnTracker) Track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateClosed, http.StateHijacked:
+		delete(t.conns, c)
+	default:
+		t.conns[c] = state
+	}
+}
+
+// Open returns the number of connections the server still holds.
+func (t *ConnTracker) Open() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.conns)
+}
+
+// Drain gracefully shuts srv down, waiting up to timeout for in-flight
+// requests to finish. Connections still open after that are closed
+// forcibly, and their count is returned as undrained.
+func Drain(srv *http.Server, conns *ConnTracker, timeout time.Duration) (undrained int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = srv.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return 0, err
+	}
+
+	undrained = conns.Open()
+	return undrained, srv.Close()
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDrainClosesStuckConnections(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	conns := NewConnTracker()
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}),
+		ConnState: conns.Track,
+	}
+	go func() { _ = srv.Serve(ln) }()
+
+	go func() { _, _ = http.Get("http://" + ln.Addr().String()) }()
+	<-started
+
+	undrained, err := Drain(srv, conns, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if undrained != 1 {
+		t.Errorf("undrained = %d, want 1", undrained)
+	}
+}
+
+func TestDrainIdleServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conns := NewConnTracker()
+	srv := &http.Server{Handler: http.NotFoundHandler(), ConnState: conns.Track}
+	go func() { _ = srv.Serve(ln) }()
+
+	undrained, err := Drain(srv, conns, time.Second)
+	if err != nil || undrained != 0 {
+		t.Fatalf("Drain = (%d, %v), want (0, nil)", undrained, err)
+	}
+}